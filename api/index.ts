@@ -62,7 +62,12 @@ app.post('/project', async (req: Request, res: Response) => {
     const schema = z.object({
         name: z.string().min(1),
         gitRepoUrl: z.string().url(),
-        framework: z.enum(['NEXT', 'REACT', 'VUE', 'ANGULAR', 'SVELTE', 'OTHER', 'VITE'])
+        framework: z.enum(['NEXT', 'REACT', 'VUE', 'ANGULAR', 'SVELTE', 'OTHER', 'VITE']),
+        //Repository subdirectory to build from, e.g. apps/web in a monorepo
+        rootDir: z.string()
+            .regex(/^[\w.-]+(\/[\w.-]+)*$/, 'rootDir must be a relative path')
+            .refine((dir) => !dir.split('/').some((part) => part === '.' || part === '..'), 'rootDir must stay inside the repository')
+            .optional()
     })
     const safeData = schema.safeParse(req.body);
     if (!safeData.success) {
@@ -71,7 +76,7 @@ app.post('/project', async (req: Request, res: Response) => {
         });
         return;
     }
-    const {name, gitRepoUrl, framework} = safeData.data;
+    const {name, gitRepoUrl, framework, rootDir} = safeData.data;
 
     try {
         const project = await prisma.project.create({
@@ -79,6 +84,7 @@ app.post('/project', async (req: Request, res: Response) => {
                 name,
                 gitRepoUrl,
                 framework,
+                rootDir,
                 slug: generateSlug()
             }
         })
@@ -97,11 +103,36 @@ app.post('/project', async (req: Request, res: Response) => {
     }
 })
 
+//Make the reverse proxy resolve a slug again on its next request, if REVERSE_PROXY_ADMIN_URL
+//points at its admin port. The request is authorized with REVERSE_PROXY_ADMIN_TOKEN, the proxy's
+//ADMIN_TOKEN. Failures are only logged, since the cached entry expires anyway.
+async function purgeProxyResolveCache(slug: string) {
+    const adminUrl = process.env.REVERSE_PROXY_ADMIN_URL;
+    if (!adminUrl) {
+        return;
+    }
+    try {
+        const response = await fetch(`${adminUrl.replace(/\/+$/, '')}/resolve-cache/purge?key=${encodeURIComponent(slug)}`, {
+            method: 'POST',
+            headers: {
+                Authorization: `Bearer ${process.env.REVERSE_PROXY_ADMIN_TOKEN ?? ''}`
+            }
+        });
+        if (!response.ok) {
+            console.error(`Failed to purge reverse proxy cache for ${slug}: ${response.status}`);
+        }
+    } catch (error) {
+        console.error(`Failed to purge reverse proxy cache for ${slug}:`, error);
+    }
+}
+
 //Create POST at /deploy
 app.post('/deploy', async (req: Request, res: Response) => {
-    //Validate request body with zod for projectId
+    //Validate request body with zod for projectId and the optional note
     const schema = z.object({
-        projectId: z.string().uuid()
+        projectId: z.string().uuid(),
+        //Human description of the deployment, e.g. "pricing page rework"
+        note: z.string().trim().max(200, 'note must be at most 200 characters').optional()
     })
     const safeData = schema.safeParse(req.body);
     if (!safeData.success) {
@@ -110,7 +141,7 @@ app.post('/deploy', async (req: Request, res: Response) => {
         });
         return;
     }
-    const {projectId} = safeData.data;
+    const {projectId, note} = safeData.data;
 
     //Check for the project in db
     const project = await prisma.project.findUnique({
@@ -133,7 +164,8 @@ app.post('/deploy', async (req: Request, res: Response) => {
                     id: projectId
                 }
             },
-            status: 'QUEUED'
+            status: 'QUEUED',
+            note: note || null
         }
     });
 
@@ -142,6 +174,11 @@ app.post('/deploy', async (req: Request, res: Response) => {
         data: { latestDeploymentId: deployment.id }
       });
 
+    //The reverse proxy may have cached that the slug had no deployment yet
+    if (!project.latestDeploymentId) {
+        purgeProxyResolveCache(project.slug);
+    }
+
     //Initiate a ECS Task to build and store the project in S3
     const command = new RunTaskCommand({
         cluster: `${process.env.AWS_ECS_CLUSTER}`,
@@ -175,6 +212,10 @@ app.post('/deploy', async (req: Request, res: Response) => {
                         {
                             name: 'FRAMEWORK',
                             value: project.framework
+                        },
+                        {
+                            name: 'ROOT_DIR',
+                            value: project.rootDir ?? ''
                         }
                     ]
                 }
@@ -343,15 +384,17 @@ app.get('/resolve/:slug', async (req: Request, res: Response) => {
                 slug: slug
             }
         })
-        if (!project || !project.latestDeploymentId) {
+        //A promoted deployment is served instead of the latest one
+        const deploymentId = project?.promotedDeploymentId ?? project?.latestDeploymentId;
+        if (!project || !deploymentId) {
             res.status(404).json({
                 error: 'Project or latest deployment not found'
             });
             return;
         }
-        console.log(`Resolved slug ${slug} to deployment ${project.latestDeploymentId}`);
+        console.log(`Resolved slug ${slug} to deployment ${deploymentId}`);
         res.status(200).json({
-            deploymentId : project.latestDeploymentId
+            deploymentId
         })
         
     } catch(error) {
@@ -363,6 +406,52 @@ app.get('/resolve/:slug', async (req: Request, res: Response) => {
     
 })
 
+// Create GET at /resolve/domain/:host for the reverse proxy to serve custom domains
+app.get('/resolve/domain/:host', async (req: Request, res: Response) => {
+    const schema = z.object({
+        host: z.string().min(1).max(253)
+    })
+    const safeData = schema.safeParse(req.params);
+    if (!safeData.success) {
+        res.status(400).json({
+            error: safeData.error.message
+        });
+        return;
+    }
+    //Hosts are case-insensitive and may be fully qualified with a trailing dot
+    const host = safeData.data.host.toLowerCase().replace(/\.$/, '');
+
+    // Find the project the domain is connected to
+    try {
+        const project = await prisma.project.findFirst({
+            where: {
+                customDomain: {
+                    equals: host,
+                    mode: 'insensitive'
+                }
+            }
+        })
+        //A promoted deployment is served instead of the latest one, as for slugs
+        const deploymentId = project?.promotedDeploymentId ?? project?.latestDeploymentId;
+        if (!project || !deploymentId) {
+            res.status(404).json({
+                error: 'Domain or latest deployment not found'
+            });
+            return;
+        }
+        console.log(`Resolved domain ${host} to deployment ${deploymentId}`);
+        res.status(200).json({
+            deploymentId
+        })
+
+    } catch(error) {
+        console.error('Error resolving domain:', error);
+        res.status(500).json({
+            error: 'Failed to resolve domain'
+        });
+    }
+})
+
 // Create GET for health check
 app.get('/health', (req: Request, res: Response) => {
     res.status(200).json({
@@ -576,6 +665,139 @@ app.get('/project/:id', async (req: Request, res: Response) => {
     }
 });
 
+// Add endpoint to promote a deployment, making the project slug serve it
+app.post('/project/:id/promote', async (req: Request, res: Response) => {
+    const paramsSchema = z.object({
+        id: z.string().uuid()
+    });
+    const bodySchema = z.object({
+        deploymentId: z.string().uuid()
+    });
+    const safeParams = paramsSchema.safeParse(req.params);
+    const safeBody = bodySchema.safeParse(req.body);
+    if (!safeParams.success || !safeBody.success) {
+        res.status(400).json({
+            status: 'error',
+            message: (safeParams.error ?? safeBody.error)?.message
+        });
+        return;
+    }
+
+    const { id } = safeParams.data;
+    const { deploymentId } = safeBody.data;
+
+    try {
+        const deployment = await prisma.deployment.findUnique({
+            where: {
+                id: deploymentId
+            },
+            include: {
+                project: true
+            }
+        });
+
+        if (!deployment || deployment.projectId !== id) {
+            res.status(404).json({
+                status: 'error',
+                message: 'Deployment not found'
+            });
+            return;
+        }
+
+        // Only a completed deployment has anything to serve
+        if (deployment.status !== 'COMPLETED') {
+            res.status(400).json({
+                status: 'error',
+                message: `Cannot promote deployment with status ${deployment.status}`
+            });
+            return;
+        }
+
+        const project = await prisma.project.update({
+            where: {
+                id
+            },
+            data: {
+                promotedDeploymentId: deploymentId
+            }
+        });
+
+        // The reverse proxy has the previously served deployment cached for the slug
+        purgeProxyResolveCache(deployment.project.slug);
+
+        res.status(200).json({
+            status: 'success',
+            data: {
+                project
+            }
+        });
+    } catch (error) {
+        console.error('Error promoting deployment:', error);
+        res.status(500).json({
+            status: 'error',
+            message: 'Failed to promote deployment'
+        });
+    }
+});
+
+// Add endpoint to remove a promotion, making the project slug serve the latest deployment again
+app.delete('/project/:id/promote', async (req: Request, res: Response) => {
+    const schema = z.object({
+        id: z.string().uuid()
+    });
+    const safeData = schema.safeParse(req.params);
+    if (!safeData.success) {
+        res.status(400).json({
+            status: 'error',
+            message: safeData.error.message
+        });
+        return;
+    }
+
+    const { id } = safeData.data;
+
+    try {
+        const existing = await prisma.project.findUnique({
+            where: {
+                id
+            }
+        });
+
+        if (!existing) {
+            res.status(404).json({
+                status: 'error',
+                message: 'Project not found'
+            });
+            return;
+        }
+
+        const project = await prisma.project.update({
+            where: {
+                id
+            },
+            data: {
+                promotedDeploymentId: null
+            }
+        });
+
+        // The reverse proxy has the promoted deployment cached for the slug
+        purgeProxyResolveCache(project.slug);
+
+        res.status(200).json({
+            status: 'success',
+            data: {
+                project
+            }
+        });
+    } catch (error) {
+        console.error('Error removing promotion:', error);
+        res.status(500).json({
+            status: 'error',
+            message: 'Failed to remove promotion'
+        });
+    }
+});
+
 // Add endpoint to cancel a deployment
 app.post('/deployment/:id/cancel', async (req: Request, res: Response) => {
     const schema = z.object({
@@ -639,6 +861,96 @@ app.post('/deployment/:id/cancel', async (req: Request, res: Response) => {
     }
 });
 
+// Add endpoint to delete a deployment. Its build output is left in S3 for the bucket's
+// lifecycle rules, since the API server has no access to the bucket.
+app.delete('/deployment/:id', async (req: Request, res: Response) => {
+    const schema = z.object({
+        id: z.string().uuid()
+    });
+    const safeData = schema.safeParse(req.params);
+    if (!safeData.success) {
+        res.status(400).json({
+            status: 'error',
+            message: safeData.error.message
+        });
+        return;
+    }
+
+    const { id } = safeData.data;
+
+    try {
+        const deployment = await prisma.deployment.findUnique({
+            where: {
+                id
+            },
+            include: {
+                project: true
+            }
+        });
+
+        if (!deployment) {
+            res.status(404).json({
+                status: 'error',
+                message: 'Deployment not found'
+            });
+            return;
+        }
+
+        // The project slug may be serving the promoted deployment
+        if (deployment.project.promotedDeploymentId === id) {
+            res.status(409).json({
+                status: 'error',
+                message: 'Cannot delete the promoted deployment'
+            });
+            return;
+        }
+
+        if (deployment.status === 'PENDING' || deployment.status === 'QUEUED' || deployment.status === 'IN_PROGRESS') {
+            res.status(409).json({
+                status: 'error',
+                message: `Cannot delete deployment with status ${deployment.status}, cancel it first`
+            });
+            return;
+        }
+
+        await prisma.deployment.delete({
+            where: {
+                id
+            }
+        });
+
+        // The slug falls back to the newest completed deployment left, if any
+        if (deployment.project.latestDeploymentId === id) {
+            const latest = await prisma.deployment.findFirst({
+                where: {
+                    projectId: deployment.projectId,
+                    status: 'COMPLETED'
+                },
+                orderBy: {
+                    createdAt: 'desc'
+                }
+            });
+            await prisma.project.update({
+                where: {
+                    id: deployment.projectId
+                },
+                data: {
+                    latestDeploymentId: latest?.id ?? null
+                }
+            });
+            purgeProxyResolveCache(deployment.project.slug);
+        }
+
+        res.status(204).end();
+    } catch (error) {
+        console.error('Error deleting deployment:', error);
+        res.status(500).json({
+            status: 'error',
+            message: 'Failed to delete deployment'
+        });
+    }
+});
+
 app.listen(port, async () => {
     console.log(`Server is running on port ${port}`);
     try {
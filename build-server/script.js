@@ -9,6 +9,7 @@ const {Kafka, Partitioners} = require('kafkajs');
 const PROJECT_ID = process.env.PROJECT_ID;
 const DEPLOYMENT_ID = process.env.DEPLOYMENT_ID;
 const FRAMEWORK = process.env.FRAMEWORK;
+const ROOT_DIR = process.env.ROOT_DIR || '';
 
 //Initialize S3 Client
 const s3Client = new S3Client({
@@ -87,6 +88,17 @@ const prepareOutputDirectory = () => {
     return outDirPath;
 };
 
+// Resolve the repository subdirectory the app is built from, if the project has one
+const resolveRootDir = (outDirPath) => {
+    if (!ROOT_DIR) return outDirPath;
+    const rootDirPath = path.resolve(outDirPath, ROOT_DIR);
+    if (!rootDirPath.startsWith(outDirPath + path.sep) || !fs.existsSync(rootDirPath)) {
+        throw new Error(`Root directory ${ROOT_DIR} not found in the repository`);
+    }
+    console.log(`Building from ${ROOT_DIR}`);
+    return rootDirPath;
+};
+
 // Execute the build process
 const executeBuild = async (outDirPath) => {
     return new Promise( async (resolve, reject) => {
@@ -158,7 +170,7 @@ const uploadToS3 = async (distDir) => {
 const main = async () => {
     try {
         await connectToKafka();
-        const outDirPath = prepareOutputDirectory();
+        const outDirPath = resolveRootDir(prepareOutputDirectory());
         await executeBuild(outDirPath);
         const distDir = verifyBuildOutput(outDirPath);
         await uploadToS3(distDir);
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/velgardey/yok/cli/internal/config"
+	"github.com/velgardey/yok/cli/internal/types"
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+// userSetting is a user config setting that can be read and changed with 'yok config'
+type userSetting struct {
+	get func(types.UserConfig) string
+	set func(*types.UserConfig, string) error
+}
+
+// userSettings are the user config settings exposed by 'yok config', by key
+var userSettings = map[string]userSetting{
+	"channel": {
+		get: func(c types.UserConfig) string {
+			if c.Channel == "" {
+				return config.ChannelStable
+			}
+			return c.Channel
+		},
+		set: func(c *types.UserConfig, value string) error {
+			c.Channel = strings.ToLower(value)
+			return nil
+		},
+	},
+	"checkUpdates": {
+		get: func(c types.UserConfig) string { return strconv.FormatBool(c.CheckUpdates) },
+		set: func(c *types.UserConfig, value string) error {
+			checkUpdates, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value %q: must be true or false", value)
+			}
+			c.CheckUpdates = checkUpdates
+			return nil
+		},
+	},
+	"updateCheckInterval": {
+		get: func(c types.UserConfig) string { return c.UpdateCheckInterval },
+		set: func(c *types.UserConfig, value string) error {
+			c.UpdateCheckInterval = value
+			return nil
+		},
+	},
+}
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change your Yok CLI settings",
+	Long: `Read and change the settings stored in your user config, which apply to every project.
+
+Available settings:
+  channel              Release channel updates come from: stable (default) or beta, which includes prereleases
+  checkUpdates         Whether to tell you when a new version is available: true or false
+  updateCheckInterval  How long a looked up latest release is reused, e.g. 24h
+
+To run 'git config', use 'yok git config'.`,
+}
+
+func init() {
+	configCmd.AddCommand(&cobra.Command{
+		Use:       "get <key>",
+		Short:     "Print the value of a setting",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: userSettingKeys(),
+		Run: func(cmd *cobra.Command, args []string) {
+			setting, err := lookupUserSetting(args[0])
+			utils.HandleError(err, "Error reading setting")
+
+			userConfig, err := config.LoadUserConfig()
+			utils.HandleError(err, "Error loading user configuration")
+
+			fmt.Println(setting.get(userConfig))
+		},
+	})
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:       "set <key> <value>",
+		Short:     "Change the value of a setting",
+		Args:      cobra.ExactArgs(2),
+		ValidArgs: userSettingKeys(),
+		Run: func(cmd *cobra.Command, args []string) {
+			setting, err := lookupUserSetting(args[0])
+			utils.HandleError(err, "Error changing setting")
+
+			var userConfig types.UserConfig
+			err = config.UpdateUserConfig(func(conf *types.UserConfig) error {
+				if err := setting.set(conf, args[1]); err != nil {
+					return err
+				}
+				userConfig = *conf
+				return nil
+			})
+			utils.HandleError(err, "Error changing setting")
+
+			utils.SuccessColor.Printf("[OK] %s set to %s\n", args[0], setting.get(userConfig))
+		},
+	})
+
+	RootCmd.AddCommand(configCmd)
+}
+
+// lookupUserSetting returns the setting with key, or an error listing the known keys
+func lookupUserSetting(key string) (userSetting, error) {
+	setting, ok := userSettings[key]
+	if !ok {
+		return userSetting{}, fmt.Errorf("unknown setting %q: must be one of %s", key, strings.Join(userSettingKeys(), ", "))
+	}
+	return setting, nil
+}
+
+// userSettingKeys returns the keys of the settings in alphabetical order
+func userSettingKeys() []string {
+	keys := make([]string, 0, len(userSettings))
+	for key := range userSettings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,14 +1,19 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/signal"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 	"github.com/velgardey/yok/cli/internal/api"
+	"github.com/velgardey/yok/cli/internal/config"
 	"github.com/velgardey/yok/cli/internal/git"
+	"github.com/velgardey/yok/cli/internal/types"
 	"github.com/velgardey/yok/cli/internal/utils"
 )
 
@@ -23,6 +28,16 @@ func init() {
 	// Add flags to the deploy command
 	deployCmd.Flags().BoolP("logs", "l", false, "Follow deployment logs")
 	deployCmd.Flags().BoolP("no-sync-check", "n", false, "Skip repository sync check")
+	deployCmd.Flags().Bool("allow-dirty", false, "Deploy despite uncommitted changes or a diverged branch, with a warning instead of a prompt")
+	deployCmd.Flags().String("note", "", "Attach a short note describing this deployment")
+	deployCmd.Flags().Bool("dry-run", false, "Show what would be deployed without deploying")
+	deployCmd.Flags().Bool("skip-hooks", false, "Don't run the deployment hooks")
+	deployCmd.Flags().Bool("skip-unchanged", false, "Don't deploy if nothing changed since the last deployed commit")
+	deployCmd.Flags().Bool("force", false, "Deploy even if nothing changed since the last deployed commit")
+	addProjectFlags(deployCmd)
+	addWaitFlags(deployCmd)
+	addEventsFlag(deployCmd)
+	addOpenFlag(deployCmd)
 
 	// Ship command - combines git commit, push, and deploy
 	var shipCmd = &cobra.Command{
@@ -33,24 +48,89 @@ func init() {
 
 	// Add flags to the ship command
 	shipCmd.Flags().BoolP("logs", "l", false, "Follow deployment logs")
+	shipCmd.Flags().String("note", "", "Attach a short note describing this deployment (defaults to the commit subject)")
+	shipCmd.Flags().Bool("dry-run", false, "Show what would be committed and deployed without doing it")
+	shipCmd.Flags().Bool("skip-hooks", false, "Don't run the deployment hooks")
+	addWaitFlags(shipCmd)
+	addEventsFlag(shipCmd)
+	addOpenFlag(shipCmd)
+
+	// Redeploy command - triggers a fresh deployment without touching the repository
+	var redeployCmd = &cobra.Command{
+		Use:     "redeploy",
+		Short:   "Trigger a fresh deployment of the current project",
+		Long:    "Trigger a fresh deployment of the currently linked project without running the sync check or committing anything. The backend always builds the current HEAD of the repository.",
+		Aliases: []string{"restart"},
+		Run:     runRedeploy,
+	}
+
+	// Add flags to the redeploy command
+	redeployCmd.Flags().BoolP("logs", "l", false, "Follow deployment logs")
+	addWaitFlags(redeployCmd)
+	addEventsFlag(redeployCmd)
 
 	// Add commands to root
-	RootCmd.AddCommand(deployCmd, shipCmd)
+	RootCmd.AddCommand(deployCmd, shipCmd, redeployCmd)
 }
 
 // runDeploy handles the deploy command logic
 func runDeploy(cmd *cobra.Command, args []string) {
 	// Get flags
-	followLogs, _ := cmd.Flags().GetBool("logs")
 	skipSyncCheck, _ := cmd.Flags().GetBool("no-sync-check")
+	note, _ := cmd.Flags().GetString("note")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	skipHooks, _ := cmd.Flags().GetBool("skip-hooks")
+	force, _ := cmd.Flags().GetBool("force")
+	allowDirty, _ := cmd.Flags().GetBool("allow-dirty")
+
+	// Validate the note and project flags before doing anything else
+	utils.HandleError(validateNote(note), "Invalid note")
+	_, err := projectDetailsFromFlags(cmd)
+	utils.HandleError(err, "Invalid project flags")
+
+	// An explicit --wait makes the command a CI gate that never prompts
+	if waitRequested(cmd) {
+		noInput = true
+	}
+	utils.HandleError(setUpEvents(cmd), "Invalid --events")
 
-	// Get project configuration
-	config, err := EnsureProjectID()
+	if dryRun {
+		runDeployDryRun(note, !skipSyncCheck, false, skipHooks)
+		return
+	}
+
+	// Skip the deployment if nothing changed since the last deployed commit
+	if !force && shouldSkipUnchanged(cmd) {
+		utils.SuccessColor.Println("[OK] Nothing to deploy: HEAD is already deployed and the working tree is clean")
+		return
+	}
+
+	// Run the pre-deploy hooks before anything is pushed or triggered
+	if !skipHooks {
+		utils.HandleError(runPreDeployHooks(), "Deployment aborted")
+	}
+
+	// Get project configuration, creating the project given with --name if there is none
+	config, err := ensureProjectIDFromFlags(cmd)
 	utils.HandleError(err, "Error setting up project")
 
 	// Check repository sync status
-	if !skipSyncCheck {
-		if err := checkRepositorySync(); err != nil {
+	if !skipSyncCheck && allowDirty {
+		utils.InfoColor.Print("Checking local/remote sync... ")
+		if _, err := git.CheckLocalRemoteSync(""); git.IsNetworkError(err) {
+			fmt.Println()
+			warnSyncCheckSkipped(err)
+		} else if err != nil {
+			fmt.Println()
+			utils.WarnColor.Printf("Warning: %v (deploying anyway because of --allow-dirty)\n", err)
+		} else {
+			utils.SuccessColor.Println("Done")
+		}
+	} else if !skipSyncCheck {
+		if err := checkRepositorySync(!force); err != nil {
+			if !isInteractive() {
+				utils.HandleError(fmt.Errorf("%v; use --allow-dirty to deploy anyway", err), "Deployment aborted")
+			}
 			utils.WarnColor.Printf("Warning: %v\n", err)
 			if !confirmContinueDeployment() {
 				utils.ErrorColor.Println("Deployment cancelled")
@@ -59,36 +139,46 @@ func runDeploy(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	// Deploy the project
-	deployment, err := api.DeployProject(config.ProjectID)
-	utils.HandleError(err, "Error deploying project")
-
-	utils.SuccessColor.Printf("[OK] Deployment triggered: %s\n", deployment.Data.DeploymentId)
-
-	// Ask if user wants to follow logs if not explicitly specified
-	if !cmd.Flags().Changed("logs") {
-		utils.InfoColor.Println("Would you like to follow deployment logs?")
-		followLogs = confirmFollowLogs()
-	}
-
-	// Handle deployment follow-up based on flags
-	handleDeploymentFollowUp(followLogs, deployment.Data.DeploymentId, deployment.Data.DeploymentUrl, config.ProjectID)
+	// Deploy the project and follow it
+	triggerAndFollowDeployment(cmd, types.DeployRequest{ProjectID: config.ProjectID, Note: note})
 }
 
 // runShip handles the ship command logic (commit, push, and deploy)
 func runShip(cmd *cobra.Command, args []string) {
 	// Get flags
-	followLogs, _ := cmd.Flags().GetBool("logs")
+	note, _ := cmd.Flags().GetString("note")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	skipHooks, _ := cmd.Flags().GetBool("skip-hooks")
+
+	// Validate the note before touching the repository
+	utils.HandleError(validateNote(note), "Invalid note")
+	utils.HandleError(setUpEvents(cmd), "Invalid --events")
+
+	if dryRun {
+		runDeployDryRun(note, false, true, skipHooks)
+		return
+	}
+
+	// Run the pre-deploy hooks before anything is committed or pushed
+	if !skipHooks {
+		utils.HandleError(runPreDeployHooks(), "Ship aborted")
+	}
 
 	// Get commit message
-	commitMessage, err := getShipCommitMessage()
+	commitMessage, err := getCommitMessage()
 	if err != nil {
 		utils.ErrorColor.Printf("Error: %v\n", err)
 		return
 	}
 
+	// Default the note to the commit subject line
+	if !cmd.Flags().Changed("note") {
+		subject, _, _ := strings.Cut(commitMessage, "\n")
+		note = utils.TruncateString(strings.TrimSpace(subject), maxNoteLength)
+	}
+
 	// Perform git operations using the centralized function
-	if err := git.CommitAndPushChanges(commitMessage); err != nil {
+	if err := commitAndPushChanges(commitMessage); err != nil {
 		utils.HandleError(err, "Git operations failed")
 	}
 
@@ -96,104 +186,332 @@ func runShip(cmd *cobra.Command, args []string) {
 	config, err := EnsureProjectID()
 	utils.HandleError(err, "Error setting up project")
 
-	// Deploy the project
-	deployment, err := api.DeployProject(config.ProjectID)
-	utils.HandleError(err, "Error deploying project")
+	// Deploy the project and follow it
+	triggerAndFollowDeployment(cmd, types.DeployRequest{ProjectID: config.ProjectID, Note: note})
+}
 
-	utils.SuccessColor.Printf("[OK] Deployment triggered: %s\n", deployment.Data.DeploymentId)
+// runRedeploy handles the redeploy command logic
+func runRedeploy(cmd *cobra.Command, args []string) {
+	// An explicit --wait makes the command a CI gate that never prompts
+	if waitRequested(cmd) {
+		noInput = true
+	}
+	utils.HandleError(setUpEvents(cmd), "Invalid --events")
 
-	// Ask if user wants to follow logs if not explicitly specified
-	if !cmd.Flags().Changed("logs") {
-		utils.InfoColor.Println("Would you like to follow deployment logs?")
-		followLogs = confirmFollowLogs()
+	// Get project configuration
+	config, err := EnsureProjectID()
+	utils.HandleError(err, "Error setting up project")
+
+	// Deploy the project and follow it
+	triggerAndFollowDeployment(cmd, types.DeployRequest{ProjectID: config.ProjectID})
+}
+
+// triggerAndFollowDeployment deploys the project and, unless asked not to wait, follows the
+// deployment and exits with the code matching its final status. A failed deployment is retried
+// with a new one as --retry allows, or if the user agrees to when asked.
+func triggerAndFollowDeployment(cmd *cobra.Command, deployRequest types.DeployRequest) {
+	followLogs, _ := cmd.Flags().GetBool("logs")
+	projectID := deployRequest.ProjectID
+
+	// Remember which commit is being deployed so later deploys can tell if anything changed
+	deployedCommit := pushedHeadCommit()
+
+	// Warn early if the server may reject what this CLI sends
+	warnOnServerVersionMismatch()
+
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	skipHooks, _ := cmd.Flags().GetBool("skip-hooks") // false for commands without the flag
+	openSite, _ := cmd.Flags().GetBool("open")        // false for commands without the flag
+	retries, _ := cmd.Flags().GetInt("retry")
+	if retries < 0 {
+		utils.HandleError(fmt.Errorf("--retry must not be negative"), "Invalid flag")
 	}
+	askedAboutLogs := false
+
+	// Each retry of a failed deployment triggers and follows a new one
+	for attempt := 0; ; attempt++ {
+		// Deploy the project
+		s := utils.StartSpinner("Deploying project to Yok...")
+		deployment, err := api.DeployProject(deployRequest)
+		utils.StopSpinner(s)
+		handleAPIError(err, "Error deploying project")
+
+		utils.SuccessColor.Printf("[OK] Deployment triggered: %s\n", deployment.Data.DeploymentId)
+		emitEvent("triggered", deployment.Data.DeploymentId, "", deployment.Data.DeploymentUrl)
+
+		// Return immediately without waiting for the deployment to finish; exit code 0 only
+		// means the deployment was accepted
+		if !shouldWait(cmd) {
+			if deployment.Data.DeploymentUrl != "" {
+				utils.InfoColor.Printf("[i] Deployment URL: %s\n", deployment.Data.DeploymentUrl)
+			}
+			return
+		}
+
+		// Ask if user wants to follow logs if not explicitly specified; events replace the logs
+		if !cmd.Flags().Changed("logs") && isInteractive() && events == nil && !askedAboutLogs {
+			utils.InfoColor.Println("Would you like to follow deployment logs?")
+			followLogs = confirmFollowLogs()
+			askedAboutLogs = true
+		}
 
-	// Handle deployment follow-up based on flags
-	handleDeploymentFollowUp(followLogs, deployment.Data.DeploymentId, deployment.Data.DeploymentUrl, config.ProjectID)
+		// Handle deployment follow-up based on flags
+		outcome := handleDeploymentFollowUp(followLogs, deployment.Data.DeploymentId, deployment.Data.DeploymentUrl, projectID, timeout, !skipHooks)
+		if outcome == outcomeCompleted && deployedCommit != "" {
+			recordDeployedCommit(projectID, deployedCommit)
+		}
+		if outcome == outcomeCompleted && openSite {
+			openDeployment(deployment.Data.DeploymentId, deployment.Data.DeploymentUrl)
+		}
+		if outcome != outcomeFailed || !shouldRetryDeployment(attempt, retries) {
+			os.Exit(outcome.exitCode())
+		}
+	}
 }
 
-// handleDeploymentFollowUp handles the post-deployment logic (following logs or status)
-func handleDeploymentFollowUp(followLogs bool, deploymentID string, deploymentURL string, projectID string) {
-	if followLogs {
-		// Follow logs
-		utils.InfoColor.Println("Following deployment logs (Press Ctrl+C to stop)...")
+// maxRetryPrompts is how often a failed deployment is offered to be retried interactively
+const maxRetryPrompts = 3
 
-		// Create a channel for stopping log streaming
-		stopChan := make(chan bool)
+// shouldRetryDeployment reports whether a failed deployment should be retried after attempt
+// retries so far: automatically if --retry allows more, or else if the user agrees to when asked
+func shouldRetryDeployment(attempt int, retries int) bool {
+	if retries > 0 {
+		if attempt >= retries {
+			return false
+		}
+		utils.InfoColor.Printf("Retrying the deployment (%d/%d)...\n", attempt+1, retries)
+		return true
+	}
+
+	if !isInteractive() || attempt >= maxRetryPrompts {
+		return false
+	}
 
-		// Set up a signal handler for Ctrl+C
-		signalChan := make(chan os.Signal, 1)
-		signal.Notify(signalChan, os.Interrupt)
+	retry := false
+	prompt := &survey.Confirm{
+		Message: "Retry the deployment?",
+		Default: false,
+	}
+	if err := survey.AskOne(prompt, &retry, utils.GetSurveyOptions()); err != nil {
+		return false
+	}
+	return retry
+}
 
-		// Start a goroutine to handle Ctrl+C
-		go func() {
-			<-signalChan
-			stopChan <- true
-		}()
+// shouldSkipUnchanged reports whether the deployment should be skipped because --skip-unchanged
+// (or skipUnchanged in the config) is set and nothing changed since the last deployed commit
+func shouldSkipUnchanged(cmd *cobra.Command) bool {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		return false
+	}
 
-		// Stream logs and get completion status
-		deploymentSucceeded := api.StreamDeploymentLogs(deploymentID, stopChan)
+	skipUnchanged := conf.SkipUnchanged
+	if cmd.Flags().Changed("skip-unchanged") {
+		skipUnchanged, _ = cmd.Flags().GetBool("skip-unchanged")
+	}
+	if !skipUnchanged {
+		return false
+	}
 
-		// Show URLs and exit with appropriate code based on completion status
-		if deploymentSucceeded {
-			showDeploymentUrls(projectID, deploymentID, deploymentURL)
-			os.Exit(0)
-		} else {
-			// Check if deployment actually failed or was just interrupted
-			status, err := api.GetDeploymentStatus(deploymentID)
-			if err == nil && status.Status == "FAILED" {
-				utils.ErrorColor.Println("Deployment failed. Check the logs above for detailed error messages.")
-				os.Exit(1)
-			}
+	unchanged, err := git.UnchangedSince(lastDeployedCommit(conf.ProjectID))
+	if err != nil {
+		utils.WarnColor.Printf("Warning: Could not compare with the last deployed commit: %v\n", err)
+		return false
+	}
+	return unchanged
+}
+
+// lastDeployedCommit returns the commit of the last successful deployment of the project
+// recorded in the user config, or an empty string if none was
+func lastDeployedCommit(projectID string) string {
+	if projectID == "" {
+		return ""
+	}
+	userConf, err := config.LoadUserConfig()
+	if err != nil {
+		return ""
+	}
+	return userConf.DeployedCommits[projectID]
+}
+
+// pushedHeadCommit returns HEAD if it is the commit the branch's upstream is at, which is what
+// the backend builds. Otherwise, e.g. with unpushed commits, the deployed commit isn't known
+// and an empty string is returned.
+func pushedHeadCommit() string {
+	head, err := git.HeadCommit()
+	if err != nil {
+		return ""
+	}
+	upstream, err := git.UpstreamCommit()
+	if err != nil || upstream != head {
+		return ""
+	}
+	return head
+}
+
+// recordDeployedCommit stores the commit of a completed deployment of the project in the user
+// config, rather than the project's config, which is usually committed
+func recordDeployedCommit(projectID string, commitSHA string) {
+	err := config.UpdateUserConfig(func(userConf *types.UserConfig) error {
+		if userConf.DeployedCommits == nil {
+			userConf.DeployedCommits = make(map[string]string)
 		}
-	} else {
-		// Just follow deployment status
-		api.FollowDeploymentStatus(deploymentID, deploymentURL, projectID)
-
-		// Check final status to determine exit code
-		finalStatus, err := api.GetDeploymentStatus(deploymentID)
-		if err == nil && finalStatus.Status == "FAILED" {
-			os.Exit(1)
+		userConf.DeployedCommits[projectID] = commitSHA
+		return nil
+	})
+	if err != nil {
+		utils.WarnColor.Printf("Warning: Could not record the deployed commit: %v\n", err)
+	}
+}
+
+// handleDeploymentFollowUp waits for the deployment to finish, following its logs or its status,
+// reports the final outcome, and runs the post-deploy hooks if runHooks is set
+func handleDeploymentFollowUp(followLogs bool, deploymentID string, deploymentURL string, projectID string, timeout time.Duration, runHooks bool) deploymentOutcome {
+	outcome := waitForDeployment(deploymentID, followLogs, timeout)
+
+	switch outcome {
+	case outcomeCompleted:
+		showDeploymentSummary(deploymentID)
+		showDeploymentUrls(projectID, deploymentID, deploymentURL)
+	case outcomeFailed:
+		utils.ErrorColor.Println("Deployment failed. Check the logs above for detailed error messages.")
+	}
+
+	if runHooks {
+		runPostDeployHooks(outcome, deploymentID, deploymentURL)
+	}
+
+	return outcome
+}
+
+// showDeploymentSummary displays the duration and build/upload metrics of a finished deployment,
+// omitting anything the API doesn't report
+func showDeploymentSummary(deploymentID string) {
+	deployment, err := api.GetDeploymentStatus(deploymentID)
+	if err != nil {
+		return
+	}
+
+	var lines [][2]string
+	if deployment.CompletedAt != nil {
+		duration := deployment.CompletedAt.Sub(deployment.CreatedAt).Round(time.Second)
+		lines = append(lines, [2]string{"Total duration:", duration.String()})
+	}
+
+	if metrics := deployment.Metrics; metrics != nil {
+		if metrics.BuildDurationMs > 0 {
+			buildTime := (time.Duration(metrics.BuildDurationMs) * time.Millisecond).Round(time.Second)
+			lines = append(lines, [2]string{"Build time:", buildTime.String()})
+		}
+		if metrics.FilesUploaded > 0 {
+			lines = append(lines, [2]string{"Files uploaded:", fmt.Sprintf("%d", metrics.FilesUploaded)})
+		}
+		if metrics.TotalSizeBytes > 0 {
+			lines = append(lines, [2]string{"Total size:", utils.FormatBytes(metrics.TotalSizeBytes)})
 		}
 	}
+
+	if len(lines) == 0 {
+		return
+	}
+
+	utils.InfoColor.Println("[i] Deployment summary:")
+	for _, line := range lines {
+		fmt.Printf("  %-16s %s\n", line[0], line[1])
+	}
 }
 
-// showDeploymentUrls displays the URLs where the deployed site is available
+// showDeploymentUrls displays the URLs where the deployed site is available. The project URL is
+// only listed if it serves this deployment, which it doesn't while another one is promoted.
 func showDeploymentUrls(projectID string, deploymentID string, deploymentURL string) {
+	project, err := api.GetProject(projectID)
+	pinned := err == nil && project.PromotedDeploymentID != "" && project.PromotedDeploymentID != deploymentID
+	if pinned {
+		utils.WarnColor.Printf("Deployment %s is promoted, so the project URL still serves it instead of this deployment.\n", project.PromotedDeploymentID)
+		fmt.Printf("Run 'yok promote %s' to serve this deployment, or 'yok promote --latest' to serve every new deployment again.\n", deploymentID)
+	}
+
 	utils.InfoColor.Printf("[i] Your site is available at:\n")
 
 	// Try to get the project slug for a nicer URL
-	project, err := api.GetProject(projectID)
-	if err == nil && project.Slug != "" {
+	if err == nil && project.Slug != "" && !pinned {
 		fmt.Printf("- https://%s.yok.ninja\n", project.Slug)
+	} else if err == nil && project.Partial {
+		utils.DimColor.Println("  (project URL unavailable: the project details couldn't be fetched)")
 	}
 
 	// Always try to show a deployment-specific URL
+	fmt.Printf("- %s\n", resolveDeploymentURL(deploymentID, deploymentURL))
+}
+
+// resolveDeploymentURL returns the URL of a deployment, fetching it from the API if deploymentURL
+// is empty and constructing it if the API doesn't know it either
+func resolveDeploymentURL(deploymentID string, deploymentURL string) string {
 	if deploymentURL != "" {
-		fmt.Printf("- %s\n", deploymentURL)
-	} else {
-		// If we don't have the deploymentURL, fetch it from the API
-		deployment, err := api.GetDeploymentStatus(deploymentID)
-		if err == nil && deployment.DeploymentUrl != "" {
-			fmt.Printf("- %s\n", deployment.DeploymentUrl)
-		} else {
-			// Construct the URL if we couldn't get it from the API
-			fmt.Printf("- https://%s.yok.ninja\n", deploymentID)
-		}
+		return deploymentURL
+	}
+	deployment, err := api.GetDeploymentStatus(deploymentID)
+	if err == nil && deployment.DeploymentUrl != "" {
+		return deployment.DeploymentUrl
+	}
+	return fmt.Sprintf("https://%s.yok.ninja", deploymentID)
+}
+
+// addOpenFlag adds the flag that opens a deployment in the browser once it has completed. It
+// needs the command to wait for the deployment.
+func addOpenFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("open", false, "Open the deployment in the browser once it has completed")
+	cmd.MarkFlagsMutuallyExclusive("open", "no-wait")
+	cmd.MarkFlagsMutuallyExclusive("open", "detach")
+}
+
+// openDeployment opens a completed deployment in the browser, warning if that isn't possible
+func openDeployment(deploymentID string, deploymentURL string) {
+	url := resolveDeploymentURL(deploymentID, deploymentURL)
+	utils.InfoColor.Printf("[i] Opening %s\n", url)
+	if err := utils.OpenBrowser(url); err != nil {
+		utils.WarnColor.Printf("Warning: Could not open browser: %v\n", err)
 	}
 }
 
-// checkRepositorySync checks if the local repository is in sync with remote
-func checkRepositorySync() error {
+// checkRepositorySync checks if the local repository is in sync with remote. If offerPull is set
+// and the local branch is only behind, the user is offered to pull and check again.
+func checkRepositorySync(offerPull bool) error {
 	utils.InfoColor.Print("Checking local/remote sync... ")
 
-	_, err := git.CheckLocalRemoteSync()
+	_, err := git.CheckLocalRemoteSync("")
+	if git.IsNetworkError(err) {
+		// Being offline says nothing about the repository, so it doesn't block the deployment
+		fmt.Println()
+		warnSyncCheckSkipped(err)
+		return nil
+	}
+	var behindErr *git.BehindRemoteError
+	if errors.As(err, &behindErr) && offerPull && isInteractive() {
+		fmt.Println()
+		if !confirmPullChanges(behindErr.Commits) {
+			return err
+		}
+
+		utils.InfoColor.Print("[v] Pulling from remote... ")
+		if pullErr := git.PullFastForward(); pullErr != nil {
+			fmt.Println()
+			return pullErr
+		}
+		utils.SuccessColor.Println("Done")
+
+		utils.InfoColor.Print("Checking local/remote sync again... ")
+		_, err = git.CheckLocalRemoteSync("")
+	}
 	if err != nil {
 		utils.SuccessColor.Println()
 
-		// Try to handle uncommitted changes
-		if handleErr := git.HandleUncommittedChanges(); handleErr != nil {
-			return handleErr
+		// Try to handle uncommitted changes, which needs prompting
+		if isInteractive() {
+			if handleErr := handleUncommittedChanges(); handleErr != nil {
+				return handleErr
+			}
 		}
 
 		return err
@@ -203,6 +521,22 @@ func checkRepositorySync() error {
 	return nil
 }
 
+// warnSyncCheckSkipped warns that the sync check couldn't reach the remote and was skipped
+func warnSyncCheckSkipped(err error) {
+	utils.WarnColor.Printf("Warning: %v; skipping the sync check. Pass --no-sync-check to skip it without trying\n", err)
+}
+
+// maxNoteLength is the longest deployment note accepted by the CLI
+const maxNoteLength = 200
+
+// validateNote checks that a deployment note fits within maxNoteLength
+func validateNote(note string) error {
+	if length := utf8.RuneCountInString(note); length > maxNoteLength {
+		return fmt.Errorf("note is %d characters long, the maximum is %d", length, maxNoteLength)
+	}
+	return nil
+}
+
 // confirmContinueDeployment asks user if they want to continue with deployment
 func confirmContinueDeployment() bool {
 	opts := utils.GetSurveyOptions()
@@ -220,8 +554,8 @@ func confirmContinueDeployment() bool {
 	return continueDeploy
 }
 
-// getShipCommitMessage prompts user for commit message
-func getShipCommitMessage() (string, error) {
+// getCommitMessage prompts user for commit message
+func getCommitMessage() (string, error) {
 	opts := utils.GetSurveyOptions()
 
 	var commitMessage string
@@ -233,13 +567,30 @@ func getShipCommitMessage() (string, error) {
 		return "", err
 	}
 
-	if commitMessage == "" {
+	if strings.TrimSpace(commitMessage) == "" {
 		return "", fmt.Errorf("commit message cannot be empty")
 	}
 
 	return commitMessage, nil
 }
 
+// confirmPullChanges asks the user whether to pull the commits their branch is missing
+func confirmPullChanges(commits string) bool {
+	opts := utils.GetSurveyOptions()
+
+	var pull bool
+	prompt := &survey.Confirm{
+		Message: fmt.Sprintf("Your local branch is %s commits behind the remote. Pull them (git pull --ff-only) and check again?", commits),
+		Default: true,
+	}
+
+	if err := survey.AskOne(prompt, &pull, opts); err != nil {
+		return false
+	}
+
+	return pull
+}
+
 // confirmFollowLogs asks user if they want to follow deployment logs
 func confirmFollowLogs() bool {
 	opts := utils.GetSurveyOptions()
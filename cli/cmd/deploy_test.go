@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/velgardey/yok/cli/internal/config"
+)
+
+func TestDeployedCommitIsKeptPerUser(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	recordDeployedCommit("project-1", "abc123")
+	recordDeployedCommit("project-2", "def456")
+
+	if got := lastDeployedCommit("project-1"); got != "abc123" {
+		t.Errorf("lastDeployedCommit(project-1) = %q, want abc123", got)
+	}
+	if got := lastDeployedCommit("project-2"); got != "def456" {
+		t.Errorf("lastDeployedCommit(project-2) = %q, want def456", got)
+	}
+	if _, err := os.Stat(config.Path()); !os.IsNotExist(err) {
+		t.Errorf("the project config was written: %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/velgardey/yok/cli/internal/api"
+	"github.com/velgardey/yok/cli/internal/git"
+	"github.com/velgardey/yok/cli/internal/types"
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+// diffDeployCmd represents the diff-deploy command
+var diffDeployCmd = &cobra.Command{
+	Use:   "diff-deploy [fromDeploymentId toDeploymentId]",
+	Short: "Compare two deployments",
+	Long: `Compare two deployments by listing the commits and file changes between them.
+
+If no deployment IDs are provided, the latest deployment is compared against the
+previous successful one. When the deployments don't carry commit information,
+their details are shown side by side instead.
+
+Examples:
+  yok diff-deploy                    # Latest deployment vs. previous successful one
+  yok diff-deploy abc123 def456      # Changes from abc123 to def456
+  yok diff-deploy --json             # Machine-readable output`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("expected either no deployment IDs or exactly two, got %d", len(args))
+		}
+		return nil
+	},
+	Run: runDiffDeploy,
+}
+
+func init() {
+	RootCmd.AddCommand(diffDeployCmd)
+
+	// Add flags
+	diffDeployCmd.Flags().Bool("json", false, "Output the comparison as JSON")
+}
+
+// deploymentDiff is the result of comparing two deployments
+type deploymentDiff struct {
+	From     types.Deployment `json:"from"`
+	To       types.Deployment `json:"to"`
+	Commits  []string         `json:"commits,omitempty"`
+	DiffStat string           `json:"diffStat,omitempty"`
+	// HasCommitInfo is false when the deployments don't record the commit they were built from
+	HasCommitInfo bool `json:"hasCommitInfo"`
+}
+
+// runDiffDeploy handles the diff-deploy command logic
+func runDiffDeploy(cmd *cobra.Command, args []string) {
+	// Get flags
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	var from, to *types.Deployment
+	var err error
+
+	if len(args) == 2 {
+		from, err = api.GetDeploymentStatus(args[0])
+		utils.HandleError(err, "Error fetching deployment "+args[0])
+		to, err = api.GetDeploymentStatus(args[1])
+		utils.HandleError(err, "Error fetching deployment "+args[1])
+	} else {
+		conf, err := EnsureProjectID()
+		utils.HandleError(err, "Error setting up project")
+
+		from, to, err = pickDeploymentsToCompare(conf.ProjectID)
+		utils.HandleError(err, "Error selecting deployments")
+	}
+
+	diff := deploymentDiff{From: *from, To: *to}
+
+	if from.CommitSHA != "" && to.CommitSHA != "" {
+		diff.HasCommitInfo = true
+		revRange := from.CommitSHA + ".." + to.CommitSHA
+
+		logOutput, err := git.ExecuteCommand("log", "--oneline", revRange)
+		utils.HandleError(err, "Error listing commits between deployments")
+		for _, line := range strings.Split(strings.TrimSpace(logOutput), "\n") {
+			if line != "" {
+				diff.Commits = append(diff.Commits, line)
+			}
+		}
+
+		diffOutput, err := git.ExecuteCommand("diff", "--stat", revRange)
+		utils.HandleError(err, "Error comparing deployment commits")
+		diff.DiffStat = strings.TrimRight(diffOutput, "\n")
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		utils.HandleError(encoder.Encode(diff), "Error encoding JSON")
+		return
+	}
+
+	renderDeploymentDiff(diff)
+}
+
+// pickDeploymentsToCompare returns the latest deployment and the successful deployment before it
+func pickDeploymentsToCompare(projectID string) (*types.Deployment, *types.Deployment, error) {
+	deployments, err := api.ListDeployments(projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sortDeploymentsByCreatedDesc(deployments)
+
+	if len(deployments) < 2 {
+		return nil, nil, fmt.Errorf("at least two deployments are needed to compare")
+	}
+
+	latest := deployments[0]
+	for _, d := range deployments[1:] {
+		if d.Status == "COMPLETED" {
+			return fetchDeploymentPair(d.ID, latest.ID)
+		}
+	}
+
+	return nil, nil, fmt.Errorf("no successful deployment found before %s", latest.ID)
+}
+
+// fetchDeploymentPair fetches the full details of two deployments
+func fetchDeploymentPair(fromID, toID string) (*types.Deployment, *types.Deployment, error) {
+	from, err := api.GetDeploymentStatus(fromID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	to, err := api.GetDeploymentStatus(toID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return from, to, nil
+}
+
+// renderDeploymentDiff prints a deployment comparison for humans
+func renderDeploymentDiff(diff deploymentDiff) {
+	utils.InfoColor.Printf("Comparing %s -> %s\n\n", diff.From.ID, diff.To.ID)
+
+	if !diff.HasCommitInfo {
+		utils.WarnColor.Println("Commit information isn't available for these deployments; showing their details instead.")
+		fmt.Println()
+		renderDeploymentsSideBySide(diff.From, diff.To)
+		return
+	}
+
+	utils.InfoColor.Printf("Commits (%d):\n", len(diff.Commits))
+	if len(diff.Commits) == 0 {
+		fmt.Println("  No new commits")
+	}
+	for _, commit := range diff.Commits {
+		sha, message, _ := strings.Cut(commit, " ")
+		fmt.Printf("  %s %s\n", utils.DimColor.Sprint(sha), message)
+	}
+
+	if diff.DiffStat != "" {
+		fmt.Println()
+		utils.InfoColor.Println("Changed files:")
+		for _, line := range strings.Split(diff.DiffStat, "\n") {
+			fmt.Printf("  %s\n", colorizeDiffStat(line))
+		}
+	}
+}
+
+// colorizeDiffStat colors the +/- graph of a `git diff --stat` line
+func colorizeDiffStat(line string) string {
+	name, graph, found := strings.Cut(line, "|")
+	if !found {
+		return line
+	}
+
+	graph = strings.ReplaceAll(graph, "+", utils.SuccessColor.Sprint("+"))
+	graph = strings.ReplaceAll(graph, "-", utils.ErrorColor.Sprint("-"))
+	return name + "|" + graph
+}
+
+// renderDeploymentsSideBySide prints the details of two deployments in adjacent columns
+func renderDeploymentsSideBySide(from, to types.Deployment) {
+	rows := [][3]string{
+		{"", "FROM", "TO"},
+		{"ID", from.ID, to.ID},
+		{"Status", from.Status, to.Status},
+		{"Created", from.CreatedAt.Format("Jan 02, 2006 15:04:05"), to.CreatedAt.Format("Jan 02, 2006 15:04:05")},
+		{"Duration", deploymentDuration(from), deploymentDuration(to)},
+		{"URL", from.DeploymentUrl, to.DeploymentUrl},
+	}
+
+	for _, row := range rows {
+		fmt.Printf("%-10s %-40s %s\n", row[0], row[1], row[2])
+	}
+}
+
+// deploymentDuration returns how long a deployment took, or "-" if it hasn't finished
+func deploymentDuration(d types.Deployment) string {
+	if d.CompletedAt == nil {
+		return "-"
+	}
+	return d.CompletedAt.Sub(d.CreatedAt).Round(time.Second).String()
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/velgardey/yok/cli/internal/api"
+	"github.com/velgardey/yok/cli/internal/config"
+	"github.com/velgardey/yok/cli/internal/git"
+	"github.com/velgardey/yok/cli/internal/types"
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+// runDeployDryRun performs the local steps of deploy (or ship) and prints the deploy request that
+// would be sent, without committing, pushing, or deploying anything. It exits non-zero if a
+// condition that would stop the deployment was found.
+func runDeployDryRun(note string, checkSync bool, ship bool, skipHooks bool) {
+	utils.InfoColor.Println("Dry run: nothing will be committed, pushed, or deployed")
+	fmt.Println()
+
+	blocked := false
+
+	// Resolve the project without creating one
+	conf, err := config.LoadProjectConfig()
+	if err != nil {
+		utils.ErrorColor.Printf("[X] Project:      %v\n", err)
+		blocked = true
+	} else {
+		fmt.Printf("Project:          %s (%s)\n", conf.RepoName, conf.ProjectID)
+	}
+
+	// Describe the repository state
+	fmt.Printf("Branch:           %s\n", valueOrUnknown(git.CurrentBranch()))
+	fmt.Printf("Commit:           %s\n", valueOrUnknown(git.HeadCommit()))
+	fmt.Printf("Root dir:         %s\n", valueOrUnknown(git.RootDir()))
+	fmt.Printf("Framework:        %s\n", api.DetectFramework())
+	fmt.Printf("Output dir:       %s\n", api.DetectOutputDir())
+
+	// Hooks are listed but not run
+	if localConf, err := config.LoadConfig(); err == nil {
+		hooks := preDeployHooks(localConf)
+		switch {
+		case len(hooks) == 0:
+			fmt.Println("Pre-deploy hooks: none")
+		case skipHooks:
+			fmt.Printf("Pre-deploy hooks: %d, skipped\n", len(hooks))
+		default:
+			fmt.Printf("Pre-deploy hooks: %s\n", strings.Join(hooks, " && "))
+		}
+	}
+
+	switch {
+	case ship:
+		if git.HasUncommittedChanges() {
+			fmt.Println("Changes:          uncommitted changes would be committed and pushed")
+		} else {
+			fmt.Println("Changes:          nothing to commit")
+		}
+	case checkSync:
+		if _, err := git.CheckLocalRemoteSync(""); git.IsNetworkError(err) {
+			utils.WarnColor.Printf("Sync check:       skipped, %v\n", err)
+		} else if err != nil {
+			utils.ErrorColor.Printf("[X] Sync check:   %v\n", err)
+			blocked = true
+		} else {
+			utils.SuccessColor.Println("[OK] Sync check:  local and remote are in sync")
+		}
+	default:
+		fmt.Println("Sync check:       skipped")
+	}
+
+	// Show the request that would be sent
+	deployRequest := types.DeployRequest{ProjectID: conf.ProjectID, Note: note}
+	payload, err := json.MarshalIndent(deployRequest, "", "  ")
+	utils.HandleError(err, "Error encoding deploy request")
+
+	fmt.Println()
+	utils.InfoColor.Println("Deploy request:")
+	fmt.Println(string(payload))
+	if ship && note == "" {
+		utils.DimColor.Println("The note defaults to the subject of the commit message.")
+	}
+	fmt.Println()
+
+	if blocked {
+		utils.ErrorColor.Println("[X] The deployment would not proceed")
+		os.Exit(utils.ExitError)
+	}
+
+	utils.SuccessColor.Println("[OK] The deployment would proceed")
+}
+
+// valueOrUnknown returns value, or "unknown" if it couldn't be determined
+func valueOrUnknown(value string, err error) string {
+	if err != nil || value == "" {
+		return "unknown"
+	}
+	return value
+}
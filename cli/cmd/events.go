@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/spf13/cobra"
+)
+
+// deploymentEvent is a state change of a deployment, printed as one JSON object per line with
+// --events json
+type deploymentEvent struct {
+	// Event is "triggered", the lowercased deployment status (e.g. "in_progress", "completed",
+	// "failed"), or "timed_out" or "interrupted" when the CLI stops waiting
+	Event        string    `json:"event"`
+	DeploymentID string    `json:"deploymentId"`
+	Status       string    `json:"status,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+	URL          string    `json:"url,omitempty"`
+	// QueuePosition is the deployment's place in the build queue, for "queued" events from APIs
+	// that report it
+	QueuePosition int `json:"queuePosition,omitempty"`
+}
+
+// events prints deployment events to stdout when --events json is set, nil otherwise
+var events *json.Encoder
+
+// addEventsFlag adds the flag that makes a command print deployment events instead of the spinner
+func addEventsFlag(cmd *cobra.Command) {
+	cmd.Flags().String("events", "", "Print deployment progress as events instead of the spinner (json: one JSON object per line on stdout)")
+	cmd.MarkFlagsMutuallyExclusive("events", "logs")
+}
+
+// setUpEvents enables deployment events if --events is set. Stdout is then reserved for the
+// events, so everything else the command prints goes to stderr.
+func setUpEvents(cmd *cobra.Command) error {
+	format, _ := cmd.Flags().GetString("events")
+	switch strings.ToLower(format) {
+	case "":
+		return nil
+	case "json":
+	default:
+		return fmt.Errorf("unknown event format %q: must be json", format)
+	}
+
+	events = json.NewEncoder(os.Stdout)
+	os.Stdout = os.Stderr
+	color.SetOutput(os.Stderr)
+	return nil
+}
+
+// emitEvent prints a deployment event if events are enabled
+func emitEvent(event string, deploymentID string, status string, url string) {
+	emitDeploymentEvent(deploymentEvent{Event: event, DeploymentID: deploymentID, Status: status, URL: url})
+}
+
+// emitDeploymentEvent prints a deployment event, stamped with the current time, if events are
+// enabled
+func emitDeploymentEvent(event deploymentEvent) {
+	if events == nil {
+		return
+	}
+	event.Timestamp = time.Now().UTC()
+	events.Encode(event)
+}
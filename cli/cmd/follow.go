@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+	"github.com/velgardey/yok/cli/internal/api"
+	"github.com/velgardey/yok/cli/internal/types"
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+// Renderer and tail used when following deployment logs, configured by the logs command
+var (
+	followLogRenderer *utils.LogRenderer
+	followLogTail     int // Number of existing log entries shown before streaming new ones, 0 shows all
+)
+
+// deploymentOutcome describes how following a deployment ended
+type deploymentOutcome int
+
+const (
+	outcomeCompleted deploymentOutcome = iota
+	outcomeFailed
+	outcomeCancelled
+	outcomeTimedOut
+	outcomeInterrupted
+)
+
+// exitCode maps a deployment outcome to the process exit code. A failed deployment exits 2 rather
+// than 1, which every command uses for generic errors, and a timeout has its own code so scripts
+// can tell it apart from the user stopping the command.
+func (o deploymentOutcome) exitCode() int {
+	switch o {
+	case outcomeCompleted:
+		return utils.ExitSuccess
+	case outcomeTimedOut:
+		return utils.ExitTimedOut
+	case outcomeCancelled, outcomeInterrupted:
+		return utils.ExitInterrupted
+	default:
+		return utils.ExitDeploymentFailed
+	}
+}
+
+// outcomeForStatus maps a terminal deployment status to an outcome
+func outcomeForStatus(status string) deploymentOutcome {
+	switch status {
+	case "COMPLETED":
+		return outcomeCompleted
+	case "CANCELLED":
+		return outcomeCancelled
+	default:
+		return outcomeFailed
+	}
+}
+
+// addWaitFlags adds the flags controlling whether and how long a command waits for its deployment
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("wait", true, "Wait for the deployment to reach a terminal status")
+	cmd.Flags().Bool("no-wait", false, "Return immediately after the deployment is triggered")
+	cmd.Flags().BoolP("detach", "d", false, "Print the deployment ID and URL and exit once the deployment is accepted")
+	cmd.Flags().Duration("timeout", 0, "Maximum time to wait for the deployment (0 waits indefinitely)")
+	cmd.Flags().Int("retry", 0, "Retry a failed deployment up to this many times without asking")
+	cmd.MarkFlagsMutuallyExclusive("wait", "no-wait", "detach")
+}
+
+// waitRequested reports whether --wait was given explicitly. The command then behaves as a CI
+// gate: it doesn't prompt, and its exit code comes only from the deployment's final status.
+func waitRequested(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("wait") && shouldWait(cmd)
+}
+
+// shouldWait reports whether the command was asked to wait for its deployment to finish
+func shouldWait(cmd *cobra.Command) bool {
+	wait, _ := cmd.Flags().GetBool("wait")
+	noWait, _ := cmd.Flags().GetBool("no-wait")
+	detach, _ := cmd.Flags().GetBool("detach")
+	return wait && !noWait && !detach
+}
+
+// stopOnInterruptOrTimeout returns a channel that is closed when the user hits Ctrl+C or the
+// timeout elapses, so every follow loop watching it stops, and a channel then holding which of
+// the two happened. release stops handling Ctrl+C, so a second one exits immediately; it may be
+// called more than once.
+func stopOnInterruptOrTimeout(timeout time.Duration) (stopChan chan bool, stopReason <-chan deploymentOutcome, release func()) {
+	stopChan = make(chan bool)
+	reason := make(chan deploymentOutcome, 1)
+
+	// Set up a signal handler for Ctrl+C
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt)
+
+	var timeoutChan <-chan time.Time
+	var timer *time.Timer
+	if timeout > 0 {
+		timer = time.NewTimer(timeout)
+		timeoutChan = timer.C
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			signal.Stop(signalChan)
+			if timer != nil {
+				timer.Stop()
+			}
+			close(done)
+		})
+	}
+
+	// Start a goroutine to handle Ctrl+C and the timeout
+	go func() {
+		select {
+		case <-signalChan:
+			reason <- outcomeInterrupted
+		case <-timeoutChan:
+			reason <- outcomeTimedOut
+		case <-done:
+			return
+		}
+		close(stopChan)
+	}()
+
+	return stopChan, reason, release
+}
+
+// waitForDeployment follows a deployment, either by streaming its logs or by polling its
+// status, until it reaches a terminal status, the timeout elapses, or the user hits Ctrl+C
+func waitForDeployment(deploymentID string, followLogs bool, timeout time.Duration) deploymentOutcome {
+	stopChan, stopReason, release := stopOnInterruptOrTimeout(timeout)
+	defer release()
+
+	var status string
+	if followLogs {
+		utils.InfoColor.Println("Following deployment logs (Press Ctrl+C to stop)...")
+		status = streamDeploymentLogs(deploymentID, stopChan)
+	} else if events != nil {
+		var err error
+		status, err = api.WatchDeploymentStatus(deploymentID, stopChan, func(d *types.Deployment) {
+			emitDeploymentEvent(deploymentEvent{
+				Event:         strings.ToLower(d.Status),
+				DeploymentID:  deploymentID,
+				Status:        d.Status,
+				URL:           d.DeploymentUrl,
+				QueuePosition: d.QueuePosition,
+			})
+		})
+		if err != nil {
+			utils.WarnColor.Printf("\n%v\n", err)
+			return outcomeFailed
+		}
+	} else {
+		s := utils.StartSpinner("Waiting for deployment to complete...")
+		var err error
+		status, err = api.WatchDeploymentStatus(deploymentID, stopChan, func(d *types.Deployment) {
+			s.Lock()
+			s.Suffix = " " + waitingMessage(d)
+			s.Unlock()
+		})
+		utils.StopSpinner(s)
+		if err != nil {
+			utils.WarnColor.Printf("\n%v\n", err)
+			return outcomeFailed
+		}
+
+		switch status {
+		case "COMPLETED":
+			utils.SuccessColor.Printf("\n[OK] Deployment completed successfully!\n")
+		case "FAILED":
+			utils.ErrorColor.Printf("\n[X] Deployment failed\n")
+		case "CANCELLED":
+			utils.WarnColor.Printf("\n[X] Deployment cancelled\n")
+		}
+	}
+
+	if status != "" {
+		return outcomeForStatus(status)
+	}
+
+	// Restore default Ctrl+C handling so a second interrupt exits immediately
+	release()
+
+	select {
+	case reason := <-stopReason:
+		switch reason {
+		case outcomeTimedOut:
+			emitEvent("timed_out", deploymentID, "", "")
+			utils.WarnColor.Printf("\nTimed out after %s waiting for deployment %s\n", timeout, deploymentID)
+		case outcomeInterrupted:
+			emitEvent("interrupted", deploymentID, "", "")
+			return offerCancelDeployment(deploymentID)
+		}
+		return reason
+	default:
+		// The follow loop gave up on its own (e.g. the logs could not be fetched)
+		return outcomeFailed
+	}
+}
+
+// waitingMessage describes what a deployment that is being waited for is doing, so a deployment
+// waiting for a build server doesn't look stuck
+func waitingMessage(d *types.Deployment) string {
+	switch d.Status {
+	case "QUEUED":
+		if d.QueuePosition > 0 {
+			return fmt.Sprintf("Queued (position %d), waiting for other builds to finish...", d.QueuePosition)
+		}
+		return "Queued, waiting for a build server..."
+	case "IN_PROGRESS":
+		return "Building and deploying..."
+	default:
+		return "Waiting for deployment to complete..."
+	}
+}
+
+// offerCancelDeployment asks whether a deployment the user stopped following should also be
+// cancelled on the server
+func offerCancelDeployment(deploymentID string) deploymentOutcome {
+	fmt.Println()
+
+	if !isInteractive() {
+		utils.WarnColor.Printf("Deployment %s is still running. Run 'yok cancel %s' to cancel it.\n", deploymentID, deploymentID)
+		return outcomeInterrupted
+	}
+
+	cancel := false
+	prompt := &survey.Confirm{
+		Message: fmt.Sprintf("Deployment %s is still running — cancel it on the server?", deploymentID),
+		Default: false,
+	}
+
+	// A second Ctrl+C during the prompt returns an error and leaves the deployment running
+	if err := survey.AskOne(prompt, &cancel, utils.GetSurveyOptions()); err != nil || !cancel {
+		return outcomeInterrupted
+	}
+
+	s := utils.StartSpinner("Cancelling deployment...")
+	err := api.CancelDeployment(deploymentID)
+	utils.StopSpinner(s)
+
+	if err != nil {
+		utils.ErrorColor.Printf("Failed to cancel deployment: %v\n", err)
+		return outcomeInterrupted
+	}
+
+	utils.SuccessColor.Println("[OK] Deployment cancelled successfully")
+	return outcomeCancelled
+}
+
+// streamDeploymentLogs continuously fetches and displays logs for a deployment
+// It polls until the deployment is complete or stopChan receives a value, and returns
+// the final deployment status (empty if it was stopped before reaching one)
+func streamDeploymentLogs(deploymentID string, stopChan chan bool) string {
+	// Use the configured renderer or create a new default one
+	logRenderer := followLogRenderer
+	if logRenderer == nil {
+		logRenderer = utils.NewLogRenderer()
+	}
+
+	return streamDeploymentLogsWithRenderer(deploymentID, stopChan, logRenderer)
+}
+
+// streamDeploymentLogsWithRenderer is like streamDeploymentLogs but renders with the given renderer,
+// which lets several deployments be streamed at once
+func streamDeploymentLogsWithRenderer(deploymentID string, stopChan chan bool, logRenderer *utils.LogRenderer) string {
+	var lastEventID string
+	var lastErrorMessage string
+
+	// Keep track of logs we've already seen to avoid duplicates
+	seenLogs := make(map[string]bool)
+
+	// First fetch to get initial logs
+	logs, err := api.GetDeploymentLogs(deploymentID, "")
+	if err != nil {
+		utils.ErrorColor.Printf("Error fetching logs: %v\n", err)
+		return ""
+	}
+
+	// Display initial logs, limited to the tail if one was requested
+	shown := utils.TailLogEntries(logs.Data.Logs, followLogTail)
+	hidden := len(logs.Data.Logs) - len(shown)
+	completed := false
+	for i, logEntry := range logs.Data.Logs {
+		seenLogs[logEntry.EventID] = true
+		if i >= hidden {
+			logRenderer.RenderLogEntry(logEntry)
+		}
+		lastEventID = logEntry.EventID
+
+		// Keep track of the last error message
+		if strings.Contains(logEntry.Log, "Error:") || strings.Contains(logEntry.Log, "Failed:") {
+			lastErrorMessage = logEntry.Log
+		}
+
+		// Check for completion marker
+		if strings.Contains(logEntry.Log, "Build output uploaded to S3 successfully") {
+			completed = true
+		}
+	}
+
+	if completed {
+		utils.InfoColor.Println("\nDeployment completed successfully!")
+		return "COMPLETED"
+	}
+
+	// Start polling for new logs
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	// Set once the API reports completion so the final log gets one more poll to arrive
+	completionSeen := false
+
+	for {
+		select {
+		case <-ticker.C:
+			// Fetch new logs since the last event ID
+			newLogs, err := api.GetDeploymentLogs(deploymentID, lastEventID)
+			if err != nil {
+				utils.ErrorColor.Printf("Error fetching logs: %v\n", err)
+				continue
+			}
+
+			// Process new logs
+			for _, logEntry := range newLogs.Data.Logs {
+				if seenLogs[logEntry.EventID] {
+					continue
+				}
+
+				seenLogs[logEntry.EventID] = true
+				logRenderer.RenderLogEntry(logEntry)
+				lastEventID = logEntry.EventID
+
+				if strings.Contains(logEntry.Log, "Error:") || strings.Contains(logEntry.Log, "Failed:") {
+					lastErrorMessage = logEntry.Log
+				}
+
+				// Check for completion marker
+				if strings.Contains(logEntry.Log, "Build output uploaded to S3 successfully") {
+					utils.InfoColor.Println("\nDeployment completed successfully!")
+					return "COMPLETED"
+				}
+			}
+
+			// Check deployment status to catch completion/failure without log entry
+			deployment, err := api.GetDeploymentStatus(deploymentID)
+			if err == nil {
+				switch deployment.Status {
+				case "COMPLETED":
+					if completionSeen {
+						utils.InfoColor.Println("\nDeployment completed successfully!")
+						return deployment.Status
+					}
+					// Let's check once more for the final log in case it just came in
+					completionSeen = true
+					ticker.Reset(3 * time.Second)
+				case "FAILED":
+					utils.ErrorColor.Println("\nDeployment failed.")
+					if lastErrorMessage != "" {
+						utils.ErrorColor.Printf("Last error: %s\n", lastErrorMessage)
+					}
+					return deployment.Status
+				case "CANCELLED":
+					utils.WarnColor.Println("\nDeployment cancelled.")
+					return deployment.Status
+				}
+			}
+
+		case <-stopChan:
+			// User interrupted or the wait timed out
+			return ""
+		}
+	}
+}
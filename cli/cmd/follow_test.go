@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+func TestDeploymentOutcomeExitCode(t *testing.T) {
+	tests := []struct {
+		status string
+		want   int
+	}{
+		{"COMPLETED", utils.ExitSuccess},
+		{"FAILED", utils.ExitDeploymentFailed},
+		{"CANCELLED", utils.ExitInterrupted},
+		{"", utils.ExitDeploymentFailed},
+	}
+	for _, tt := range tests {
+		if got := outcomeForStatus(tt.status).exitCode(); got != tt.want {
+			t.Errorf("status %q exits with %d, want %d", tt.status, got, tt.want)
+		}
+	}
+
+	outcomes := []struct {
+		outcome deploymentOutcome
+		want    int
+	}{
+		{outcomeCompleted, 0},
+		{outcomeFailed, 2},
+		{outcomeCancelled, 3},
+		{outcomeTimedOut, 6},
+		{outcomeInterrupted, 3},
+	}
+	for _, tt := range outcomes {
+		if got := tt.outcome.exitCode(); got != tt.want {
+			t.Errorf("outcome %d exits with %d, want %d", tt.outcome, got, tt.want)
+		}
+	}
+}
+
+func TestCombinedOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses map[string]string
+		want     deploymentOutcome
+	}{
+		{"all completed", map[string]string{"d1": "COMPLETED", "d2": "COMPLETED"}, outcomeCompleted},
+		{"one cancelled", map[string]string{"d1": "COMPLETED", "d2": "CANCELLED"}, outcomeCancelled},
+		{"failure wins", map[string]string{"d1": "CANCELLED", "d2": "FAILED", "d3": "COMPLETED"}, outcomeFailed},
+		{"logs not followed", map[string]string{"d1": "COMPLETED", "d2": ""}, outcomeFailed},
+	}
+	for _, tt := range tests {
+		if got := combinedOutcome(tt.statuses); got != tt.want {
+			t.Errorf("%s: combinedOutcome = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStopOnInterruptOrTimeout(t *testing.T) {
+	stopChan, stopReason, release := stopOnInterruptOrTimeout(10 * time.Millisecond)
+	defer release()
+
+	select {
+	case <-stopChan:
+	case <-time.After(time.Second):
+		t.Fatal("stopChan wasn't closed after the timeout")
+	}
+	if reason := <-stopReason; reason != outcomeTimedOut {
+		t.Errorf("reason = %d, want outcomeTimedOut", reason)
+	}
+
+	// Released before anything happens, nothing is stopped
+	stopChan, stopReason, release = stopOnInterruptOrTimeout(time.Hour)
+	release()
+	release()
+	select {
+	case <-stopChan:
+		t.Error("stopChan was closed after release")
+	case reason := <-stopReason:
+		t.Errorf("reason = %d after release, want none", reason)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
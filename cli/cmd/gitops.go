@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/velgardey/yok/cli/internal/git"
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+// ensureGitRepo initializes a git repository in the current directory if there isn't one
+func ensureGitRepo() error {
+	if git.IsRepo() {
+		return nil
+	}
+
+	utils.InfoColor.Print("No Git repository found. Initializing... ")
+	if err := git.InitRepo(); err != nil {
+		fmt.Println()
+		return err
+	}
+	utils.SuccessColor.Println("Done")
+	return nil
+}
+
+// handleUncommittedChanges checks for uncommitted changes and offers to commit and push them
+func handleUncommittedChanges() error {
+	if !git.HasUncommittedChanges() {
+		return nil // No changes to handle
+	}
+
+	// Show uncommitted changes
+	statusOutput, err := git.ExecuteCommand("status", "--porcelain")
+	if err != nil {
+		return fmt.Errorf("failed to check for uncommitted changes: %w", err)
+	}
+
+	fmt.Println("Uncommitted changes detected:")
+	fmt.Println(statusOutput)
+
+	// Ask user if they want to commit changes
+	if !confirmCommitChanges() {
+		return fmt.Errorf("you have uncommitted changes")
+	}
+
+	// Get commit message
+	commitMessage, err := getCommitMessage()
+	if err != nil {
+		return err
+	}
+
+	// Perform git operations
+	return commitAndPushChanges(commitMessage)
+}
+
+// confirmCommitChanges asks user if they want to commit changes
+func confirmCommitChanges() bool {
+	opts := utils.GetSurveyOptions()
+
+	var commitChanges bool
+	prompt := &survey.Confirm{
+		Message: "Do you want to commit and push these changes before deploying?",
+		Default: true,
+	}
+
+	if err := survey.AskOne(prompt, &commitChanges, opts); err != nil {
+		return false
+	}
+
+	return commitChanges
+}
+
+// commitAndPushChanges performs the git add, commit, and push operations, reporting each step
+func commitAndPushChanges(commitMessage string) error {
+	steps := []struct {
+		message string
+		run     func() error
+	}{
+		{"[+] Adding changes... ", git.AddAll},
+		{"[*] Committing changes... ", func() error { return git.Commit(commitMessage) }},
+		{"[^] Pushing to remote... ", git.Push},
+	}
+
+	for _, step := range steps {
+		utils.InfoColor.Print(step.message)
+		if err := step.run(); err != nil {
+			fmt.Println()
+			return err
+		}
+		utils.SuccessColor.Println("Done")
+	}
+
+	return nil
+}
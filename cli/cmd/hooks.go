@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/velgardey/yok/cli/internal/config"
+	"github.com/velgardey/yok/cli/internal/git"
+	"github.com/velgardey/yok/cli/internal/run"
+	"github.com/velgardey/yok/cli/internal/types"
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+// defaultHookTimeout limits how long each hook may run when the config doesn't set a timeout
+const defaultHookTimeout = 10 * time.Minute
+
+// preDeployHooks returns the pre-deploy hooks from the config
+func preDeployHooks(conf types.Config) []string {
+	if conf.Hooks == nil {
+		return nil
+	}
+	return conf.Hooks.PreDeploy
+}
+
+// runPreDeployHooks runs the configured pre-deploy hooks and returns an error naming the first
+// hook that fails
+func runPreDeployHooks() error {
+	conf, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %v", err)
+	}
+
+	hooks := preDeployHooks(conf)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	if err := runHooks("pre-deploy", hooks, conf.Hooks.Timeout, nil); err != nil {
+		return err
+	}
+
+	utils.SuccessColor.Println("[OK] Pre-deploy hooks passed")
+	return nil
+}
+
+// runPostDeployHooks runs the postDeploy hooks after a completed deployment, or the onFailure hooks
+// after a failed or cancelled one, with the deployment exposed through YOK_DEPLOYMENT_* variables.
+// Failures are reported but don't affect the outcome of the deployment.
+func runPostDeployHooks(outcome deploymentOutcome, deploymentID string, deploymentURL string) {
+	conf, err := config.LoadConfig()
+	if err != nil || conf.Hooks == nil {
+		return
+	}
+
+	var kind, status string
+	var hooks []string
+	switch outcome {
+	case outcomeCompleted:
+		kind, status, hooks = "post-deploy", "COMPLETED", conf.Hooks.PostDeploy
+	case outcomeFailed:
+		kind, status, hooks = "on-failure", "FAILED", conf.Hooks.OnFailure
+	case outcomeCancelled:
+		kind, status, hooks = "on-failure", "CANCELLED", conf.Hooks.OnFailure
+	default:
+		// The deployment hasn't finished, e.g. waiting timed out
+		return
+	}
+
+	if len(hooks) == 0 {
+		return
+	}
+
+	if deploymentURL == "" {
+		deploymentURL = fmt.Sprintf("https://%s.yok.ninja", deploymentID)
+	}
+
+	env := []string{
+		"YOK_DEPLOYMENT_ID=" + deploymentID,
+		"YOK_DEPLOYMENT_STATUS=" + status,
+		"YOK_DEPLOYMENT_URL=" + deploymentURL,
+	}
+
+	fmt.Println()
+	if err := runHooks(kind, hooks, conf.Hooks.Timeout, env); err != nil {
+		utils.WarnColor.Printf("Warning: %v\n", err)
+	}
+}
+
+// runHooks runs hooks in order from the repository root, streaming their output, and returns an
+// error naming the first hook that fails
+func runHooks(kind string, hooks []string, timeoutSetting string, env []string) error {
+	timeout := defaultHookTimeout
+	if timeoutSetting != "" {
+		var err error
+		timeout, err = time.ParseDuration(timeoutSetting)
+		if err != nil {
+			return fmt.Errorf("invalid hook timeout %q: %w", timeoutSetting, err)
+		}
+	}
+
+	// Run hooks from the repository root, falling back to the current directory
+	dir, err := git.RootDir()
+	if err != nil {
+		dir = "."
+	}
+
+	for i, hook := range hooks {
+		utils.InfoColor.Printf("[%d/%d] Running %s hook: %s\n", i+1, len(hooks), kind, hook)
+		if err := run.Shell(hook, dir, env, timeout, os.Stdout, os.Stderr); err != nil {
+			return fmt.Errorf("%s hook %q %v", kind, hook, err)
+		}
+	}
+
+	return nil
+}
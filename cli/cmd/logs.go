@@ -1,8 +1,9 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
-	"os/signal"
+	"sync"
 
 	"github.com/spf13/cobra"
 	"github.com/velgardey/yok/cli/internal/api"
@@ -23,7 +24,9 @@ Examples:
   yok logs abc123 -f          # Follow logs for deployment with ID abc123
   yok logs -t                 # View logs without timestamps
   yok logs -c                 # View logs without colors
-  yok logs -r                 # View raw logs (no formatting)`,
+  yok logs -r                 # View raw logs (no formatting)
+  yok logs --tail 50          # View only the last 50 log lines
+  yok logs --all-active       # Follow logs for every in-progress deployment at once`,
 	Run: runLogs,
 }
 
@@ -36,8 +39,13 @@ func init() {
 	logsCmd.Flags().BoolP("no-color", "c", false, "Disable colored output")
 	logsCmd.Flags().BoolP("raw", "r", false, "Display raw logs without formatting")
 	logsCmd.Flags().BoolP("wait", "w", false, "Wait for completion (automatically exit when deployment completes)")
+	logsCmd.Flags().Int("tail", 0, "Show only the last N log lines (0 shows all)")
+	logsCmd.Flags().Bool("all-active", false, "Follow logs for all pending, queued, and in-progress deployments")
 }
 
+// maxConcurrentLogStreams bounds how many deployments are streamed at once with --all-active
+const maxConcurrentLogStreams = 4
+
 // runLogs handles the logs command logic
 func runLogs(cmd *cobra.Command, args []string) {
 	// Get flags
@@ -45,11 +53,28 @@ func runLogs(cmd *cobra.Command, args []string) {
 	noTimestamps, _ := cmd.Flags().GetBool("no-timestamps")
 	noColor, _ := cmd.Flags().GetBool("no-color")
 	rawOutput, _ := cmd.Flags().GetBool("raw")
+	allActive, _ := cmd.Flags().GetBool("all-active")
+	tail, _ := cmd.Flags().GetInt("tail")
+
+	if tail < 0 {
+		utils.HandleError(fmt.Errorf("--tail must not be negative"), "Invalid flag")
+	}
 
 	// Get project configuration
 	config, err := EnsureProjectID()
 	utils.HandleError(err, "Error setting up project")
 
+	// Follow every active deployment at once
+	if allActive {
+		newRenderer := func() *utils.LogRenderer {
+			return utils.NewLogRenderer().
+				WithTimestamps(!noTimestamps).
+				WithColors(!noColor).
+				WithRawOutput(rawOutput)
+		}
+		os.Exit(streamAllActiveLogs(config.ProjectID, newRenderer))
+	}
+
 	var deploymentID string
 
 	// If deployment ID is provided directly, use it
@@ -58,13 +83,13 @@ func runLogs(cmd *cobra.Command, args []string) {
 	} else {
 		// Otherwise, get a list of deployments and prompt user to select one
 		filter := func(d types.Deployment) bool { return true } // No filter - show all deployments
-		deploymentID, err = api.SelectDeploymentFromList(config.ProjectID, filter)
-		utils.HandleError(err, "Error selecting deployment")
+		deploymentID, err = selectDeploymentFromList(config.ProjectID, filter)
+		handleAPIError(err, "Error selecting deployment")
 	}
 
 	// Get deployment details
 	deployment, err := api.GetDeploymentStatus(deploymentID)
-	utils.HandleError(err, "Error fetching deployment details")
+	handleAPIError(err, "Error fetching deployment details")
 
 	// Display deployment information
 	utils.InfoColor.Printf("Viewing logs for deployment: %s\n", deploymentID)
@@ -90,50 +115,22 @@ func runLogs(cmd *cobra.Command, args []string) {
 		WithColors(!noColor).
 		WithRawOutput(rawOutput)
 
-	// Set log renderer for streaming
-	api.SetLogRenderer(logRenderer)
+	// Set log renderer and tail for streaming
+	followLogRenderer = logRenderer
+	followLogTail = tail
 
 	// For completed deployments, we may not want to follow logs
 	if follow && (deployment.Status != "COMPLETED" || cmd.Flags().Changed("follow")) {
-		utils.InfoColor.Println("Following logs (Press Ctrl+C to stop)...")
-
-		// Create stop channel for log streaming
-		stopChan := make(chan bool)
-
-		// Set up a signal handler for Ctrl+C
-		signalChan := make(chan os.Signal, 1)
-		signal.Notify(signalChan, os.Interrupt)
-
-		// Start a goroutine to handle Ctrl+C
-		go func() {
-			<-signalChan
-			stopChan <- true
-		}()
-
-		// Stream logs and get completion status
-		deploymentSucceeded := api.StreamDeploymentLogs(deploymentID, stopChan)
-
-		// Show URLs and exit with appropriate code based on completion status
-		if deploymentSucceeded {
-			showDeploymentUrls(config.ProjectID, deploymentID, deployment.DeploymentUrl)
-			os.Exit(0)
-		} else {
-			// Check if deployment actually failed or was just interrupted
-			status, err := api.GetDeploymentStatus(deploymentID)
-			if err == nil && status.Status == "FAILED" {
-				utils.ErrorColor.Println("Deployment failed. Check the logs above for detailed error messages.")
-				os.Exit(1)
-			}
-		}
-
-		return
+		// Post-deploy hooks only run for deployments started by yok deploy
+		outcome := handleDeploymentFollowUp(true, deploymentID, deployment.DeploymentUrl, config.ProjectID, 0, false)
+		os.Exit(outcome.exitCode())
 	}
 
 	// For non-follow mode, just fetch and display logs once
 	logs, err := api.GetDeploymentLogs(deploymentID, "")
-	utils.HandleError(err, "Error fetching logs")
+	handleAPIError(err, "Error fetching logs")
 
-	for _, logEntry := range logs.Data.Logs {
+	for _, logEntry := range utils.TailLogEntries(logs.Data.Logs, tail) {
 		logRenderer.RenderLogEntry(logEntry)
 	}
 
@@ -142,9 +139,105 @@ func runLogs(cmd *cobra.Command, args []string) {
 	case "COMPLETED":
 		utils.SuccessColor.Println("\nDeployment completed successfully.")
 		showDeploymentUrls(config.ProjectID, deploymentID, deployment.DeploymentUrl)
-		os.Exit(0)
+		os.Exit(utils.ExitSuccess)
 	case "FAILED":
 		utils.ErrorColor.Println("\nDeployment failed. Check the logs above for detailed error messages.")
-		os.Exit(1)
+		os.Exit(utils.ExitDeploymentFailed)
+	}
+}
+
+// streamAllActiveLogs concurrently streams the logs of every active deployment of the project,
+// prefixing each line with a short deployment ID, and returns the exit code for the combined result
+func streamAllActiveLogs(projectID string, newRenderer func() *utils.LogRenderer) int {
+	deployments, err := api.ListDeployments(projectID)
+	handleAPIError(err, "Error fetching deployments")
+
+	var active []types.Deployment
+	for _, d := range deployments {
+		if d.Status == "PENDING" || d.Status == "QUEUED" || d.Status == "IN_PROGRESS" {
+			active = append(active, d)
+		}
+	}
+
+	if len(active) == 0 {
+		utils.InfoColor.Println("No in-progress deployments found.")
+		return utils.ExitSuccess
+	}
+
+	utils.InfoColor.Printf("Following logs for %d active deployments (Press Ctrl+C to stop)...\n", len(active))
+
+	// Ctrl+C stops every stream at once, the same way it stops following a single deployment
+	stopChan, stopReason, release := stopOnInterruptOrTimeout(0)
+	defer release()
+
+	// Bound the number of concurrent streams
+	slots := make(chan struct{}, maxConcurrentLogStreams)
+	var mu sync.Mutex
+	statuses := make(map[string]string, len(active))
+
+	var wg sync.WaitGroup
+	for _, d := range active {
+		wg.Add(1)
+		go func(deploymentID string) {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-stopChan:
+				return
+			}
+
+			shortID := utils.TruncateString(deploymentID, 8)
+			status := streamDeploymentLogsWithRenderer(deploymentID, stopChan, newRenderer().WithLinePrefix(shortID))
+			mu.Lock()
+			statuses[deploymentID] = status
+			mu.Unlock()
+			if status == "" {
+				return
+			}
+
+			summary := fmt.Sprintf("%s | Deployment %s", shortID, status)
+			switch status {
+			case "COMPLETED":
+				utils.SuccessColor.Println(summary)
+			case "CANCELLED":
+				utils.WarnColor.Println(summary)
+			default:
+				utils.ErrorColor.Println(summary)
+			}
+		}(d.ID)
+	}
+	wg.Wait()
+
+	// Restore default Ctrl+C handling so a second interrupt exits immediately
+	release()
+
+	select {
+	case <-stopReason:
+		// Offer to cancel each deployment that is still running, as when following one
+		for _, d := range active {
+			if statuses[d.ID] == "" {
+				offerCancelDeployment(d.ID)
+			}
+		}
+		return outcomeInterrupted.exitCode()
+	default:
+		return combinedOutcome(statuses).exitCode()
+	}
+}
+
+// combinedOutcome is the outcome of following several deployments, by deployment ID: failed if
+// any failed or its logs couldn't be followed, otherwise cancelled if any was cancelled
+func combinedOutcome(statuses map[string]string) deploymentOutcome {
+	outcome := outcomeCompleted
+	for _, status := range statuses {
+		switch outcomeForStatus(status) {
+		case outcomeFailed:
+			return outcomeFailed
+		case outcomeCancelled:
+			outcome = outcomeCancelled
+		}
 	}
+	return outcome
 }
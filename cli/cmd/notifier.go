@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/velgardey/yok/cli/internal/config"
+	"github.com/velgardey/yok/cli/internal/types"
+	"github.com/velgardey/yok/cli/internal/utils"
+	"golang.org/x/term"
+)
+
+// noUpdateNotifierEnvVar silences the update notice when set to any value
+const noUpdateNotifierEnvVar = "YOK_NO_UPDATE_NOTIFIER"
+
+// updateNoticeInterval is how often the update notice is shown at most
+const updateNoticeInterval = 24 * time.Hour
+
+// updateNoticeWait is how long a finished command waits for the update check before giving up
+const updateNoticeWait = 500 * time.Millisecond
+
+// startUpdateNotifier checks for a newer release in the background if the user opted in with
+// checkUpdates in the user config. The returned channel receives the newer version, or is
+// closed without one if there is nothing to show.
+func startUpdateNotifier() <-chan string {
+	notice := make(chan string, 1)
+
+	userConfig, err := config.LoadUserConfig()
+	if err != nil || !userConfig.CheckUpdates || os.Getenv(noUpdateNotifierEnvVar) != "" ||
+		getCurrentVersion() == "dev" || !term.IsTerminal(int(os.Stderr.Fd())) ||
+		time.Since(userConfig.UpdateNoticeShownAt) < updateNoticeInterval {
+		close(notice)
+		return notice
+	}
+
+	go func() {
+		defer close(notice)
+
+		// Uses the cached release when it is recent, so this rarely talks to GitHub
+		latestVersion, hasUpdate, err := checkForUpdates(true, userConfig.Channel == config.ChannelBeta)
+		if err == nil && hasUpdate {
+			notice <- latestVersion
+		}
+	}()
+
+	return notice
+}
+
+// printUpdateNotice prints the update notice if the background check found a newer release in
+// time. It never fails the command.
+func printUpdateNotice(cmd *cobra.Command, notice <-chan string) {
+	// The update command reports new versions itself
+	if cmd == updateCmd {
+		return
+	}
+
+	var latestVersion string
+	select {
+	case latestVersion = <-notice:
+	case <-time.After(updateNoticeWait):
+	}
+	if latestVersion == "" {
+		return
+	}
+
+	fmt.Fprint(os.Stderr, utils.InfoColor.Sprintf("\nA new version v%s is available; run 'yok self-update'\n", latestVersion))
+
+	// Remember when the notice was shown so it appears at most once a day
+	config.UpdateUserConfig(func(userConfig *types.UserConfig) error {
+		userConfig.UpdateNoticeShownAt = time.Now()
+		return nil
+	})
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/velgardey/yok/cli/internal/api"
+	"github.com/velgardey/yok/cli/internal/utils"
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+// previewCmd represents the preview command
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Serve your build output locally the way Yok serves it",
+	Long: `Serve your build output on a local port using the same path handling as the Yok reverse proxy,
+so routing issues can be caught before deploying.
+
+Examples:
+  yok preview                 # Serve the detected build output directory on port 4173
+  yok preview --port 8080     # Serve on a different port
+  yok preview --dir site      # Serve a specific directory
+  yok preview --spa           # Serve index.html for client-side routes, like SPA fallback`,
+	Args: cobra.NoArgs,
+	Run:  runPreview,
+}
+
+func init() {
+	RootCmd.AddCommand(previewCmd)
+
+	// Add flags
+	previewCmd.Flags().IntP("port", "p", 4173, "Port to serve the preview on")
+	previewCmd.Flags().StringP("dir", "d", "", "Directory to serve (defaults to the detected build output directory)")
+	previewCmd.Flags().Bool("no-open", false, "Don't open the preview in the browser")
+	previewCmd.Flags().Bool("spa", false, "Serve index.html for missing pages, like deployments with SPA fallback enabled")
+}
+
+// runPreview handles the preview command logic
+func runPreview(cmd *cobra.Command, args []string) {
+	// Get flags
+	port, _ := cmd.Flags().GetInt("port")
+	dir, _ := cmd.Flags().GetString("dir")
+	noOpen, _ := cmd.Flags().GetBool("no-open")
+	spaFallback, _ := cmd.Flags().GetBool("spa")
+
+	if dir == "" {
+		dir = api.DetectOutputDir()
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		utils.HandleError(fmt.Errorf("%s is not a directory", dir), "Error starting preview")
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	utils.HandleError(err, "Error starting preview")
+
+	// Serve the files from a loopback origin that behaves like the object store, and put the
+	// reverse proxy's handler in front of it
+	originListener, err := net.Listen("tcp", "127.0.0.1:0")
+	utils.HandleError(err, "Error starting preview")
+	go http.Serve(originListener, newPreviewOrigin(dir))
+	originURL := "http://" + originListener.Addr().String() + "/"
+
+	previewURL := fmt.Sprintf("http://localhost:%d", port)
+	utils.SuccessColor.Printf("[OK] Serving %s at %s\n", dir, previewURL)
+	utils.InfoColor.Println("Press Ctrl+C to stop")
+
+	if !noOpen {
+		if err := utils.OpenBrowser(previewURL); err != nil {
+			utils.WarnColor.Printf("Warning: Could not open browser: %v\n", err)
+		}
+	}
+
+	err = http.Serve(listener, newPreviewHandler(originURL, spaFallback))
+	utils.HandleError(err, "Error serving preview")
+}
+
+// newPreviewHandler returns the reverse proxy's handler serving the objects at originURL, with
+// SPA fallback if spaFallback is set
+func newPreviewHandler(originURL string, spaFallback bool) http.Handler {
+	return proxy.Handler(func(r *http.Request) (proxy.Target, error) {
+		return proxy.Target{BasePath: originURL, SPAFallback: spaFallback}, nil
+	})
+}
+
+// newPreviewOrigin serves the files in dir like an object store would, without path rewriting
+func newPreviewOrigin(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveObject(w, r, dir, r.URL.Path)
+	})
+}
+
+// serveObject serves the file at urlPath within dir like an object store would, without
+// directory listings or index redirects. Paths leading out of dir, through .. segments or
+// symlinks, are not found.
+func serveObject(w http.ResponseWriter, r *http.Request, dir string, urlPath string) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		http.NotFound(w, r)
+		return
+	}
+	file, err := os.OpenInRoot(dir, filepath.FromSlash(name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePreviewFiles creates the files, given by their slash-separated path, in dir
+func writePreviewFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPreviewOriginStaysInDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "dist")
+	if err := os.MkdirAll(filepath.Join(dir, "assets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(dir, "index.html"):       "<html></html>",
+		filepath.Join(dir, "assets", "app.js"): "console.log(1)",
+		filepath.Join(root, "secret.txt"):      "secret",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "secret.txt"), filepath.Join(dir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/index.html", http.StatusOK},
+		{"/assets/app.js", http.StatusOK},
+		{"/assets/../index.html", http.StatusOK},
+		{"/../secret.txt", http.StatusNotFound},
+		{"/assets/../../secret.txt", http.StatusNotFound},
+		{"/../../../../etc/hostname", http.StatusNotFound},
+		{"/link.txt", http.StatusNotFound},
+		{"/assets", http.StatusNotFound},
+		{"/", http.StatusNotFound},
+		{"/missing.html", http.StatusNotFound},
+	}
+
+	origin := newPreviewOrigin(dir)
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			// The request is handed to the origin directly, so its path isn't cleaned by a ServeMux
+			req := httptest.NewRequest(http.MethodGet, "http://localhost"+tt.path, nil)
+			req.URL.Path = tt.path
+			rec := httptest.NewRecorder()
+			origin.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+			}
+			if rec.Body.String() == "secret" {
+				t.Errorf("GET %s served a file outside the preview directory", tt.path)
+			}
+		})
+	}
+}
+
+func TestPreviewServesLikeTheProxy(t *testing.T) {
+	dir := t.TempDir()
+	writePreviewFiles(t, dir, map[string]string{
+		"index.html":      "home",
+		"404.html":        "not found page",
+		"docs/index.html": "docs",
+		"assets/app.js":   "app",
+	})
+	origin := httptest.NewServer(newPreviewOrigin(dir))
+	t.Cleanup(origin.Close)
+	handler := newPreviewHandler(origin.URL+"/", false)
+
+	tests := []struct {
+		path         string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		{"/", http.StatusOK, "home", ""},
+		{"/assets/app.js", http.StatusOK, "app", ""},
+		{"/docs/", http.StatusOK, "docs", ""},
+		{"/docs", http.StatusMovedPermanently, "", "/docs/"},
+		{"/missing.js", http.StatusNotFound, "not found page", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost"+tt.path, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rec.Body.String()) != tt.wantBody {
+				t.Errorf("GET %s body = %q, want %q", tt.path, rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("GET %s Location = %q, want %q", tt.path, got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestPreviewSPAFallback(t *testing.T) {
+	dir := t.TempDir()
+	writePreviewFiles(t, dir, map[string]string{
+		"index.html": "home",
+		"404.html":   "not found page",
+	})
+	origin := httptest.NewServer(newPreviewOrigin(dir))
+	t.Cleanup(origin.Close)
+
+	tests := []struct {
+		spa      bool
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{false, "/dashboard/settings", http.StatusNotFound, "not found page"},
+		{true, "/dashboard/settings", http.StatusOK, "home"},
+		// Missing files are never answered with the page
+		{true, "/missing.js", http.StatusNotFound, "not found page"},
+	}
+	for _, tt := range tests {
+		handler := newPreviewHandler(origin.URL+"/", tt.spa)
+		req := httptest.NewRequest(http.MethodGet, "http://localhost"+tt.path, nil)
+		req.Header.Set("Accept", "text/html")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantCode || strings.TrimSpace(rec.Body.String()) != tt.wantBody {
+			t.Errorf("spa=%v: GET %s = %d %q, want %d %q", tt.spa, tt.path, rec.Code, rec.Body.String(), tt.wantCode, tt.wantBody)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/velgardey/yok/cli/internal/api"
@@ -12,122 +13,236 @@ import (
 
 // EnsureProjectID loads config and ensures a project ID exists, creating a project if needed
 func EnsureProjectID() (types.Config, error) {
+	return ensureProjectID(nil)
+}
+
+// ensureProjectIDFromFlags is EnsureProjectID for commands with the --name and --repo flags,
+// which set up the project without prompting when given
+func ensureProjectIDFromFlags(cmd *cobra.Command) (types.Config, error) {
+	details, err := projectDetailsFromFlags(cmd)
+	if err != nil {
+		return types.Config{}, err
+	}
+	return ensureProjectID(details)
+}
+
+// ensureProjectID loads config and ensures a project ID exists, setting up a project from
+// details, or from the user's answers if details is nil, if needed
+func ensureProjectID(details *types.Project) (types.Config, error) {
 	// Load config to check if we have a stored project ID
 	conf, err := config.LoadConfig()
 	if err != nil {
 		return conf, fmt.Errorf("error loading configuration: %v", err)
 	}
 
+	if conf.ProjectID != "" {
+		if details != nil {
+			utils.InfoColor.Println("Ignoring --name and --repo: this directory already has a project")
+		}
+		utils.InfoColor.Printf("Using stored project ID for: %s\n", conf.RepoName)
+		return conf, nil
+	}
+
 	// If no stored project ID, we need to create/find one
-	if conf.ProjectID == "" {
-		projectName, repoURL, framework, existingProject, usingExisting, err := api.PromptForProjectCreationDetails()
+	project, usingExisting, err := setUpProject(details)
+	if err != nil {
+		return conf, err
+	}
+
+	if usingExisting {
+		utils.SuccessColor.Printf("[OK] Using existing project: %s\n", project.Name)
+	} else {
+		utils.SuccessColor.Printf("✅ Using project: %s\n", project.Name)
+	}
+
+	// Save project ID for future use
+	conf, err = saveProjectConfig(conf, project)
+	if err != nil {
+		utils.WarnColor.Printf("Warning: Could not save project ID: %v\n", err)
+	}
+
+	return conf, nil
+}
+
+// setUpProject returns either the existing project the user chose to use or the project created
+// for them, reporting which of the two it is. The project details are prompted for unless given.
+func setUpProject(details *types.Project) (*types.Project, bool, error) {
+	if details != nil {
+		existingProject, err := api.FindProjectByName(details.Name)
+		if err != nil {
+			return nil, false, fmt.Errorf("error checking if project exists: %v", err)
+		}
+		if existingProject != nil {
+			if details.GitRepoURL != "" {
+				utils.InfoColor.Printf("Project '%s' already exists, using it and ignoring --repo\n", details.Name)
+			}
+			return existingProject, true, nil
+		}
+		if err := completeProjectDetails(details); err != nil {
+			return nil, false, err
+		}
+	} else {
+		var usingExisting bool
+		var err error
+		details, usingExisting, err = promptForProjectCreationDetails()
 		if err != nil {
-			return conf, err
+			return nil, false, err
 		}
 
 		if usingExisting {
-			// Use existing project
-			utils.SuccessColor.Printf("[OK] Using existing project: %s\n", existingProject.Name)
+			return details, true, nil
+		}
+	}
 
-			// Save project ID for future use
-			conf.ProjectID = existingProject.ID
-			conf.RepoName = existingProject.Name
-			if err := config.SaveConfig(conf); err != nil {
-				utils.WarnColor.Printf("Warning: Could not save project ID: %v\n", err)
-			}
+	// Create or get existing project (double-check since another user might have created it)
+	s := utils.StartSpinner("Creating project on Yok...")
+	project, err := api.GetOrCreateProject(details.Name, details.GitRepoURL, details.Framework, details.RootDir)
+	utils.StopSpinner(s)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating project: %v", err)
+	}
 
-			return conf, nil
-		}
+	return project, false, nil
+}
+
+// addProjectFlags adds the flags setting up a new project without prompting to cmd
+func addProjectFlags(cmd *cobra.Command) {
+	cmd.Flags().String("name", "", "Name of the project to create if this directory has none, without prompting")
+	cmd.Flags().String("repo", "", "Git repository URL of the project created with --name (default: the origin remote)")
+}
 
-		// Repository URL and framework are now handled in PromptForProjectCreationDetails
-		// No additional processing needed here
+// projectDetailsFromFlags returns the project given with --name and --repo, or nil if neither
+// was given
+func projectDetailsFromFlags(cmd *cobra.Command) (*types.Project, error) {
+	name, _ := cmd.Flags().GetString("name")
+	repoURL, _ := cmd.Flags().GetString("repo")
+	name, repoURL = strings.TrimSpace(name), strings.TrimSpace(repoURL)
+	if name == "" && repoURL == "" {
+		return nil, nil
+	}
+	if name == "" {
+		return nil, fmt.Errorf("--repo requires --name")
+	}
+	return &types.Project{Name: name, GitRepoURL: repoURL}, nil
+}
 
-		// Create or get existing project (double-check since another user might have created it)
-		project, err := api.GetOrCreateProject(projectName, repoURL, framework)
+// completeProjectDetails fills in what wasn't given for a project created without prompting:
+// the repository from the origin remote and the detected framework
+func completeProjectDetails(details *types.Project) error {
+	if details.GitRepoURL == "" {
+		repoURL, err := autoDetectRepoURL()
 		if err != nil {
-			return conf, fmt.Errorf("error creating project: %v", err)
+			return fmt.Errorf("%v; pass the repository URL with --repo", err)
 		}
+		details.GitRepoURL = repoURL
+	}
+	details.Framework = detectProjectFramework("")
+	return nil
+}
 
-		utils.SuccessColor.Printf("✅ Using project: %s\n", project.Name)
+// saveProjectConfig stores the project in conf and saves it for future commands
+func saveProjectConfig(conf types.Config, project *types.Project) (types.Config, error) {
+	conf.ProjectID = project.ID
+	conf.RepoName = project.Name
+	return conf, config.SaveConfig(conf)
+}
+
+// printProjectInfo displays the details of a project
+func printProjectInfo(project *types.Project) {
+	fmt.Println("\nProject Information:")
+	fmt.Printf("ID: %s\n", project.ID)
+	if project.Partial {
+		utils.WarnColor.Println("Project details unavailable: the API server only confirmed this project has deployments")
+		return
+	}
+	fmt.Printf("Name: %s\n", project.Name)
+	fmt.Printf("Framework: %s\n", project.Framework)
+	fmt.Printf("Slug: %s\n", project.Slug)
+	fmt.Printf("Git URL: %s\n", project.GitRepoURL)
+	if project.RootDir != "" {
+		fmt.Printf("Root directory: %s\n", project.RootDir)
+	}
+	if project.Slug != "" {
+		fmt.Printf("Project URL: https://%s.yok.ninja\n", project.Slug)
+	}
+}
 
-		// Save project ID for future use
-		conf.ProjectID = project.ID
-		conf.RepoName = project.Name
-		if err := config.SaveConfig(conf); err != nil {
-			utils.WarnColor.Printf("Warning: Could not save project ID: %v\n", err)
+// lookUpProject returns the project named or with the ID nameOrID, or the project configured for
+// this directory if nameOrID is empty
+func lookUpProject(nameOrID string) (*types.Project, error) {
+	if nameOrID == "" {
+		conf, err := config.LoadConfig()
+		if err != nil {
+			return nil, fmt.Errorf("error loading configuration: %v", err)
 		}
-	} else {
-		utils.InfoColor.Printf("Using stored project ID for: %s\n", conf.RepoName)
+		if conf.ProjectID == "" {
+			return nil, fmt.Errorf("no project configured for this directory; run 'yok create' or pass a project name or ID")
+		}
+		return api.GetProject(conf.ProjectID)
 	}
 
-	return conf, nil
+	project, err := api.FindProjectByName(nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	if project != nil {
+		return project, nil
+	}
+	return api.GetProject(nameOrID)
 }
 
 func init() {
+	// Project command grouping commands about projects
+	var projectCmd = &cobra.Command{
+		Use:   "project",
+		Short: "Inspect your Yok projects",
+	}
+
+	projectCmd.AddCommand(&cobra.Command{
+		Use:   "show [name-or-id]",
+		Short: "Show the details of a project",
+		Long: `Show the slug, framework, repository and URL of a project, looked up by name or ID.
+Without an argument, the project configured for this directory is shown.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			nameOrID := ""
+			if len(args) > 0 {
+				nameOrID = strings.TrimSpace(args[0])
+			}
+
+			s := utils.StartSpinner("Looking up project...")
+			project, err := lookUpProject(nameOrID)
+			utils.StopSpinner(s)
+			if err != nil {
+				handleAPIError(err, "Error looking up project")
+			}
+
+			printProjectInfo(project)
+		},
+	})
+
 	// Create command for creating a new project
 	var createCmd = &cobra.Command{
 		Use:   "create",
 		Short: "Create a new project on Yok",
 		Run: func(cmd *cobra.Command, args []string) {
-			projectName, repoURL, framework, existingProject, usingExisting, err := api.PromptForProjectCreationDetails()
+			details, err := projectDetailsFromFlags(cmd)
+			utils.HandleError(err, "Invalid project flags")
+			project, usingExisting, err := setUpProject(details)
 			utils.HandleError(err, "Error getting project details")
 
 			if usingExisting {
-				// Display project info and save the project ID
 				utils.SuccessColor.Printf("[OK] Using existing project\n")
-
-				// Display comprehensive project info
-				fmt.Println("\nProject Information:")
-				fmt.Printf("ID: %s\n", existingProject.ID)
-				fmt.Printf("Name: %s\n", existingProject.Name)
-				fmt.Printf("Framework: %s\n", existingProject.Framework)
-				fmt.Printf("Slug: %s\n", existingProject.Slug)
-				fmt.Printf("Git URL: %s\n", existingProject.GitRepoURL)
-				if existingProject.Slug != "" {
-					fmt.Printf("Project URL: https://%s.yok.ninja\n", existingProject.Slug)
-				}
-
-				// Save project ID
-				conf := types.Config{
-					ProjectID: existingProject.ID,
-					RepoName:  existingProject.Name,
-				}
-				err = config.SaveConfig(conf)
-				if err != nil {
-					utils.WarnColor.Printf("Warning: Could not save project ID: %v\n", err)
-				} else {
-					utils.SuccessColor.Println("\n[OK] Project ID saved for future deployments")
-				}
-				return
+			} else {
+				utils.SuccessColor.Printf("[OK] Project created/updated successfully\n")
 			}
 
-			// Repository URL and framework are already obtained from PromptForProjectCreationDetails
-			// No additional processing needed
-
-			// Create or get existing project
-			project, err := api.GetOrCreateProject(projectName, repoURL, framework)
-			utils.HandleError(err, "Error creating project")
-
-			utils.SuccessColor.Printf("[OK] Project created/updated successfully\n")
-
 			// Display comprehensive project info
-			fmt.Println("\nProject Information:")
-			fmt.Printf("ID: %s\n", project.ID)
-			fmt.Printf("Name: %s\n", project.Name)
-			fmt.Printf("Framework: %s\n", project.Framework)
-			fmt.Printf("Slug: %s\n", project.Slug)
-			fmt.Printf("Git URL: %s\n", project.GitRepoURL)
-			if project.Slug != "" {
-				fmt.Printf("Project URL: https://%s.yok.ninja\n", project.Slug)
-			}
+			printProjectInfo(project)
 
-			// Save project ID
-			conf := types.Config{
-				ProjectID: project.ID,
-				RepoName:  project.Name,
-			}
-			err = config.SaveConfig(conf)
-			if err != nil {
+			// Save project ID, keeping any other settings such as hooks
+			conf, _ := config.LoadConfig()
+			if _, err := saveProjectConfig(conf, project); err != nil {
 				utils.WarnColor.Printf("Warning: Could not save project ID: %v\n", err)
 			} else {
 				utils.SuccessColor.Println("\n[OK] Project ID saved for future deployments")
@@ -135,6 +250,8 @@ func init() {
 		},
 	}
 
+	addProjectFlags(createCmd)
+
 	// Reset config command
 	var resetCmd = &cobra.Command{
 		Use:     "reset",
@@ -151,5 +268,5 @@ func init() {
 	}
 
 	// Add commands to root
-	RootCmd.AddCommand(createCmd, resetCmd)
+	RootCmd.AddCommand(projectCmd, createCmd, resetCmd)
 }
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+	"github.com/velgardey/yok/cli/internal/api"
+	"github.com/velgardey/yok/cli/internal/types"
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+// promoteCmd represents the promote command
+var promoteCmd = &cobra.Command{
+	Use:   "promote [deploymentId]",
+	Short: "Point the project URL at a specific deployment",
+	Long: `Point the project's slug URL at a specific completed deployment.
+
+This lets you deploy, verify the deployment on its own URL, and then flip production to it.
+If no deployment ID is provided, you'll be prompted to select from completed deployments.
+
+While a deployment is promoted, new deployments are only served on their own URLs. Use
+--latest to remove the promotion so the project URL serves the latest deployment again.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runPromote,
+}
+
+func init() {
+	RootCmd.AddCommand(promoteCmd)
+
+	// Add flags
+	promoteCmd.Flags().BoolP("yes", "y", false, "Promote without asking for confirmation")
+	promoteCmd.Flags().Bool("latest", false, "Remove the promotion so the project URL serves the latest deployment")
+}
+
+// runPromote handles the promote command logic
+func runPromote(cmd *cobra.Command, args []string) {
+	// Get flags
+	skipConfirm, _ := cmd.Flags().GetBool("yes")
+
+	// Get project configuration
+	config, err := EnsureProjectID()
+	utils.HandleError(err, "Error setting up project")
+
+	if latest, _ := cmd.Flags().GetBool("latest"); latest {
+		if len(args) > 0 {
+			utils.HandleError(fmt.Errorf("got deployment ID %s", args[0]), "--latest can't be combined with a deployment ID")
+		}
+		promoteLatest(config.ProjectID, skipConfirm)
+		return
+	}
+
+	var deploymentID string
+
+	// If deployment ID is provided directly, use it
+	if len(args) > 0 {
+		deploymentID = args[0]
+	} else {
+		// Only completed deployments can be promoted
+		deploymentID, err = selectDeploymentFromList(config.ProjectID, func(d types.Deployment) bool {
+			return d.Status == "COMPLETED"
+		})
+		if err != nil {
+			if errors.Is(err, errNoMatchingDeployments) {
+				utils.InfoColor.Println("No completed deployments found to promote.")
+				return
+			}
+			utils.HandleError(err, "Error selecting deployment")
+		}
+	}
+
+	// Verify the deployment can be promoted
+	deployment, err := api.GetDeploymentStatus(deploymentID)
+	handleAPIError(err, "Error fetching deployment details")
+
+	if deployment.Status != "COMPLETED" {
+		utils.HandleError(fmt.Errorf("deployment %s is %s", deploymentID, deployment.Status), "Only completed deployments can be promoted")
+	}
+
+	// Show what is currently promoted
+	project, err := api.GetProject(config.ProjectID)
+	utils.HandleError(err, "Error fetching project details")
+
+	if project.PromotedDeploymentID == deploymentID {
+		utils.InfoColor.Printf("Deployment %s is already promoted.\n", deploymentID)
+		return
+	}
+
+	if project.Partial {
+		utils.InfoColor.Println("Currently promoted: unknown (project details unavailable)")
+	} else if project.PromotedDeploymentID != "" {
+		utils.InfoColor.Printf("Currently promoted: %s\n", project.PromotedDeploymentID)
+	} else {
+		utils.InfoColor.Println("Currently promoted: latest deployment")
+	}
+
+	// Confirm promotion unless skipped
+	if !skipConfirm {
+		confirm := false
+		promotePrompt := &survey.Confirm{
+			Message: fmt.Sprintf("Promote deployment %s to production?", deploymentID),
+			Default: false,
+		}
+		opts := utils.GetSurveyOptions()
+		survey.AskOne(promotePrompt, &confirm, opts)
+
+		if !confirm {
+			utils.InfoColor.Println("Promotion aborted.")
+			return
+		}
+	}
+
+	// Promote the deployment
+	s := utils.StartSpinner("Promoting deployment...")
+	err = api.PromoteDeployment(config.ProjectID, deploymentID)
+	utils.StopSpinner(s)
+	handleAPIError(err, "Error promoting deployment")
+
+	utils.SuccessColor.Printf("[OK] Deployment %s promoted\n", deploymentID)
+	utils.InfoColor.Printf("[i] Your site is available at:\n")
+	if project.Slug != "" {
+		fmt.Printf("- https://%s.yok.ninja\n", project.Slug)
+	}
+	if deployment.DeploymentUrl != "" {
+		fmt.Printf("- %s\n", deployment.DeploymentUrl)
+	} else {
+		fmt.Printf("- https://%s.yok.ninja\n", deploymentID)
+	}
+}
+
+// promoteLatest removes the project's promotion, so its slug serves the latest deployment again
+func promoteLatest(projectID string, skipConfirm bool) {
+	project, err := api.GetProject(projectID)
+	handleAPIError(err, "Error fetching project details")
+
+	if !project.Partial && project.PromotedDeploymentID == "" {
+		utils.InfoColor.Println("No deployment is promoted; the project URL already serves the latest deployment.")
+		return
+	}
+	if project.PromotedDeploymentID != "" {
+		utils.InfoColor.Printf("Currently promoted: %s\n", project.PromotedDeploymentID)
+	}
+
+	// Confirm unless skipped
+	if !skipConfirm {
+		confirm := false
+		prompt := &survey.Confirm{
+			Message: "Serve the latest deployment on the project URL instead?",
+			Default: false,
+		}
+		survey.AskOne(prompt, &confirm, utils.GetSurveyOptions())
+
+		if !confirm {
+			utils.InfoColor.Println("Promotion kept.")
+			return
+		}
+	}
+
+	s := utils.StartSpinner("Removing promotion...")
+	err = api.UnpromoteDeployment(projectID)
+	utils.StopSpinner(s)
+	handleAPIError(err, "Error removing promotion")
+
+	utils.SuccessColor.Println("[OK] The project URL now serves the latest deployment")
+	if project.Slug != "" {
+		fmt.Printf("- https://%s.yok.ninja\n", project.Slug)
+	}
+}
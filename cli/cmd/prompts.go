@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/velgardey/yok/cli/internal/api"
+	"github.com/velgardey/yok/cli/internal/config"
+	"github.com/velgardey/yok/cli/internal/git"
+	"github.com/velgardey/yok/cli/internal/types"
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+// errNoMatchingDeployments is returned by selectDeploymentFromList when no deployment passes the filter
+var errNoMatchingDeployments = errors.New("no matching deployments found")
+
+// selectDeploymentFromList prompts the user to select a deployment from a list
+// filter can be used to filter deployments by status (e.g. only in-progress deployments)
+// if filter is nil, all deployments are shown
+func selectDeploymentFromList(projectID string, filter func(types.Deployment) bool) (string, error) {
+	// Get recent deployments
+	deployments, err := api.ListDeployments(projectID)
+	if err != nil {
+		return "", fmt.Errorf("error fetching deployments: %w", err)
+	}
+
+	// Filter deployments if a filter is provided
+	filteredDeployments := []types.Deployment{}
+	if filter != nil {
+		for _, d := range deployments {
+			if filter(d) {
+				filteredDeployments = append(filteredDeployments, d)
+			}
+		}
+	} else {
+		filteredDeployments = deployments
+	}
+
+	if len(filteredDeployments) == 0 {
+		return "", errNoMatchingDeployments
+	}
+
+	sortDeploymentsForSelection(filteredDeployments)
+
+	// Create options for selection; the filter matches the plain text, not the colors
+	options := make([]string, len(filteredDeployments))
+	plainOptions := make([]string, len(filteredDeployments))
+	for i, d := range filteredDeployments {
+		id := d.ID[:8]
+		timeAgo := utils.FormatTimeAgo(d.CreatedAt)
+		created := d.CreatedAt.Format("Jan 02 15:04")
+		padding := strings.Repeat(" ", max(0, 12-len(d.Status)))
+		options[i] = fmt.Sprintf("%s  %s%s %-9s %s", id, utils.ColorizeStatus(d.Status), padding, timeAgo, utils.DimColor.Sprint(created))
+		plainOptions[i] = fmt.Sprintf("%s  %s%s %-9s %s", id, d.Status, padding, timeAgo, created)
+	}
+
+	var selected int
+	prompt := &survey.Select{
+		Message:  "Select a deployment (type to filter by ID or status):",
+		Options:  options,
+		PageSize: 10,
+		Filter: func(filter string, _ string, index int) bool {
+			return strings.Contains(strings.ToLower(plainOptions[index]), strings.ToLower(filter))
+		},
+	}
+	opts := utils.GetSurveyOptions()
+	if err := survey.AskOne(prompt, &selected, opts); err != nil {
+		return "", fmt.Errorf("deployment selection cancelled: %w", err)
+	}
+
+	return filteredDeployments[selected].ID, nil
+}
+
+// deploymentStatusGroup orders deployment statuses for selection: deployments still running
+// first, then successful, failed, and cancelled ones
+func deploymentStatusGroup(status string) int {
+	switch status {
+	case "PENDING", "QUEUED", "IN_PROGRESS":
+		return 0
+	case "COMPLETED":
+		return 1
+	case "FAILED":
+		return 2
+	case "CANCELLED":
+		return 3
+	default:
+		return 4
+	}
+}
+
+// sortDeploymentsForSelection groups deployments by status and sorts each group newest first
+func sortDeploymentsForSelection(deployments []types.Deployment) {
+	sortDeploymentsByCreatedDesc(deployments)
+	sort.SliceStable(deployments, func(i, j int) bool {
+		return deploymentStatusGroup(deployments[i].Status) < deploymentStatusGroup(deployments[j].Status)
+	})
+}
+
+// promptForProjectCreationDetails asks the user for a project name, checks if it exists, and
+// gets Git repo info and the build root. Returns either the existing project the user chose to
+// use or the details of the project to create, and a flag indicating which of the two it is.
+func promptForProjectCreationDetails() (*types.Project, bool, error) {
+	// Use centralized survey options to fix PowerShell echo issues
+	opts := utils.GetSurveyOptions()
+
+	// Get project name
+	var projectName string
+	prompt := &survey.Input{
+		Message: "Enter a name for your project:",
+	}
+
+	if err := survey.AskOne(prompt, &projectName, opts); err != nil {
+		return nil, false, fmt.Errorf("error getting project name: %v", err)
+	}
+
+	if projectName == "" {
+		return nil, false, fmt.Errorf("project name cannot be empty")
+	}
+
+	// Check if a project with this name already exists
+	existingProject, err := api.FindProjectByName(projectName)
+	if err != nil {
+		utils.WarnColor.Printf("Warning: Could not check if project exists: %v\n", err)
+		// Continue anyway, the creation step will fail if there's a duplicate
+	} else if existingProject != nil {
+		utils.InfoColor.Printf("Project with name '%s' already exists!\n", projectName)
+
+		// Ask if user wants to use the existing project
+		useExisting := false
+		confirmPrompt := &survey.Confirm{
+			Message: "Do you want to use this existing project?",
+			Default: true,
+		}
+		survey.AskOne(confirmPrompt, &useExisting, opts)
+
+		if useExisting {
+			// User wants to use the existing project
+			return existingProject, true, nil
+		}
+		// User chose not to use existing project, ask for a different name
+		return nil, false, fmt.Errorf("a project with this name already exists, please choose a different name")
+	}
+
+	// Ask user how they want to specify the Git repository
+	repoOptions := []string{
+		"Auto-detect Git repository from current directory",
+		"Manually enter Git repository URL",
+	}
+	repoOptionIndex := 0
+	repoPrompt := &survey.Select{
+		Message: "How would you like to specify the Git repository?",
+		Options: repoOptions,
+		Default: 0,
+	}
+
+	if err := survey.AskOne(repoPrompt, &repoOptionIndex, opts); err != nil {
+		return nil, false, fmt.Errorf("error getting repository option: %v", err)
+	}
+
+	var repoURL string
+
+	if repoOptionIndex == 1 {
+		// Manual entry - prompt for URL
+		var repoURLInput string
+		repoPromptInput := &survey.Input{
+			Message: "Enter your Git repository URL:",
+		}
+
+		if err := survey.AskOne(repoPromptInput, &repoURLInput, opts); err != nil {
+			return nil, false, fmt.Errorf("error getting repository URL: %v", err)
+		}
+
+		if strings.TrimSpace(repoURLInput) == "" {
+			return nil, false, fmt.Errorf("repository URL cannot be empty")
+		}
+
+		repoURL = strings.TrimSpace(repoURLInput)
+	} else {
+		// Auto-detect from current directory
+		var autoErr error
+		repoURL, autoErr = autoDetectRepoURL()
+		if autoErr != nil {
+			// If auto-detect fails, prompt user to enter URL manually
+			utils.WarnColor.Printf("Auto-detection failed: %v\n", autoErr)
+			utils.InfoColor.Println("Please enter your Git repository URL manually:")
+
+			var repoURLInput string
+			repoPromptInput := &survey.Input{
+				Message: "Enter your Git repository URL:",
+			}
+
+			if err := survey.AskOne(repoPromptInput, &repoURLInput, opts); err != nil {
+				return nil, false, fmt.Errorf("error getting repository URL: %v", err)
+			}
+
+			if strings.TrimSpace(repoURLInput) == "" {
+				return nil, false, fmt.Errorf("repository URL cannot be empty")
+			}
+
+			repoURL = strings.TrimSpace(repoURLInput)
+		}
+	}
+
+	// Build from a subdirectory if that's where the app is, then detect its framework there
+	rootDir, err := promptForRootDir()
+	if err != nil {
+		return nil, false, err
+	}
+	framework := detectProjectFramework(rootDir)
+	if rootDir != "" {
+		if rootDir, err = repoRelativePath(rootDir); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return &types.Project{Name: projectName, GitRepoURL: repoURL, Framework: framework, RootDir: rootDir}, false, nil
+}
+
+// detectProjectFramework returns the framework of a new project built from rootDir, relative to
+// the current directory: the one set in .yokrc, or else the one detected from its files
+func detectProjectFramework(rootDir string) string {
+	if settings, err := config.LoadSettings("."); err == nil && settings[frameworkSetting] != "" {
+		return strings.ToUpper(settings[frameworkSetting])
+	}
+	return api.DetectFrameworkIn(filepath.FromSlash(rootDir))
+}
+
+// promptForRootDir offers to build from a subdirectory when the current directory has no
+// package.json or index.html but a subdirectory does. Returns the chosen subdirectory relative
+// to the current directory, or an empty string to build from the current directory.
+func promptForRootDir() (string, error) {
+	if api.IsAppDir(".") {
+		return "", nil
+	}
+	appDirs := api.FindAppDirs(".")
+	if len(appDirs) == 0 {
+		return "", nil
+	}
+
+	const currentDir = "Use the current directory"
+	var selected string
+	prompt := &survey.Select{
+		Message: "No package.json or index.html here. Which directory should be built?",
+		Options: append(appDirs, currentDir),
+		Default: appDirs[0],
+	}
+	if err := survey.AskOne(prompt, &selected, utils.GetSurveyOptions()); err != nil {
+		return "", fmt.Errorf("error getting build directory: %v", err)
+	}
+	if selected == currentDir {
+		return "", nil
+	}
+	return selected, nil
+}
+
+// repoRelativePath turns dir, relative to the current directory, into a slash-separated path
+// relative to the repository root, which is where the build server starts from
+func repoRelativePath(dir string) (string, error) {
+	root, err := git.RootDir()
+	if err != nil {
+		// Not in a repository, e.g. the URL was entered manually; assume this is its root
+		return dir, nil
+	}
+	abs, err := filepath.Abs(filepath.FromSlash(dir))
+	if err != nil {
+		return "", fmt.Errorf("error resolving build directory: %v", err)
+	}
+	// Resolve symlinks on both sides, git reports the real path of the root
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = real
+	}
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("build directory %s is outside the repository", dir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// autoDetectRepoURL automatically detects the repository URL from the current directory
+func autoDetectRepoURL() (string, error) {
+	// Ensure we have a git repository
+	if err := ensureGitRepo(); err != nil {
+		return "", err
+	}
+
+	// Try to get remote URL using git command
+	remoteURL, err := git.GetRemoteURL()
+	if err != nil {
+		return "", fmt.Errorf("failed to detect git remote URL: %w", err)
+	}
+
+	return remoteURL, nil
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+	"github.com/velgardey/yok/cli/internal/api"
+	"github.com/velgardey/yok/cli/internal/types"
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old deployments",
+	Long: `Delete old deployments that match the given filters.
+
+The deployments to delete are listed before anything is deleted. The promoted deployment,
+the latest completed deployment, and deployments that are still running are never deleted.
+
+Examples:
+  yok prune --keep 5                   # Keep the newest 5 completed deployments
+  yok prune --older-than 720h          # Delete deployments older than 30 days
+  yok prune --status FAILED --yes      # Delete every failed deployment without asking`,
+	Args: cobra.NoArgs,
+	Run:  runPrune,
+}
+
+func init() {
+	RootCmd.AddCommand(pruneCmd)
+
+	// Add flags
+	pruneCmd.Flags().Int("keep", 0, "Number of newest completed deployments to keep")
+	pruneCmd.Flags().Duration("older-than", 0, "Only delete deployments older than this, e.g. 720h")
+	pruneCmd.Flags().String("status", "", "Only delete deployments with this status, e.g. FAILED")
+	pruneCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation")
+}
+
+// runPrune handles the prune command logic
+func runPrune(cmd *cobra.Command, args []string) {
+	// Get flags
+	keep, _ := cmd.Flags().GetInt("keep")
+	olderThan, _ := cmd.Flags().GetDuration("older-than")
+	status, _ := cmd.Flags().GetString("status")
+	skipConfirm, _ := cmd.Flags().GetBool("yes")
+	status = strings.ToUpper(status)
+
+	if !cmd.Flags().Changed("keep") && olderThan == 0 && status == "" {
+		utils.HandleError(fmt.Errorf("no filter given"), "Specify at least one of --keep, --older-than, or --status")
+	}
+	if keep < 0 {
+		utils.HandleError(fmt.Errorf("--keep must not be negative"), "Invalid flag")
+	}
+
+	// Get project configuration
+	config, err := EnsureProjectID()
+	utils.HandleError(err, "Error setting up project")
+
+	s := utils.StartSpinner("Fetching deployments...")
+	deployments, err := api.ListDeployments(config.ProjectID)
+	utils.StopSpinner(s)
+	handleAPIError(err, "Error fetching deployments")
+
+	project, err := api.GetProject(config.ProjectID)
+	handleAPIError(err, "Error fetching project details")
+	if project.Partial {
+		utils.HandleError(fmt.Errorf("project details unavailable, so the promoted deployment can't be kept"), "Error fetching project details")
+	}
+
+	toDelete := selectDeploymentsToPrune(deployments, project.PromotedDeploymentID, keep, olderThan, status)
+	kept := len(deployments) - len(toDelete)
+
+	if len(toDelete) == 0 {
+		utils.InfoColor.Println("No deployments to prune.")
+		return
+	}
+
+	// Show what would be deleted
+	utils.InfoColor.Printf("The following %d deployments will be deleted:\n\n", len(toDelete))
+	for _, d := range toDelete {
+		utils.FormatTableRow(d.ID, d.Status, d.CreatedAt, d.Note)
+	}
+	fmt.Println()
+
+	// Confirm deletion unless skipped
+	if !skipConfirm {
+		if !isInteractive() {
+			utils.HandleError(fmt.Errorf("confirmation required"), "Run with --yes to prune without prompting")
+		}
+
+		confirm := false
+		prunePrompt := &survey.Confirm{
+			Message: fmt.Sprintf("Delete %d deployments? This cannot be undone.", len(toDelete)),
+			Default: false,
+		}
+		opts := utils.GetSurveyOptions()
+		survey.AskOne(prunePrompt, &confirm, opts)
+
+		if !confirm {
+			utils.InfoColor.Println("Prune aborted.")
+			return
+		}
+	}
+
+	// Delete the deployments one by one
+	deleted, failed := 0, 0
+	for i, d := range toDelete {
+		fmt.Printf("[%d/%d] Deleting %s... ", i+1, len(toDelete), d.ID)
+		if err := api.DeleteDeployment(d.ID); err != nil {
+			utils.ErrorColor.Printf("failed: %v\n", err)
+			failed++
+			continue
+		}
+		utils.SuccessColor.Println("done")
+		deleted++
+	}
+
+	fmt.Println()
+	utils.InfoColor.Printf("Deleted: %d, kept: %d, failed to delete: %d\n", deleted, kept+failed, failed)
+
+	if failed > 0 {
+		os.Exit(utils.ExitError)
+	}
+}
+
+// selectDeploymentsToPrune returns the deployments matching the filters, newest first. The
+// promoted deployment, the latest completed deployment, the newest keep completed deployments,
+// and deployments that are still running are never selected.
+func selectDeploymentsToPrune(deployments []types.Deployment, promotedID string, keep int, olderThan time.Duration, status string) []types.Deployment {
+	sorted := make([]types.Deployment, len(deployments))
+	copy(sorted, deployments)
+	sortDeploymentsByCreatedDesc(sorted)
+
+	var selected []types.Deployment
+	completedSeen := 0
+	for _, d := range sorted {
+		if d.Status == "COMPLETED" {
+			completedSeen++
+			// The latest completed deployment is always kept, as are the newest keep ones
+			if completedSeen == 1 || completedSeen <= keep {
+				continue
+			}
+		}
+
+		switch {
+		case d.ID == promotedID:
+			continue
+		case d.Status == "PENDING" || d.Status == "QUEUED" || d.Status == "IN_PROGRESS":
+			continue
+		case olderThan > 0 && time.Since(d.CreatedAt) < olderThan:
+			continue
+		case status != "" && d.Status != status:
+			continue
+		}
+
+		selected = append(selected, d)
+	}
+
+	return selected
+}
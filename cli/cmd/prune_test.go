@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/velgardey/yok/cli/internal/types"
+)
+
+func TestSelectDeploymentsToPrune(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	// Listed out of order, since the API server's order isn't relied on
+	deployments := []types.Deployment{
+		{ID: "completed-3d", Status: "COMPLETED", CreatedAt: now.Add(-3 * day)},
+		{ID: "completed-1d", Status: "COMPLETED", CreatedAt: now.Add(-1 * day)},
+		{ID: "failed-2d", Status: "FAILED", CreatedAt: now.Add(-2 * day)},
+		{ID: "running", Status: "IN_PROGRESS", CreatedAt: now.Add(-time.Hour)},
+		{ID: "completed-5d", Status: "COMPLETED", CreatedAt: now.Add(-5 * day)},
+		{ID: "failed-6d", Status: "FAILED", CreatedAt: now.Add(-6 * day)},
+		{ID: "queued", Status: "QUEUED", CreatedAt: now.Add(-10 * day)},
+	}
+
+	tests := []struct {
+		name       string
+		promotedID string
+		keep       int
+		olderThan  time.Duration
+		status     string
+		want       []string
+	}{
+		{"keeps the latest completed and running ones", "", 0, 0, "", []string{"failed-2d", "completed-3d", "completed-5d", "failed-6d"}},
+		{"keeps the newest completed ones", "", 2, 0, "", []string{"failed-2d", "completed-5d", "failed-6d"}},
+		{"keeps the promoted one", "completed-5d", 0, 0, "", []string{"failed-2d", "completed-3d", "failed-6d"}},
+		{"only older ones", "", 0, 4 * day, "", []string{"completed-5d", "failed-6d"}},
+		{"only with the status", "", 0, 0, "FAILED", []string{"failed-2d", "failed-6d"}},
+		{"every filter", "completed-5d", 1, 4 * day, "COMPLETED", nil},
+		{"keeping more than there are", "", 10, 0, "COMPLETED", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range selectDeploymentsToPrune(deployments, tt.promotedID, tt.keep, tt.olderThan, tt.status) {
+				got = append(got, d.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,30 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/velgardey/yok/cli/internal/api"
+	"github.com/velgardey/yok/cli/internal/config"
 	"github.com/velgardey/yok/cli/internal/git"
+	"github.com/velgardey/yok/cli/internal/utils"
+	"golang.org/x/term"
 )
 
 var version = "dev" // Will be injected at build time by GoReleaser
 
+// commit and date are the commit the CLI was built from and when, injected by GoReleaser
+var commit, date string
+
+// noInput disables all interactive prompts when set via --no-input
+var noInput bool
+
+// configFile overrides the configuration file when set via --config
+var configFile string
+
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:     "yok",
@@ -22,35 +36,128 @@ var RootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	// Customize version template
+	// Customize version template; --version, the version command and the update check all
+	// report the version injected at build time the same way
+	RootCmd.Version = getCurrentVersion()
 	RootCmd.SetVersionTemplate("Yok CLI v{{.Version}}\n")
 
 	// Add git command support
 	addGitCommands()
 
-	// Set up special handling for unknown commands to pass them to git
-	RootCmd.SetFlagErrorFunc(handleUnknownCommand)
+	// Pass commands yok doesn't know, with all their flags, straight to git
+	if args := os.Args[1:]; isGitPassthrough(args) {
+		executeGitCommand(args)
+		return
+	}
+
+	// Look for a newer release while the command runs
+	updateNotice := startUpdateNotifier()
 
-	if err := RootCmd.Execute(); err != nil {
+	executedCmd, err := RootCmd.ExecuteC()
+	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(utils.ExitError)
 	}
+
+	printUpdateNotice(executedCmd, updateNotice)
 }
 
-// handleUnknownCommand handles unknown commands by trying to pass them to git
-func handleUnknownCommand(cmd *cobra.Command, err error) error {
-	// Check if the command is a git command that we don't explicitly handle
-	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
-		if output, cmdErr := git.ExecuteCommand(os.Args[1:]...); cmdErr == nil {
-			fmt.Print(output)
-			os.Exit(0)
-		}
+// isGitPassthrough reports whether args name a command yok doesn't have, which is then run as a
+// git command. This is decided before cobra parses any flags, since those belong to git.
+func isGitPassthrough(args []string) bool {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return false
+	}
+	// Cobra's own hidden commands, such as __complete for shell completion
+	if strings.HasPrefix(args[0], "__") {
+		return false
 	}
-	return err
+
+	// help and completion are only added when the command runs, so add them to be found
+	RootCmd.InitDefaultHelpCmd()
+	RootCmd.InitDefaultCompletionCmd()
+	_, _, err := RootCmd.Find(args)
+	return err != nil
 }
 
 func init() {
 	// Git commands will be added in Execute() function to avoid initialization issues
+
+	RootCmd.PersistentFlags().BoolVar(&noInput, "no-input", false, "Disable interactive prompts")
+	RootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to the config file (default \""+utils.ConfigFile+"\")")
+
+	// Point the config package at the chosen file before any command runs
+	cobra.OnInitialize(func() {
+		config.SetPath(configFile)
+	})
+
+	RootCmd.PersistentPreRun = applySettings
+}
+
+// frameworkSetting is the .yokrc setting choosing the framework of new projects instead of
+// detecting it; every other setting is a flag default
+const frameworkSetting = "framework"
+
+// applySettings sets the flags of cmd that weren't given on the command line to their defaults
+// from the .yokrc in the current directory. Settings for flags cmd doesn't have are ignored, so
+// one file can hold the defaults of several commands. Since this runs before every command, a
+// .yokrc that can't be read, or a setting cmd can't use, is warned about rather than fatal, so
+// commands like version and self-update keep working.
+func applySettings(cmd *cobra.Command, args []string) {
+	settings, err := config.LoadSettings(".")
+	if err != nil {
+		utils.WarnColor.Printf("Warning: Ignoring %s: %v\n", config.SettingsFile, err)
+		return
+	}
+
+	for name, value := range settings {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		// A failed Set may still have overwritten the value, so the default is put back
+		previous := flag.Value.String()
+		if err := cmd.Flags().Set(name, value); err != nil {
+			flag.Value.Set(previous)
+			utils.WarnColor.Printf("Warning: Ignoring invalid %s in %s: %v\n", name, config.SettingsFile, err)
+		}
+	}
+}
+
+// isInteractive reports whether the CLI may prompt the user for input
+func isInteractive() bool {
+	return !noInput && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// warnOnServerVersionMismatch warns when the API server's major version differs from the CLI's,
+// since the server may then reject what the CLI sends. The check is best-effort and never fails.
+func warnOnServerVersionMismatch() {
+	serverVersion, err := api.GetServerVersion()
+	if err != nil || serverVersion == "" {
+		return
+	}
+
+	if utils.MajorVersionsDiffer(getCurrentVersion(), serverVersion) {
+		utils.WarnColor.Printf("Warning: This CLI (v%s) may not be compatible with the Yok server (v%s). Run 'yok self-update' to update.\n",
+			getCurrentVersion(), strings.TrimPrefix(serverVersion, "v"))
+	}
+}
+
+// exitCodeForError returns the exit code matching an error returned by the API
+func exitCodeForError(err error) int {
+	switch {
+	case errors.Is(err, api.ErrUnauthorized):
+		return utils.ExitAuthError
+	case errors.Is(err, api.ErrNotFound):
+		return utils.ExitNotFound
+	default:
+		return utils.ExitError
+	}
+}
+
+// handleAPIError prints an API error and exits with the matching exit code
+func handleAPIError(err error, message string) {
+	utils.HandleErrorWithMessage(err, message, exitCodeForError(err))
 }
 
 // addGitCommands adds all common git commands as explicit subcommands
@@ -95,12 +202,12 @@ func createGitFallbackCommand() *cobra.Command {
 	}
 }
 
-// executeGitCommand executes a git command and handles errors
+// executeGitCommand runs a git command attached to the terminal and exits with git's exit code
+// if it fails
 func executeGitCommand(args []string) {
-	output, err := git.ExecuteCommand(args...)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	code, err := git.RunAttached(args...)
+	utils.HandleError(err, "Error running git")
+	if code != 0 {
+		os.Exit(code)
 	}
-	fmt.Print(output)
 }
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var addGitCommandsOnce sync.Once
+
+// fakeGit puts a git on PATH that records its arguments, one per line, and returns a function
+// reading the arguments of the last run
+func fakeGit(t *testing.T) func() []string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake git is a shell script")
+	}
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nfor arg in \"$@\"; do printf '%s\\n' \"$arg\"; done > \"$FAKE_GIT_ARGS\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "git"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_GIT_ARGS", argsFile)
+
+	return func() []string {
+		t.Helper()
+		out, err := os.ReadFile(argsFile)
+		if err != nil {
+			t.Fatalf("git wasn't run: %v", err)
+		}
+		os.Remove(argsFile)
+		return strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	}
+}
+
+func TestGitCommandsPassArgsThrough(t *testing.T) {
+	gitArgs := fakeGit(t)
+	// No .yokrc applies to the commands
+	t.Chdir(t.TempDir())
+	addGitCommandsOnce.Do(addGitCommands)
+	t.Cleanup(func() { RootCmd.SetArgs(nil) })
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		// Flags git shares with yok and cobra are git's
+		{[]string{"log", "--oneline", "-p"}, []string{"log", "--oneline", "-p"}},
+		{[]string{"log", "--version"}, []string{"log", "--version"}},
+		{[]string{"stash", "-h"}, []string{"stash", "-h"}},
+		// yok status is yok's own, so git's is run through the fallback
+		{[]string{"git", "status", "-sb"}, []string{"status", "-sb"}},
+		{[]string{"push", "--help"}, []string{"push", "--help"}},
+		{[]string{"commit", "--no-input", "--config", "x"}, []string{"commit", "--no-input", "--config", "x"}},
+		// The order of arguments, including values that look like flags, is kept
+		{[]string{"commit", "-m", "--amend", "--amend"}, []string{"commit", "-m", "--amend", "--amend"}},
+		{[]string{"log", "-n", "3", "--", "cli/", "-v"}, []string{"log", "-n", "3", "--", "cli/", "-v"}},
+		// The fallback drops only its own name
+		{[]string{"git", "cherry-pick", "-x", "--version", "abc123"}, []string{"cherry-pick", "-x", "--version", "abc123"}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			if isGitPassthrough(tt.args) {
+				t.Fatal("handled as an unknown command")
+			}
+			RootCmd.SetArgs(tt.args)
+			if _, err := RootCmd.ExecuteC(); err != nil {
+				t.Fatal(err)
+			}
+			if got := gitArgs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("git ran with %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsGitPassthrough(t *testing.T) {
+	addGitCommandsOnce.Do(addGitCommands)
+
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"cherry-pick", "--continue"}, true},
+		{[]string{"worktree", "add", "-b", "feature", "../feature"}, true},
+		{[]string{"lgo", "--oneline"}, true},
+		// yok's own commands, flags and cobra's commands are never git's
+		{[]string{"deploy", "--wait"}, false},
+		{[]string{"log", "--oneline"}, false},
+		{[]string{"help", "deploy"}, false},
+		{[]string{"completion", "bash"}, false},
+		{[]string{"__complete", "dep"}, false},
+		{[]string{"--version"}, false},
+		{[]string{"-h"}, false},
+		{[]string{"--verison"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isGitPassthrough(tt.args); got != tt.want {
+			t.Errorf("isGitPassthrough(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownCommandsPassArgsThrough(t *testing.T) {
+	gitArgs := fakeGit(t)
+
+	args := []string{"cherry-pick", "-h", "--version", "-x", "abc123"}
+	executeGitCommand(args)
+	if got := gitArgs(); !reflect.DeepEqual(got, args) {
+		t.Errorf("git ran with %q, want %q", got, args)
+	}
+}
+
+func TestApplySettingsToleratesBadYokrc(t *testing.T) {
+	tests := []struct {
+		name        string
+		yokrc       string
+		wantTimeout string
+	}{
+		{"valid", "timeout=10m\nlogs=true\n", "10m0s"},
+		{"malformed", "{\"timeout\": ", "5m0s"},
+		{"invalid value", "timeout=soon\nlogs=true\n", "5m0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, ".yokrc"), []byte(tt.yokrc), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Chdir(dir)
+
+			cmd := &cobra.Command{Use: "deploy"}
+			timeout := cmd.Flags().Duration("timeout", 5*time.Minute, "")
+			logs := cmd.Flags().Bool("logs", false, "")
+
+			applySettings(cmd, nil)
+			if got := timeout.String(); got != tt.wantTimeout {
+				t.Errorf("timeout = %s, want %s", got, tt.wantTimeout)
+			}
+			// Valid settings still apply next to an invalid one
+			if wantLogs := tt.name != "malformed"; *logs != wantLogs {
+				t.Errorf("logs = %v, want %v", *logs, wantLogs)
+			}
+		})
+	}
+}
@@ -1,7 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
@@ -17,22 +23,76 @@ func init() {
 	var statusCmd = &cobra.Command{
 		Use:   "status [deployment_id]",
 		Short: "Check the status of your Yok deployments",
-		Long:  "Check the status of your current or a specific deployment",
-		Args:  cobra.MaximumNArgs(1),
-		Run:   runStatus,
+		Long: `Check the status of your current or a specific deployment.
+
+With --format, the deployment is printed using a Go template instead of the summary, with the
+fields .ID, .Status, .CreatedAt, .CompletedAt, .DeploymentUrl, .Note, .CommitSHA, and
+.QueuePosition available, and the project as .Project (.Project.Name, .Project.Slug, ...).
+
+Examples:
+  yok status abc123 --format '{{.Status}} {{.DeploymentUrl}}'
+  yok status abc123 --format '{{.Project.Slug}}: {{.Status}}'`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  runStatus,
 	}
 
 	// Add flags to status command
 	statusCmd.Flags().BoolP("all", "a", false, "Show all deployments, not just recent ones")
 	statusCmd.Flags().BoolP("logs", "l", false, "Show logs for the selected deployment")
+	statusCmd.Flags().String("format", "", "Print the deployment using a Go template, e.g. '{{.Status}} {{.DeploymentUrl}}'")
+	statusCmd.MarkFlagsMutuallyExclusive("format", "logs")
 
 	// List command to list all deployments
 	var listCmd = &cobra.Command{
 		Use:   "list",
 		Short: "List all deployments for your project",
+		Long: `List all deployments for your project.
+
+With --format, each deployment is printed using a Go template instead of the table, with the
+fields .ID, .Status, .CreatedAt, .DeploymentUrl, .Note, and .CommitSHA available.
+With --count, only the number of deployments with each status is printed.
+With --refresh, the live status of deployments that are still running is fetched, since the
+list may be behind.
+
+Examples:
+  yok list
+  yok list --status FAILED
+  yok list --count
+  yok list --refresh
+  yok list --format '{{.ID}} {{.Status}}'
+  yok list --format '{{.ID}} {{.CreatedAt.Format "2006-01-02"}} {{.DeploymentUrl}}'`,
 		Run: func(cmd *cobra.Command, args []string) {
+			// Get flags
+			format, _ := cmd.Flags().GetString("format")
+			status, _ := cmd.Flags().GetString("status")
+			count, _ := cmd.Flags().GetBool("count")
+			refresh, _ := cmd.Flags().GetBool("refresh")
+			status = strings.ToUpper(status)
+
+			// Parse the format template before fetching anything
+			var formatTemplate *template.Template
+			if format != "" {
+				var err error
+				formatTemplate, err = template.New("format").Parse(format)
+				utils.HandleError(err, "Invalid --format template")
+			}
+
 			// Get project ID and ensure it exists
-			conf := config.GetProjectIDOrExit()
+			conf, err := config.LoadProjectConfig()
+			utils.HandleError(err, "Error loading configuration")
+
+			// Templated output is meant for scripts, so it gets no spinner or table
+			if formatTemplate != nil {
+				deployments, err := api.ListDeployments(conf.ProjectID)
+				handleAPIError(err, "Failed to list deployments")
+				if refresh {
+					refreshDeploymentStatuses(deployments)
+				}
+				deployments = filterDeploymentsByStatus(deployments, status)
+				sortDeploymentsByCreatedDesc(deployments)
+				utils.HandleError(printDeploymentsWithTemplate(formatTemplate, deployments), "Error formatting deployments")
+				return
+			}
 
 			// Get deployments
 			s := utils.StartSpinner("Fetching deployments...")
@@ -40,69 +100,121 @@ func init() {
 			deployments, err := api.ListDeployments(conf.ProjectID)
 			utils.StopSpinner(s)
 
-			if err != nil {
-				utils.ErrorColor.Printf("Failed to list deployments: %v\n", err)
-				return
+			handleAPIError(err, "Failed to list deployments")
+			if refresh {
+				s = utils.StartSpinner("Refreshing the status of running deployments...")
+				refreshDeploymentStatuses(deployments)
+				utils.StopSpinner(s)
 			}
+			deployments = filterDeploymentsByStatus(deployments, status)
 
 			if len(deployments) == 0 {
-				utils.InfoColor.Println("No deployments found for this project.")
+				if status != "" {
+					utils.InfoColor.Printf("No %s deployments found for this project.\n", status)
+				} else {
+					utils.InfoColor.Println("No deployments found for this project.")
+				}
 				return
 			}
 
+			// Print only the tally for a quick overview
+			if count {
+				fmt.Println(formatStatusCounts(deployments))
+				return
+			}
+			sortDeploymentsByCreatedDesc(deployments)
+
+			// Look up the promoted deployment so it can be marked (best effort)
+			var promotedID string
+			if project, err := api.GetProject(conf.ProjectID); err == nil {
+				promotedID = project.PromotedDeploymentID
+			}
+
 			// Print deployments table
 			fmt.Println("\nDeployments for", conf.RepoName)
-			fmt.Println("------------------------------------------------------------------------------")
-			fmt.Printf("%-36s %-12s %-20s\n", "ID", "STATUS", "CREATED")
-			fmt.Println("------------------------------------------------------------------------------")
+			fmt.Println("-----------------------------------------------------------------------------------------------------------------")
+			fmt.Printf("  %-36s %-12s %-20s %s\n", "ID", "STATUS", "CREATED", "NOTE")
+			fmt.Println("-----------------------------------------------------------------------------------------------------------------")
 
 			for _, d := range deployments {
-				utils.FormatTableRow(d.ID, d.Status, d.CreatedAt)
+				if d.ID == promotedID {
+					utils.SuccessColor.Print("* ")
+				} else {
+					fmt.Print("  ")
+				}
+				utils.FormatTableRow(d.ID, d.Status, d.CreatedAt, d.Note)
+			}
+
+			if promotedID != "" {
+				fmt.Println("\n* promoted deployment served at the project URL")
 			}
 		},
 	}
 
+	// Add flags to the list command
+	listCmd.Flags().String("format", "", "Print each deployment using a Go template, e.g. '{{.ID}} {{.Status}}'")
+	listCmd.Flags().String("status", "", "Only list deployments with this status, e.g. FAILED")
+	listCmd.Flags().Bool("count", false, "Print the number of deployments with each status instead of the table")
+	listCmd.Flags().Bool("refresh", false, "Fetch the live status of deployments that are still running")
+	listCmd.MarkFlagsMutuallyExclusive("format", "count")
+
 	// Cancel command to cancel a deployment
 	var cancelCmd = &cobra.Command{
 		Use:   "cancel [deploymentId]",
 		Short: "Cancel a running deployment",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Cancel a running deployment.
+
+With --all, every pending, queued, or in-progress deployment of the project is cancelled after a
+single confirmation. A deployment that fails to cancel doesn't stop the others.`,
+		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
+			// Cancel every running deployment if asked to
+			if all, _ := cmd.Flags().GetBool("all"); all {
+				if len(args) > 0 {
+					utils.HandleError(fmt.Errorf("--all cancels every running deployment"), "Don't pass a deployment ID with --all")
+				}
+				skipConfirm, _ := cmd.Flags().GetBool("yes")
+				runCancelAll(skipConfirm)
+				return
+			}
+
 			var deploymentId string
 
 			// If no deployment ID provided, ask the user to select from recent in-progress deployments
 			if len(args) == 0 {
 				// Load config and ensure project ID exists
-				conf := config.GetProjectIDOrExit()
+				conf, err := config.LoadProjectConfig()
+				utils.HandleError(err, "Error loading configuration")
 
 				// Select a deployment that is in progress
-				var err error
-				deploymentId, err = api.SelectDeploymentFromList(conf.ProjectID, func(d types.Deployment) bool {
+				deploymentId, err = selectDeploymentFromList(conf.ProjectID, func(d types.Deployment) bool {
 					return d.Status == "PENDING" || d.Status == "QUEUED" || d.Status == "IN_PROGRESS"
 				})
 				if err != nil {
-					if err.Error() == "no matching deployments found" {
+					if errors.Is(err, errNoMatchingDeployments) {
 						utils.InfoColor.Println("No in-progress deployments found to cancel.")
 						return
 					}
-					utils.HandleError(err, "Error selecting deployment")
+					handleAPIError(err, "Error selecting deployment")
 				}
 			} else {
 				deploymentId = args[0]
 			}
 
-			// Confirm cancellation
-			confirm := false
-			cancelPrompt := &survey.Confirm{
-				Message: fmt.Sprintf("Are you sure you want to cancel deployment %s?", deploymentId),
-				Default: false,
-			}
-			opts := utils.GetSurveyOptions()
-			survey.AskOne(cancelPrompt, &confirm, opts)
+			// Confirm cancellation unless skipped
+			if skipConfirm, _ := cmd.Flags().GetBool("yes"); !skipConfirm {
+				confirm := false
+				cancelPrompt := &survey.Confirm{
+					Message: fmt.Sprintf("Are you sure you want to cancel deployment %s?", deploymentId),
+					Default: false,
+				}
+				opts := utils.GetSurveyOptions()
+				survey.AskOne(cancelPrompt, &confirm, opts)
 
-			if !confirm {
-				utils.InfoColor.Println("Cancellation aborted.")
-				return
+				if !confirm {
+					utils.InfoColor.Println("Cancellation aborted.")
+					return
+				}
 			}
 
 			// Cancel deployment
@@ -111,28 +223,202 @@ func init() {
 			err := api.CancelDeployment(deploymentId)
 			utils.StopSpinner(s)
 
-			if err != nil {
-				utils.ErrorColor.Printf("Failed to cancel deployment: %v\n", err)
-				return
-			}
+			handleAPIError(err, "Failed to cancel deployment")
 
 			utils.SuccessColor.Println("[OK] Deployment cancelled successfully")
 		},
 	}
 
+	// Add flags to the cancel command
+	cancelCmd.Flags().Bool("all", false, "Cancel every pending, queued, or in-progress deployment")
+	cancelCmd.Flags().BoolP("yes", "y", false, "Cancel without asking for confirmation")
+
 	// Add commands to root
 	RootCmd.AddCommand(statusCmd, listCmd, cancelCmd)
 }
 
+// isActiveStatus reports whether a deployment with status hasn't finished yet
+func isActiveStatus(status string) bool {
+	return status == "PENDING" || status == "QUEUED" || status == "IN_PROGRESS"
+}
+
+// maxConcurrentRefreshes is how many deployment statuses refreshDeploymentStatuses fetches at
+// the same time
+const maxConcurrentRefreshes = 5
+
+// refreshDeploymentStatuses replaces the status of the deployments that are still running with
+// their live status, fetched by up to maxConcurrentRefreshes workers. Deployments whose status
+// can't be fetched keep the listed one.
+func refreshDeploymentStatuses(deployments []types.Deployment) {
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < maxConcurrentRefreshes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each worker only writes the deployments it was given
+			for index := range jobs {
+				if deployment, err := api.GetDeploymentStatus(deployments[index].ID); err == nil && deployment.Status != "" {
+					deployments[index].Status = deployment.Status
+				}
+			}
+		}()
+	}
+
+	for i, d := range deployments {
+		if isActiveStatus(d.Status) {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// maxConcurrentCancels is how many deployments cancelDeployments cancels at the same time
+const maxConcurrentCancels = 5
+
+// cancelResult is the outcome of cancelling one deployment
+type cancelResult struct {
+	deploymentID string
+	err          error
+}
+
+// cancelDeployments cancels deployments using up to maxConcurrentCancels workers and returns the
+// error for each deployment ID, nil for the ones cancelled
+func cancelDeployments(deployments []types.Deployment) map[string]error {
+	jobs := make(chan string)
+	results := make(chan cancelResult, len(deployments))
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(maxConcurrentCancels, len(deployments)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				results <- cancelResult{deploymentID: id, err: api.CancelDeployment(id)}
+			}
+		}()
+	}
+
+	for _, d := range deployments {
+		jobs <- d.ID
+	}
+	close(jobs)
+	wg.Wait()
+	close(results)
+
+	errs := make(map[string]error, len(deployments))
+	for result := range results {
+		errs[result.deploymentID] = result.err
+	}
+	return errs
+}
+
+// runCancelAll cancels every running deployment of the project, continuing past failures and
+// reporting how many were cancelled
+func runCancelAll(skipConfirm bool) {
+	conf, err := config.LoadProjectConfig()
+	utils.HandleError(err, "Error loading configuration")
+
+	s := utils.StartSpinner("Fetching deployments...")
+	deployments, err := api.ListDeployments(conf.ProjectID)
+	utils.StopSpinner(s)
+	handleAPIError(err, "Error fetching deployments")
+
+	var toCancel []types.Deployment
+	for _, d := range deployments {
+		if isActiveStatus(d.Status) {
+			toCancel = append(toCancel, d)
+		}
+	}
+	sortDeploymentsByCreatedDesc(toCancel)
+
+	if len(toCancel) == 0 {
+		utils.InfoColor.Println("No in-progress deployments found to cancel.")
+		return
+	}
+
+	// Show what will be cancelled
+	utils.InfoColor.Printf("The following %d deployments will be cancelled:\n\n", len(toCancel))
+	for _, d := range toCancel {
+		utils.FormatTableRow(d.ID, d.Status, d.CreatedAt, d.Note)
+	}
+	fmt.Println()
+
+	// Confirm cancellation unless skipped
+	if !skipConfirm {
+		if !isInteractive() {
+			utils.HandleError(fmt.Errorf("confirmation required"), "Run with --yes to cancel without prompting")
+		}
+
+		confirm := false
+		cancelPrompt := &survey.Confirm{
+			Message: fmt.Sprintf("Cancel %d deployments?", len(toCancel)),
+			Default: false,
+		}
+		opts := utils.GetSurveyOptions()
+		survey.AskOne(cancelPrompt, &confirm, opts)
+
+		if !confirm {
+			utils.InfoColor.Println("Cancellation aborted.")
+			return
+		}
+	}
+
+	// Cancel the deployments concurrently; only this goroutine prints, so the spinner stays intact
+	s = utils.StartSpinner(fmt.Sprintf("Cancelling %d deployments...", len(toCancel)))
+	errs := cancelDeployments(toCancel)
+	utils.StopSpinner(s)
+
+	// Summarize the result for each deployment, in the order they were listed
+	cancelled, failed := 0, 0
+	fmt.Printf("%-36s %s\n", "ID", "RESULT")
+	for _, d := range toCancel {
+		fmt.Printf("%-36s ", d.ID)
+		if err := errs[d.ID]; err != nil {
+			utils.ErrorColor.Printf("failed: %v\n", err)
+			failed++
+			continue
+		}
+		utils.SuccessColor.Println("cancelled")
+		cancelled++
+	}
+
+	fmt.Println()
+	utils.InfoColor.Printf("Cancelled: %d, failed to cancel: %d\n", cancelled, failed)
+
+	if failed > 0 {
+		os.Exit(utils.ExitError)
+	}
+}
+
 // runStatus handles the status command logic
 func runStatus(cmd *cobra.Command, args []string) {
 	// Get flags
 	showAll, _ := cmd.Flags().GetBool("all")
 	showLogs, _ := cmd.Flags().GetBool("logs")
+	format, _ := cmd.Flags().GetString("format")
+
+	// Parse the format template before fetching anything
+	var formatTemplate *template.Template
+	if format != "" {
+		var err error
+		formatTemplate, err = template.New("format").Parse(format)
+		utils.HandleError(err, "Invalid --format template")
+	}
 
-	// Get project configuration
-	config, err := EnsureProjectID()
-	utils.HandleError(err, "Error setting up project")
+	// Get project configuration; templated output is meant for scripts, so it must not print
+	// anything else
+	var conf types.Config
+	var err error
+	if formatTemplate != nil {
+		conf, err = config.LoadProjectConfig()
+		utils.HandleError(err, "Error loading configuration")
+	} else {
+		conf, err = EnsureProjectID()
+		utils.HandleError(err, "Error setting up project")
+	}
 
 	var deploymentID string
 
@@ -152,29 +438,40 @@ func runStatus(cmd *cobra.Command, args []string) {
 		}
 
 		// Let user select a deployment
-		deploymentID, err = api.SelectDeploymentFromList(config.ProjectID, filter)
-		if err != nil {
-			utils.ErrorColor.Printf("Error selecting deployment: %v\n", err)
-			return
-		}
+		deploymentID, err = selectDeploymentFromList(conf.ProjectID, filter)
+		handleAPIError(err, "Error selecting deployment")
 	}
 
 	// Get deployment details
 	deployment, err := api.GetDeploymentStatus(deploymentID)
-	utils.HandleError(err, "Error fetching deployment details")
+	handleAPIError(err, "Error fetching deployment details")
 
 	// Get project details (if possible)
-	project, err := api.GetProject(config.ProjectID)
+	project, err := api.GetProject(conf.ProjectID)
+	if formatTemplate != nil {
+		data := statusTemplateData{Deployment: *deployment}
+		if err == nil {
+			data.Project = *project
+		}
+		utils.HandleError(formatTemplate.Execute(os.Stdout, data), "Error formatting deployment")
+		fmt.Println()
+		return
+	}
 	if err != nil {
 		// If we can't get project details, just continue with what we have
 		utils.WarnColor.Printf("Warning: Could not fetch project details: %v\n", err)
+		project = &types.Project{ID: conf.ProjectID, Partial: true}
 	}
 
 	// Display deployment status information
 	fmt.Println()
 	utils.InfoColor.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	utils.InfoColor.Printf("Deployment ID:    %s\n", deployment.ID)
-	utils.InfoColor.Printf("Project:          %s\n", project.Name)
+	if project.Partial {
+		utils.InfoColor.Println("Project:          details unavailable")
+	} else {
+		utils.InfoColor.Printf("Project:          %s\n", project.Name)
+	}
 
 	// Show status with appropriate color
 	utils.InfoColor.Printf("Status:           ")
@@ -189,6 +486,10 @@ func runStatus(cmd *cobra.Command, args []string) {
 		fmt.Println(deployment.Status)
 	}
 
+	if deployment.Status == "QUEUED" && deployment.QueuePosition > 0 {
+		utils.InfoColor.Printf("Queue position:   %d\n", deployment.QueuePosition)
+	}
+
 	utils.InfoColor.Printf("Created:          %s\n", deployment.CreatedAt.Format("Jan 02, 2006 15:04:05"))
 
 	if deployment.CompletedAt != nil {
@@ -199,11 +500,17 @@ func runStatus(cmd *cobra.Command, args []string) {
 
 	if deployment.Status == "COMPLETED" && project.Slug != "" {
 		utils.InfoColor.Printf("Public URL:       https://%s.yok.ninja\n", project.Slug)
+	} else if deployment.Status == "COMPLETED" && project.Partial {
+		utils.InfoColor.Println("Public URL:       unavailable without the project details")
 	}
 
 	if deployment.DeploymentUrl != "" {
 		utils.InfoColor.Printf("Deployment URL:   %s\n", deployment.DeploymentUrl)
 	}
+
+	if deployment.Note != "" {
+		utils.InfoColor.Printf("Note:             %s\n", deployment.Note)
+	}
 	utils.InfoColor.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Println()
 
@@ -225,3 +532,73 @@ func runStatus(cmd *cobra.Command, args []string) {
 		}
 	}
 }
+
+// sortDeploymentsByCreatedDesc sorts deployments newest first, in the same order every time
+// for deployments created at the same time
+func sortDeploymentsByCreatedDesc(deployments []types.Deployment) {
+	sort.Slice(deployments, func(i, j int) bool {
+		if !deployments[i].CreatedAt.Equal(deployments[j].CreatedAt) {
+			return deployments[i].CreatedAt.After(deployments[j].CreatedAt)
+		}
+		return deployments[i].ID < deployments[j].ID
+	})
+}
+
+// filterDeploymentsByStatus returns the deployments with status, or all of them if status is empty
+func filterDeploymentsByStatus(deployments []types.Deployment, status string) []types.Deployment {
+	if status == "" {
+		return deployments
+	}
+
+	var filtered []types.Deployment
+	for _, d := range deployments {
+		if d.Status == status {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// formatStatusCounts returns how many deployments have each status, most common first, e.g.
+// "COMPLETED: 12, FAILED: 3, IN_PROGRESS: 1"
+func formatStatusCounts(deployments []types.Deployment) string {
+	counts := make(map[string]int)
+	for _, d := range deployments {
+		counts[d.Status]++
+	}
+
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if counts[statuses[i]] != counts[statuses[j]] {
+			return counts[statuses[i]] > counts[statuses[j]]
+		}
+		return statuses[i] < statuses[j]
+	})
+
+	parts := make([]string, len(statuses))
+	for i, status := range statuses {
+		parts[i] = fmt.Sprintf("%s: %d", utils.ColorizeStatus(status), counts[status])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// statusTemplateData is what a status --format template is evaluated against: the fields of the
+// deployment, plus the project as .Project (empty if it couldn't be fetched)
+type statusTemplateData struct {
+	types.Deployment
+	Project types.Project
+}
+
+// printDeploymentsWithTemplate prints each deployment on its own line using tmpl
+func printDeploymentsWithTemplate(tmpl *template.Template, deployments []types.Deployment) error {
+	for _, d := range deployments {
+		if err := tmpl.Execute(os.Stdout, d); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	return nil
+}
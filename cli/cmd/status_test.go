@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/velgardey/yok/cli/internal/types"
+)
+
+// deploymentIDs returns the IDs of deployments in order
+func deploymentIDs(deployments []types.Deployment) []string {
+	ids := make([]string, len(deployments))
+	for i, d := range deployments {
+		ids[i] = d.ID
+	}
+	return ids
+}
+
+func TestSortDeployments(t *testing.T) {
+	now := time.Now()
+	// Listed oldest first, as the API server may, with two created at the same time
+	listed := func() []types.Deployment {
+		return []types.Deployment{
+			{ID: "failed-3h", Status: "FAILED", CreatedAt: now.Add(-3 * time.Hour)},
+			{ID: "completed-2h-b", Status: "COMPLETED", CreatedAt: now.Add(-2 * time.Hour)},
+			{ID: "completed-2h-a", Status: "COMPLETED", CreatedAt: now.Add(-2 * time.Hour)},
+			{ID: "cancelled-90m", Status: "CANCELLED", CreatedAt: now.Add(-90 * time.Minute)},
+			{ID: "completed-1h", Status: "COMPLETED", CreatedAt: now.Add(-time.Hour)},
+			{ID: "queued-1m", Status: "QUEUED", CreatedAt: now.Add(-time.Minute)},
+		}
+	}
+
+	deployments := listed()
+	sortDeploymentsByCreatedDesc(deployments)
+	want := []string{"queued-1m", "completed-1h", "cancelled-90m", "completed-2h-a", "completed-2h-b", "failed-3h"}
+	if got := deploymentIDs(deployments); !reflect.DeepEqual(got, want) {
+		t.Errorf("sortDeploymentsByCreatedDesc = %v, want %v", got, want)
+	}
+
+	deployments = listed()
+	sortDeploymentsForSelection(deployments)
+	want = []string{"queued-1m", "completed-1h", "completed-2h-a", "completed-2h-b", "failed-3h", "cancelled-90m"}
+	if got := deploymentIDs(deployments); !reflect.DeepEqual(got, want) {
+		t.Errorf("sortDeploymentsForSelection = %v, want %v", got, want)
+	}
+}
@@ -3,81 +3,236 @@ package cmd
 import (
 	"archive/tar"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/blang/semver"
+	"github.com/google/go-github/v30/github"
 	"github.com/rhysd/go-github-selfupdate/selfupdate"
 	"github.com/spf13/cobra"
+	"github.com/velgardey/yok/cli/internal/config"
+	"github.com/velgardey/yok/cli/internal/types"
 	"github.com/velgardey/yok/cli/internal/utils"
 )
 
+// defaultUpdateCheckInterval is how long a looked up latest release is reused unless the user
+// config sets updateCheckInterval
+const defaultUpdateCheckInterval = 24 * time.Hour
+
+// Where releases are looked up and downloaded from; tests point these at a local server
+var (
+	releasesURL    = "https://github.com/velgardey/yok/releases"
+	releasesAPIURL = "https://api.github.com/repos/velgardey/yok/releases"
+)
+
 // checkForUpdates checks for newer version on GitHub
-func checkForUpdates() (string, bool, error) {
+// With useCache set, the latest release found by a recent check is reused instead of asking GitHub again
+// With prerelease set, prereleases are considered too
+func checkForUpdates(useCache bool, prerelease bool) (string, bool, error) {
 	currentVersion := getCurrentVersion()
 
+	channel := config.ChannelStable
+	if prerelease {
+		channel = config.ChannelBeta
+	}
+
+	latestVersionStr, cached := "", false
+	if useCache {
+		latestVersionStr, cached = cachedLatestVersion(channel)
+	}
+	if !cached {
+		fetch := fetchLatestVersion
+		if prerelease {
+			fetch = fetchLatestPrerelease
+		}
+		latest, err := fetch()
+		if err != nil {
+			return "", false, err
+		}
+		latestVersionStr = latest
+		cacheLatestVersion(latestVersionStr, channel)
+	}
+
+	// Compare versions the same way on every platform (dev builds always update)
+	hasUpdate := utils.CompareVersions(currentVersion, latestVersionStr)
+
+	return latestVersionStr, hasUpdate, nil
+}
+
+// cachedLatestVersion returns the latest release of channel stored in the user config if it
+// was looked up within the update check interval
+func cachedLatestVersion(channel string) (string, bool) {
+	userConfig, err := config.LoadUserConfig()
+	if err != nil || userConfig.LatestVersion == "" {
+		return "", false
+	}
+
+	cachedChannel := userConfig.LatestVersionChannel
+	if cachedChannel == "" {
+		cachedChannel = config.ChannelStable
+	}
+	if cachedChannel != channel {
+		return "", false
+	}
+
+	interval := defaultUpdateCheckInterval
+	if userConfig.UpdateCheckInterval != "" {
+		if parsed, err := time.ParseDuration(userConfig.UpdateCheckInterval); err == nil {
+			interval = parsed
+		}
+	}
+
+	if time.Since(userConfig.LatestVersionCheckedAt) > interval {
+		return "", false
+	}
+	return userConfig.LatestVersion, true
+}
+
+// cacheLatestVersion stores the latest release of channel in the user config; failures only
+// mean the next check asks GitHub again
+func cacheLatestVersion(latestVersion string, channel string) {
+	config.UpdateUserConfig(func(userConfig *types.UserConfig) error {
+		userConfig.LatestVersion = latestVersion
+		userConfig.LatestVersionChannel = channel
+		userConfig.LatestVersionCheckedAt = time.Now()
+		return nil
+	})
+}
+
+// fetchLatestVersion looks up the latest release on GitHub
+func fetchLatestVersion() (string, error) {
 	// Create and set HTTP client with reasonable timeout
 	httpClient := utils.CreateHTTPClient()
 	http.DefaultClient = httpClient
 
 	var latestVersionStr string
-	var hasUpdate bool
-	var err error
 
 	if runtime.GOOS == "windows" {
 		// Use non-API method for Windows
-		latestVersionStr, err = getLatestVersionNoAPI()
-		if err != nil {
-			return "", false, fmt.Errorf("failed to check for updates: %w", err)
-		}
-
-		// Parse versions for comparison
-		currentSemver, err := semver.Parse(currentVersion)
+		latest, err := getLatestVersionNoAPI()
 		if err != nil {
-			if currentVersion == "dev" || currentVersion == "development" {
-				hasUpdate = true // Always update dev versions
-			} else {
-				return "", false, fmt.Errorf("failed to parse current version: %w", err)
-			}
-		} else {
-			latestSemver, err := semver.Parse(latestVersionStr)
-			if err != nil {
-				return "", false, fmt.Errorf("failed to parse latest version: %w", err)
-			}
-			hasUpdate = latestSemver.GT(currentSemver)
+			return "", fmt.Errorf("failed to check for updates: %w", err)
 		}
+		latestVersionStr = latest
 	} else {
 		// Use GitHub API for non-Windows platforms
 		latest, found, err := selfupdate.DetectLatest("velgardey/yok")
 		if err != nil {
-			return "", false, fmt.Errorf("error checking for updates: %w", err)
+			return latestVersionAfterAPIError(err)
 		}
 
 		if !found {
-			return "", false, fmt.Errorf("no release found for velgardey/yok")
+			return "", fmt.Errorf("no release found for velgardey/yok")
 		}
 
-		v, err := semver.Parse(currentVersion)
+		latestVersionStr = latest.Version.String()
+	}
+
+	return latestVersionStr, nil
+}
+
+// latestVersionAfterAPIError looks up the latest release on the releases page if the API lookup
+// failed with err because of the rate limit, and returns err otherwise
+func latestVersionAfterAPIError(err error) (string, error) {
+	if !isRateLimitError(err) {
+		return "", fmt.Errorf("error checking for updates: %w", err)
+	}
+
+	// The API limit is per IP, which CI runners often share; the releases page has none
+	latestNoAPI, fallbackErr := getLatestVersionNoAPI()
+	if fallbackErr != nil {
+		return "", fmt.Errorf("the GitHub API rate limit was exceeded and the releases page couldn't be checked either (%v); try again later, or set GITHUB_TOKEN to raise the limit", fallbackErr)
+	}
+	return latestNoAPI, nil
+}
+
+// fetchLatestPrerelease looks up the newest release on GitHub, including prereleases, using the
+// releases list API
+func fetchLatestPrerelease() (string, error) {
+	client := utils.CreateHTTPClient()
+
+	req, err := http.NewRequest(http.MethodGet, releasesAPIURL+"?per_page=100", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch releases: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		return "", fmt.Errorf("the GitHub API rate limit was exceeded; try again later")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch releases: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var releases []types.GitHubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return "", fmt.Errorf("failed to parse releases: %w", err)
+	}
+
+	// Pick the highest version rather than the most recent release, which may be a backport
+	var latest *semver.Version
+	for _, release := range releases {
+		releaseVersion, err := semver.ParseTolerant(strings.TrimPrefix(release.TagName, "v"))
 		if err != nil {
-			// Handle dev version
-			if currentVersion == "dev" || currentVersion == "development" {
-				return latest.Version.String(), true, nil // Always update dev versions
-			}
-			return "", false, fmt.Errorf("failed to parse current version: %w", err)
+			continue
 		}
-
-		latestVersionStr = latest.Version.String()
-		hasUpdate = latest.Version.GT(v)
+		if latest == nil || releaseVersion.GT(*latest) {
+			latest = &releaseVersion
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no release found for velgardey/yok")
 	}
+	return latest.String(), nil
+}
 
-	return latestVersionStr, hasUpdate, nil
+// onBetaChannel reports whether the user subscribed to the beta channel in the user config
+func onBetaChannel() bool {
+	userConfig, err := config.LoadUserConfig()
+	return err == nil && userConfig.Channel == config.ChannelBeta
+}
+
+// isPrereleaseVersion reports whether version is a prerelease, such as 1.2.0-beta.1
+func isPrereleaseVersion(version string) bool {
+	parsed, err := semver.ParseTolerant(version)
+	return err == nil && len(parsed.Pre) > 0
+}
+
+// isRateLimitError reports whether the GitHub API refused a request because of its rate limit
+func isRateLimitError(err error) bool {
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var respErr *github.ErrorResponse
+	switch {
+	case errors.As(err, &rateLimitErr), errors.As(err, &abuseErr):
+		return true
+	case errors.As(err, &respErr):
+		return respErr.Response != nil &&
+			(respErr.Response.StatusCode == http.StatusForbidden || respErr.Response.StatusCode == http.StatusTooManyRequests)
+	default:
+		return false
+	}
 }
 
 // getCurrentVersion returns the current version without the 'v' prefix
@@ -95,7 +250,7 @@ func getLatestVersionNoAPI() (string, error) {
 		return http.ErrUseLastResponse
 	}
 
-	resp, err := client.Get("https://github.com/velgardey/yok/releases/latest")
+	resp, err := client.Get(releasesURL + "/latest")
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch latest release: %w", err)
 	}
@@ -212,16 +367,12 @@ func isLocationWritable(dir string) bool {
 }
 
 // runUnixUpdate handles the update process for Unix-based systems (Linux/macOS) using atomic rename
-func runUnixUpdate(execPath string, version string) error {
-	// Determine archive name based on platform and architecture
-	osName := runtime.GOOS
-	arch := runtime.GOARCH
-
+func runUnixUpdate(execPath string, version string, platform releasePlatform) error {
 	// Format archive name: yok_VERSION_OS_ARCH.tar.gz
-	archiveName := fmt.Sprintf("yok_%s_%s_%s.tar.gz", version, osName, arch)
+	archiveName := fmt.Sprintf("yok_%s_%s_%s.tar.gz", version, platform.os, platform.arch)
 
 	// Format download URL
-	downloadURL := fmt.Sprintf("https://github.com/velgardey/yok/releases/download/v%s/%s", version, archiveName)
+	downloadURL := releaseAssetURL(version, archiveName)
 
 	// Create temp directory for update
 	tmpDir, err := os.MkdirTemp("", "yok-update-*")
@@ -237,6 +388,12 @@ func runUnixUpdate(execPath string, version string) error {
 		return fmt.Errorf("failed to download update: %w", err)
 	}
 
+	// Verify the archive against the release checksums before using it
+	utils.InfoColor.Println("Verifying checksum...")
+	if err := verifyChecksum(archivePath, archiveName, version, tmpDir); err != nil {
+		return fmt.Errorf("failed to verify update, nothing was installed: %w", err)
+	}
+
 	// Extract binary from archive
 	utils.InfoColor.Println("Extracting update...")
 	extractedBinaryPath, err := extractBinary(archivePath, tmpDir)
@@ -252,36 +409,298 @@ func runUnixUpdate(execPath string, version string) error {
 	// Get target path
 	targetPath := execPath
 
-	utils.InfoColor.Println("This operation requires elevated privileges.")
-	fmt.Println("You will be prompted for your password.")
+	// Only use sudo if the binary can't be replaced by the current user
+	useSudo := needsSudo(targetPath)
+	if useSudo {
+		utils.InfoColor.Println("This operation requires elevated privileges.")
+		fmt.Println("You will be prompted for your password.")
+	}
+
+	// Keep the current binary so the update can be rolled back
+	if err := backupBinary(targetPath, useSudo); err != nil {
+		return err
+	}
 
-	// Use sudo to copy the file to the target location
 	utils.InfoColor.Println("Installing update...")
-	sudoCmd := exec.Command("sudo", "cp", extractedBinaryPath, targetPath)
+	if err := installBinary(extractedBinaryPath, targetPath, useSudo); err != nil {
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+
+	utils.SuccessColor.Printf("\n[OK] Yok CLI has been updated to v%s successfully!\n", version)
+	fmt.Println("Run 'yok version' to verify the update.")
+	return nil
+}
+
+// releasePlatform is the OS and architecture of the release archive to install
+type releasePlatform struct {
+	os   string
+	arch string
+}
+
+// Operating systems and architectures that releases are built for, as in .goreleaser.yml
+var (
+	publishedOSes  = []string{"linux", "darwin", "windows"}
+	publishedArchs = []string{"amd64", "arm64"}
+)
+
+// resolvePlatform returns the platform of the release to install: this binary's, unless
+// overridden, e.g. to install the native build from an amd64 binary running under Rosetta.
+// It fails for platforms that no release is built for.
+func resolvePlatform(osOverride string, archOverride string) (releasePlatform, error) {
+	platform := releasePlatform{os: runtime.GOOS, arch: runtime.GOARCH}
+	if osOverride != "" {
+		platform.os = osOverride
+	}
+	if archOverride != "" {
+		platform.arch = archOverride
+	}
+
+	if !slices.Contains(publishedOSes, platform.os) {
+		return platform, fmt.Errorf("no release is published for the %s OS (available: %s)", platform.os, strings.Join(publishedOSes, ", "))
+	}
+	if !slices.Contains(publishedArchs, platform.arch) {
+		return platform, fmt.Errorf("no release is published for the %s architecture (available: %s); download a build for your platform from https://github.com/velgardey/yok/releases", platform.arch, strings.Join(publishedArchs, ", "))
+	}
+	// Windows and Unix releases are packaged and installed differently
+	if (platform.os == "windows") != (runtime.GOOS == "windows") {
+		return platform, fmt.Errorf("a %s release can't be installed on %s", platform.os, runtime.GOOS)
+	}
+	return platform, nil
+}
+
+// needsSudo reports whether replacing the binary at targetPath requires elevated privileges
+func needsSudo(targetPath string) bool {
+	return !isLocationWritable(filepath.Dir(targetPath))
+}
+
+// backupBinary copies the binary at targetPath to its backup path so it can be restored later
+func backupBinary(targetPath string, useSudo bool) error {
+	if _, err := os.Stat(targetPath); err != nil {
+		return nil // Nothing to back up
+	}
+
+	utils.InfoColor.Println("Backing up current binary...")
+	if useSudo {
+		if err := runWithSudo("cp", targetPath, backupPathFor(targetPath)); err != nil {
+			return fmt.Errorf("failed to back up current binary with sudo: %w", err)
+		}
+		return nil
+	}
+
+	if err := copyFile(targetPath, backupPathFor(targetPath)); err != nil {
+		return fmt.Errorf("failed to back up current binary: %w", err)
+	}
+	return nil
+}
+
+// installBinary replaces the binary at targetPath with the one at srcPath. The new binary is
+// written next to the target and renamed over it, so the target is never half-written.
+func installBinary(srcPath string, targetPath string, useSudo bool) error {
+	if useSudo {
+		return installBinaryWithSudo(srcPath, targetPath)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(targetPath), ".yok-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+
+	if err := copyFile(srcPath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	err = os.Rename(tmpPath, targetPath)
+	os.Remove(tmpPath)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) && !errors.Is(err, syscall.EBUSY) {
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+
+	// The target is on another filesystem than its directory, e.g. a bind-mounted file, so it
+	// can only be copied over. It's removed first, since a running binary can't be overwritten.
+	utils.WarnColor.Println("Warning: The binary can't be replaced atomically, copying it instead")
+	if err := os.Remove(targetPath); err != nil {
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+	if err := copyFile(srcPath, targetPath); err != nil {
+		return fmt.Errorf("failed to copy binary: %w", err)
+	}
+	return nil
+}
+
+// installBinaryWithSudo replaces the binary at targetPath with sudo, staging the new binary next
+// to the target and moving it over the target. mv renames it atomically, and falls back to
+// copying when the target is on another filesystem.
+func installBinaryWithSudo(srcPath string, targetPath string) error {
+	stagingPath := filepath.Join(filepath.Dir(targetPath), "."+filepath.Base(targetPath)+".new")
+
+	if err := runWithSudo("cp", srcPath, stagingPath); err != nil {
+		return fmt.Errorf("failed to copy binary with sudo: %w", err)
+	}
+	if err := runWithSudo("chmod", "755", stagingPath); err != nil {
+		runWithSudo("rm", "-f", stagingPath)
+		return fmt.Errorf("failed to set permissions with sudo: %w", err)
+	}
+	if err := runWithSudo("mv", "-f", stagingPath, targetPath); err != nil {
+		runWithSudo("rm", "-f", stagingPath)
+		return fmt.Errorf("failed to replace binary with sudo: %w", err)
+	}
+	return nil
+}
+
+// copyFile copies the file at srcPath to destPath as an executable, replacing destPath if it exists
+func copyFile(srcPath string, destPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dest, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dest, src); err != nil {
+		dest.Close()
+		return err
+	}
+	if err := dest.Close(); err != nil {
+		return err
+	}
+
+	// The mode passed to OpenFile doesn't apply to files that already exist
+	return os.Chmod(destPath, 0755)
+}
+
+// runWithSudo runs a command with sudo, letting it prompt for the password
+func runWithSudo(args ...string) error {
+	sudoCmd := exec.Command("sudo", args...)
 	sudoCmd.Stdin = os.Stdin
 	sudoCmd.Stdout = os.Stdout
 	sudoCmd.Stderr = os.Stderr
+	return sudoCmd.Run()
+}
 
-	if err := sudoCmd.Run(); err != nil {
-		return fmt.Errorf("failed to copy update with sudo: %w", err)
+// backupPathFor returns where the binary at targetPath is backed up before it is replaced
+// Only the most recent backup is kept
+func backupPathFor(targetPath string) string {
+	return targetPath + ".backup"
+}
+
+// runRollback restores the binary that the last update replaced
+func runRollback(force bool) error {
+	installDir, targetName, err := getExePath()
+	if err != nil {
+		return err
 	}
 
-	// Set permissions with sudo
-	chmodCmd := exec.Command("sudo", "chmod", "755", targetPath)
-	chmodCmd.Stdin = os.Stdin
-	chmodCmd.Stdout = os.Stdout
-	chmodCmd.Stderr = os.Stderr
+	targetPath := filepath.Join(installDir, targetName)
+	backupPath := backupPathFor(targetPath)
+	if _, err := os.Stat(backupPath); err != nil {
+		return fmt.Errorf("no backup found at %s; one is made each time the CLI is updated", backupPath)
+	}
 
-	if err := chmodCmd.Run(); err != nil {
-		return fmt.Errorf("failed to set permissions with sudo: %w", err)
+	// Confirm rollback unless forced
+	if !force {
+		rollbackConfirm := false
+		rollbackPrompt := &survey.Confirm{
+			Message: fmt.Sprintf("Do you want to restore the previous version from %s?", backupPath),
+			Default: true,
+		}
+		opts := utils.GetSurveyOptions()
+		if err := survey.AskOne(rollbackPrompt, &rollbackConfirm, opts); err != nil {
+			return fmt.Errorf("rollback cancelled: %v", err)
+		}
+
+		if !rollbackConfirm {
+			utils.InfoColor.Println("Rollback cancelled")
+			return nil
+		}
 	}
 
-	utils.SuccessColor.Printf("\n[OK] Yok CLI has been updated to v%s successfully!\n", version)
-	fmt.Println("Run 'yok version' to verify the update.")
+	// Handle platform-specific rollback
+	if runtime.GOOS == "windows" {
+		return runWindowsRollback(targetPath, backupPath)
+	}
+	return runUnixRollback(targetPath, backupPath)
+}
+
+// runUnixRollback copies the backup over the binary on Unix-based systems (Linux/macOS)
+func runUnixRollback(targetPath string, backupPath string) error {
+	// Only use sudo if the binary can't be replaced by the current user
+	useSudo := needsSudo(targetPath)
+	if useSudo {
+		utils.InfoColor.Println("This operation requires elevated privileges.")
+		fmt.Println("You will be prompted for your password.")
+	}
+
+	utils.InfoColor.Println("Restoring previous version...")
+	if err := installBinary(backupPath, targetPath, useSudo); err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	utils.SuccessColor.Println("\n[OK] The previous version of Yok CLI has been restored")
+	fmt.Println("Run 'yok version' to verify the rollback.")
 	return nil
 }
 
-// downloadFile downloads a file from the given URL
+// runWindowsRollback copies the backup over the binary on Windows from a separate process,
+// since the running binary can't be replaced
+func runWindowsRollback(targetPath string, backupPath string) error {
+	scriptPath := filepath.Join(os.TempDir(), "yok_rollback.ps1")
+	scriptContent := []string{
+		"# Yok CLI Rollback Script",
+		"$ErrorActionPreference = \"Stop\"",
+		"",
+		"try {",
+		"    # Wait for the main process to exit",
+		"    Start-Sleep -Seconds 2",
+		"    ",
+		"    Write-Host \"Restoring previous version...\" -ForegroundColor Cyan",
+		fmt.Sprintf("    Copy-Item -Path \"%s\" -Destination \"%s\" -Force", backupPath, targetPath),
+		"    Write-Host \"`n[OK] The previous version of Yok CLI has been restored\" -ForegroundColor Green",
+		"    Write-Host \"Run 'yok version' to verify the rollback.\" -ForegroundColor Cyan",
+		"} catch {",
+		"    Write-Host \"`n====== ERROR ======\" -ForegroundColor Red",
+		"    Write-Host \"Failed to restore the backup: $_\" -ForegroundColor Red",
+		"    Start-Sleep -Seconds 5",
+		"}",
+		"",
+		"# Self-delete after a delay",
+		"Start-Sleep -Seconds 1",
+		"Remove-Item -Path $PSCommandPath -Force -ErrorAction SilentlyContinue",
+	}
+
+	if err := os.WriteFile(scriptPath, []byte(strings.Join(scriptContent, "\n")), 0700); err != nil {
+		return fmt.Errorf("failed to create rollback script: %w", err)
+	}
+
+	utils.InfoColor.Println("Starting rollback process...")
+	utils.InfoColor.Println("The CLI will exit and a new process will complete the rollback.")
+
+	// Launch PowerShell script as a separate process
+	cmd := exec.Command("powershell.exe", "-ExecutionPolicy", "Bypass", "-File", scriptPath)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Start (not Run) to avoid waiting for completion
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start rollback process: %v", err)
+	}
+
+	fmt.Println("Rollback in progress... please wait.")
+	os.Exit(0)
+	return nil // This is never reached
+}
+
+// downloadFile downloads a file from the given URL, showing the progress in a spinner
 func downloadFile(url string, destPath string) error {
 	client := utils.CreateHTTPClient()
 
@@ -301,10 +720,59 @@ func downloadFile(url string, destPath string) error {
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	s := utils.StartSpinner("")
+	defer utils.StopSpinner(s)
+	progress := utils.NewProgressWriter(s, "Downloading "+path.Base(url)+"...", resp.ContentLength)
+
+	_, err = io.Copy(out, io.TeeReader(resp.Body, progress))
 	return err
 }
 
+// verifyChecksum checks the SHA-256 of the downloaded archive against the checksums.txt
+// published with the release
+func verifyChecksum(archivePath string, archiveName string, version string, tmpDir string) error {
+	checksumsURL := releaseAssetURL(version, "checksums.txt")
+	checksumsPath := filepath.Join(tmpDir, "checksums.txt")
+	if err := downloadFile(checksumsURL, checksumsPath); err != nil {
+		return fmt.Errorf("failed to download checksums: %w", err)
+	}
+
+	checksums, err := os.ReadFile(checksumsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	// Each line is "<sha256>  <file name>"
+	var expected string
+	for _, line := range strings.Split(string(checksums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == archiveName {
+			expected = strings.ToLower(fields[0])
+			break
+		}
+	}
+	if expected == "" {
+		return fmt.Errorf("no checksum found for %s", archiveName)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to hash archive: %w", err)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archiveName, expected, actual)
+	}
+
+	return nil
+}
+
 // extractBinary extracts the binary from a tar.gz archive
 func extractBinary(archivePath string, destDir string) (string, error) {
 	file, err := os.Open(archivePath)
@@ -360,9 +828,9 @@ func extractBinary(archivePath string, destDir string) (string, error) {
 }
 
 // runWindowsUpdate handles the update process for Windows
-func runWindowsUpdate(execPath string, version string) error {
+func runWindowsUpdate(execPath string, version string, platform releasePlatform) error {
 	// Create the PowerShell script
-	scriptPath, err := createWindowsUpdateScript(execPath, version)
+	scriptPath, err := createWindowsUpdateScript(execPath, version, platform)
 	if err != nil {
 		return err
 	}
@@ -388,12 +856,13 @@ func runWindowsUpdate(execPath string, version string) error {
 }
 
 // createWindowsUpdateScript generates a PowerShell script for updating the Windows binary
-func createWindowsUpdateScript(targetPath, version string) (string, error) {
+func createWindowsUpdateScript(targetPath, version string, platform releasePlatform) (string, error) {
 	tmpDir := os.TempDir()
 	scriptPath := filepath.Join(tmpDir, "yok_update.ps1")
-	downloadUrl := fmt.Sprintf("https://github.com/velgardey/yok/releases/download/v%s/yok_%s_windows_amd64.zip",
-		version, version)
-	backupPath := targetPath + ".backup"
+	archiveName := fmt.Sprintf("yok_%s_windows_%s.zip", version, platform.arch)
+	downloadUrl := releaseAssetURL(version, archiveName)
+	checksumsUrl := releaseAssetURL(version, "checksums.txt")
+	backupPath := backupPathFor(targetPath)
 
 	// Build the script content
 	scriptContent := []string{
@@ -460,6 +929,24 @@ func createWindowsUpdateScript(targetPath, version string) (string, error) {
 		"        Handle-Error \"Failed to download the update package\" $_",
 		"    }",
 		"    ",
+		"    # Verify the package against the release checksums before using it",
+		"    Write-Host \"Verifying checksum...\" -ForegroundColor Cyan",
+		"    $checksumsPath = \"$updateDir\\checksums.txt\"",
+		"    try {",
+		fmt.Sprintf("        Invoke-WebRequest -Uri \"%s\" -OutFile $checksumsPath", checksumsUrl),
+		"    } catch {",
+		"        Handle-Error \"Failed to download the release checksums\" $_",
+		"    }",
+		fmt.Sprintf("    $checksumLine = Get-Content $checksumsPath | Where-Object { ($_ -split '\\s+')[1] -eq \"%s\" } | Select-Object -First 1", archiveName),
+		"    if (-not $checksumLine) {",
+		fmt.Sprintf("        Handle-Error \"No checksum found for %s\"", archiveName),
+		"    }",
+		"    $expectedHash = ($checksumLine -split '\\s+')[0]",
+		"    $actualHash = (Get-FileHash -Path $zipPath -Algorithm SHA256).Hash",
+		"    if ($actualHash -ne $expectedHash) {",
+		"        Handle-Error \"Checksum mismatch: expected $expectedHash, got $actualHash. Nothing was installed.\"",
+		"    }",
+		"    ",
 		"    # Create backup of current executable",
 		"    Write-Host \"Creating backup...\" -ForegroundColor Cyan",
 		"    try {",
@@ -533,11 +1020,20 @@ func getExePath() (string, string, error) {
 	return installDir, targetName, nil
 }
 
+// errUpdateAvailable is returned by runSelfUpdate when it only checked for updates and found one
+var errUpdateAvailable = errors.New("update available")
+
 // runSelfUpdate implements the update logic
-func runSelfUpdate(_ *cobra.Command, force bool, checkOnly bool) error {
-	// Check for updates
+func runSelfUpdate(_ *cobra.Command, force bool, checkOnly bool, noCache bool, prerelease bool, targetVersion string, platform releasePlatform) error {
+	// A specific version is installed whether or not it is newer
+	if targetVersion != "" {
+		return installVersion(strings.TrimPrefix(targetVersion, "v"), force, platform)
+	}
+
+	// Check for updates; a recent result is good enough to report, but installing always
+	// looks up the latest release
 	spinner := utils.StartSpinner("Checking for updates...")
-	latestVersionStr, hasUpdate, err := checkForUpdates()
+	latestVersionStr, hasUpdate, err := checkForUpdates(checkOnly && !noCache, prerelease || onBetaChannel())
 	utils.StopSpinner(spinner)
 
 	if err != nil {
@@ -550,10 +1046,14 @@ func runSelfUpdate(_ *cobra.Command, force bool, checkOnly bool) error {
 	if checkOnly {
 		if hasUpdate {
 			utils.InfoColor.Printf("\nUpdate available: v%s (current: %s)\n", latestVersionStr, currentVersion)
-			fmt.Printf("Run 'yok self-update' to update to the latest version\n")
-		} else {
-			utils.SuccessColor.Printf("You're already using the latest version (v%s)\n", currentVersion)
+			if prerelease {
+				fmt.Printf("Run 'yok self-update --prerelease' to update to it\n")
+			} else {
+				fmt.Printf("Run 'yok self-update' to update to the latest version\n")
+			}
+			return errUpdateAvailable
 		}
+		utils.SuccessColor.Printf("You're already using the latest version (v%s)\n", currentVersion)
 		return nil
 	}
 
@@ -569,11 +1069,65 @@ func runSelfUpdate(_ *cobra.Command, force bool, checkOnly bool) error {
 	fmt.Printf("Latest version: v%s\n", latestVersionStr)
 	fmt.Printf("Release page: https://github.com/velgardey/yok/releases/tag/v%s\n", latestVersionStr)
 
+	return confirmAndInstall(latestVersionStr, force, platform)
+}
+
+// installVersion installs a specific release, such as an older one to roll back a bad update
+func installVersion(version string, force bool, platform releasePlatform) error {
+	spinner := utils.StartSpinner(fmt.Sprintf("Checking release v%s...", version))
+	err := checkReleaseExists(version)
+	utils.StopSpinner(spinner)
+	if err != nil {
+		return err
+	}
+
+	// Display update information
+	utils.InfoColor.Printf("\nSelected version:\n")
+	fmt.Printf("Current version: v%s\n", getCurrentVersion())
+	fmt.Printf("Target version: v%s\n", version)
+	fmt.Printf("Release page: https://github.com/velgardey/yok/releases/tag/v%s\n", version)
+
+	return confirmAndInstall(version, force, platform)
+}
+
+// releaseAssetURL returns the download URL of a file attached to the release of version
+func releaseAssetURL(version string, name string) string {
+	return fmt.Sprintf("%s/download/v%s/%s", releasesURL, version, name)
+}
+
+// checkReleaseExists checks that GitHub has a release for version
+func checkReleaseExists(version string) error {
+	client := utils.CreateHTTPClient()
+
+	resp, err := client.Head(fmt.Sprintf("%s/tag/v%s", releasesURL, version))
+	if err != nil {
+		return fmt.Errorf("failed to check release v%s: %w", version, err)
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("release v%s doesn't exist", version)
+	default:
+		return fmt.Errorf("failed to check release v%s: unexpected status code: %d", version, resp.StatusCode)
+	}
+}
+
+// confirmAndInstall asks for confirmation unless forced, then installs the given release
+func confirmAndInstall(version string, force bool, platform releasePlatform) error {
+	currentVersion := getCurrentVersion()
+
+	if isPrereleaseVersion(version) {
+		utils.WarnColor.Printf("\nWarning: v%s is a prerelease and may be unstable. Run 'yok self-update --rollback' to go back if it causes problems.\n", version)
+	}
+
 	// Confirm update unless forced
 	if !force {
 		updateConfirm := false
 		updatePrompt := &survey.Confirm{
-			Message: fmt.Sprintf("Do you want to update from v%s to v%s?", currentVersion, latestVersionStr),
+			Message: fmt.Sprintf("Do you want to update from v%s to v%s?", currentVersion, version),
 			Default: true,
 		}
 		opts := utils.GetSurveyOptions()
@@ -597,9 +1151,9 @@ func runSelfUpdate(_ *cobra.Command, force bool, checkOnly bool) error {
 
 	// Handle platform-specific update
 	if runtime.GOOS == "windows" {
-		return runWindowsUpdate(targetPath, latestVersionStr)
+		return runWindowsUpdate(targetPath, version, platform)
 	} else {
-		return runUnixUpdate(targetPath, latestVersionStr)
+		return runUnixUpdate(targetPath, version, platform)
 	}
 }
 
@@ -608,17 +1162,44 @@ var updateCmd *cobra.Command
 
 func init() {
 	var (
-		force     bool
-		checkOnly bool
+		force         bool
+		checkOnly     bool
+		noCache       bool
+		prerelease    bool
+		targetVersion string
+		rollback      bool
+		osOverride    string
+		archOverride  string
 	)
 
 	updateCmd = &cobra.Command{
-		Use:     "self-update",
-		Short:   "Update Yok CLI to the latest version",
-		Long:    `Update Yok CLI to the latest version from GitHub releases.`,
+		Use:   "self-update",
+		Short: "Update Yok CLI to the latest version",
+		Long: `Update Yok CLI to the latest version from GitHub releases.
+
+With --check, nothing is installed and the exit code tells scripts whether an update is available:
+  0   You're using the latest version
+  10  An update is available
+  1   Checking for updates failed`,
 		Aliases: []string{"update"},
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := runSelfUpdate(cmd, force, checkOnly); err != nil {
+			if rollback {
+				if err := runRollback(force); err != nil {
+					utils.HandleError(err, "Rollback failed")
+				}
+				return
+			}
+
+			platform, err := resolvePlatform(osOverride, archOverride)
+			if err != nil {
+				utils.HandleError(err, "Update failed")
+			}
+
+			err = runSelfUpdate(cmd, force, checkOnly, noCache, prerelease, targetVersion, platform)
+			if errors.Is(err, errUpdateAvailable) {
+				os.Exit(utils.ExitUpdateAvailable)
+			}
+			if err != nil {
 				utils.ErrorColor.Printf("Update failed: %v\n", err)
 
 				utils.WarnColor.Println("\nTroubleshooting tips:")
@@ -642,6 +1223,16 @@ func init() {
 
 	updateCmd.Flags().BoolVarP(&force, "force", "f", false, "Force update without confirmation")
 	updateCmd.Flags().BoolVarP(&checkOnly, "check", "c", false, "Only check for updates without installing")
+	updateCmd.Flags().BoolVar(&noCache, "no-cache", false, "Look up the latest release even if it was checked recently")
+	updateCmd.Flags().BoolVar(&prerelease, "prerelease", false, "Update to the newest release including prereleases, which may be unstable")
+	updateCmd.Flags().StringVar(&targetVersion, "version", "", "Install a specific version instead of the latest, e.g. 1.2.3")
+	updateCmd.Flags().BoolVar(&rollback, "rollback", false, "Restore the version that the last update replaced")
+	updateCmd.Flags().StringVar(&osOverride, "os", "", "Install the release built for this OS instead of the detected one")
+	updateCmd.Flags().StringVar(&archOverride, "arch", "", "Install the release built for this architecture instead of the detected one, e.g. arm64")
+	updateCmd.Flags().MarkHidden("os")
+	updateCmd.Flags().MarkHidden("arch")
+	updateCmd.MarkFlagsMutuallyExclusive("check", "version", "rollback")
+	updateCmd.MarkFlagsMutuallyExclusive("prerelease", "version", "rollback")
 
 	RootCmd.AddCommand(updateCmd)
 }
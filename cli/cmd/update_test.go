@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v30/github"
+	"github.com/velgardey/yok/cli/internal/config"
+	"github.com/velgardey/yok/cli/internal/types"
+)
+
+// useReleasesServer points release lookups at a local server answering with handler
+func useReleasesServer(t *testing.T, handler http.Handler) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	url, apiURL := releasesURL, releasesAPIURL
+	t.Cleanup(func() { releasesURL, releasesAPIURL = url, apiURL })
+	releasesURL = server.URL + "/velgardey/yok/releases"
+	releasesAPIURL = server.URL + "/repos/velgardey/yok/releases"
+}
+
+// setCheckedAt changes when the cached latest release was looked up
+func setCheckedAt(t *testing.T, checkedAt time.Time, interval string) {
+	t.Helper()
+	err := config.UpdateUserConfig(func(userConfig *types.UserConfig) error {
+		userConfig.LatestVersionCheckedAt = checkedAt
+		userConfig.UpdateCheckInterval = interval
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLatestVersionCache(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	injected := version
+	t.Cleanup(func() { version = injected })
+	version = "1.0.0"
+
+	var lookups atomic.Int32
+	latest := "v1.1.0"
+	useReleasesServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		w.Write([]byte(`[{"tag_name":"` + latest + `"},{"tag_name":"v1.0.0"}]`))
+	}))
+
+	tests := []struct {
+		name        string
+		setup       func()
+		useCache    bool
+		wantVersion string
+		wantLookups int32
+	}{
+		{"first check", func() {}, true, "1.1.0", 1},
+		{"fresh cache", func() { latest = "v1.2.0" }, true, "1.1.0", 1},
+		{"--no-cache", func() {}, false, "1.2.0", 2},
+		{"stale cache", func() { latest = "v1.3.0"; setCheckedAt(t, time.Now().Add(-25*time.Hour), "") }, true, "1.3.0", 3},
+		{"configured interval", func() { latest = "v1.4.0"; setCheckedAt(t, time.Now().Add(-2*time.Hour), "1h") }, true, "1.4.0", 4},
+		{"within the configured interval", func() { latest = "v1.5.0"; setCheckedAt(t, time.Now().Add(-2*time.Hour), "3h") }, true, "1.4.0", 4},
+	}
+	for _, tt := range tests {
+		tt.setup()
+		got, hasUpdate, err := checkForUpdates(tt.useCache, true)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.wantVersion || !hasUpdate {
+			t.Errorf("%s: checkForUpdates = %q, %v, want %q and an update", tt.name, got, hasUpdate, tt.wantVersion)
+		}
+		if n := lookups.Load(); n != tt.wantLookups {
+			t.Errorf("%s: looked up the latest release %d times, want %d", tt.name, n, tt.wantLookups)
+		}
+	}
+
+	// A release cached for the beta channel isn't reused for the stable one
+	if cached, ok := cachedLatestVersion(config.ChannelStable); ok {
+		t.Errorf("cachedLatestVersion(stable) = %q after a beta check, want none", cached)
+	}
+	if cached, ok := cachedLatestVersion(config.ChannelBeta); !ok || cached != "1.4.0" {
+		t.Errorf("cachedLatestVersion(beta) = %q, %v, want 1.4.0", cached, ok)
+	}
+}
+
+func TestInstallSpecificVersion(t *testing.T) {
+	var requests []string
+	useReleasesServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/velgardey/yok/releases/tag/v1.2.0":
+		case "/velgardey/yok/releases/tag/v1.3.0":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	if got, want := releaseAssetURL("1.2.0", "yok_1.2.0_linux_amd64.tar.gz"), releasesURL+"/download/v1.2.0/yok_1.2.0_linux_amd64.tar.gz"; got != want {
+		t.Errorf("releaseAssetURL = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		version string
+		wantErr string
+	}{
+		{"1.2.0", ""},
+		{"1.0.9", "release v1.0.9 doesn't exist"},
+		{"1.3.0", "failed to check release v1.3.0: unexpected status code: 503"},
+	}
+	for _, tt := range tests {
+		requests = nil
+		err := checkReleaseExists(tt.version)
+		if gotErr := errorString(err); gotErr != tt.wantErr {
+			t.Errorf("checkReleaseExists(%q) = %q, want %q", tt.version, gotErr, tt.wantErr)
+		}
+		if want := "HEAD /velgardey/yok/releases/tag/v" + tt.version; len(requests) != 1 || requests[0] != want {
+			t.Errorf("checkReleaseExists(%q) sent %q, want %q", tt.version, requests, want)
+		}
+	}
+
+	// A release that doesn't exist is rejected before anything is downloaded, even when older
+	requests = nil
+	platform := releasePlatform{os: "linux", arch: "amd64"}
+	err := runSelfUpdate(nil, true, false, false, false, "v1.0.9", platform)
+	if err == nil || err.Error() != "release v1.0.9 doesn't exist" {
+		t.Errorf("runSelfUpdate(--version v1.0.9) = %v, want the release to be missing", err)
+	}
+	if len(requests) != 1 {
+		t.Errorf("runSelfUpdate(--version v1.0.9) sent %q, want only the release check", requests)
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestFetchLatestPrerelease(t *testing.T) {
+	tests := []struct {
+		releases string
+		status   int
+		want     string
+		wantErr  string
+	}{
+		{`[{"tag_name":"v1.2.1"},{"tag_name":"v1.3.0-beta.2","prerelease":true},{"tag_name":"v1.3.0-beta.1","prerelease":true},{"tag_name":"v1.2.0"}]`, http.StatusOK, "1.3.0-beta.2", ""},
+		// A backport published after a newer release doesn't win
+		{`[{"tag_name":"v1.2.2"},{"tag_name":"v1.3.0"},{"tag_name":"v1.3.0-rc.1","prerelease":true}]`, http.StatusOK, "1.3.0", ""},
+		{`[{"tag_name":"nightly"},{"tag_name":"v1.0.0"}]`, http.StatusOK, "1.0.0", ""},
+		{`[]`, http.StatusOK, "", "no release found for velgardey/yok"},
+		{`{"message":"API rate limit exceeded"}`, http.StatusForbidden, "", "the GitHub API rate limit was exceeded; try again later"},
+		{``, http.StatusInternalServerError, "", "failed to fetch releases: unexpected status code: 500"},
+	}
+	for _, tt := range tests {
+		useReleasesServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/repos/velgardey/yok/releases" {
+				t.Errorf("requested %s, want the releases list", r.URL.Path)
+			}
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.releases))
+		}))
+		got, err := fetchLatestPrerelease()
+		if got != tt.want || errorString(err) != tt.wantErr {
+			t.Errorf("fetchLatestPrerelease with %s = %q, %v, want %q, %q", tt.releases, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestIsPrereleaseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"1.3.0-beta.1", true},
+		{"1.3.0-rc.1", true},
+		{"1.3.0", false},
+		{"1.3.0+build.1", false},
+		{"dev", false},
+	}
+	for _, tt := range tests {
+		if got := isPrereleaseVersion(tt.version); got != tt.want {
+			t.Errorf("isPrereleaseVersion(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestLatestVersionAfterAPIError(t *testing.T) {
+	apiResponse := func(status int) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "https://api.github.com/repos/velgardey/yok/releases", nil)
+		return &http.Response{StatusCode: status, Request: req}
+	}
+	rateLimited := []error{
+		&github.RateLimitError{Response: apiResponse(http.StatusForbidden)},
+		&github.AbuseRateLimitError{Response: apiResponse(http.StatusForbidden)},
+		&github.ErrorResponse{Response: apiResponse(http.StatusTooManyRequests)},
+		fmt.Errorf("detecting the latest release: %w", &github.ErrorResponse{Response: apiResponse(http.StatusForbidden)}),
+	}
+	other := []error{
+		&github.ErrorResponse{Response: apiResponse(http.StatusNotFound)},
+		errors.New("connection refused"),
+	}
+
+	latestPage := http.StatusFound
+	useReleasesServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/velgardey/yok/releases/latest" {
+			t.Errorf("requested %s, want the latest release page", r.URL.Path)
+		}
+		if latestPage == http.StatusFound {
+			w.Header().Set("Location", "https://github.com/velgardey/yok/releases/tag/v1.4.0")
+		}
+		w.WriteHeader(latestPage)
+	}))
+
+	for _, apiErr := range rateLimited {
+		if !isRateLimitError(apiErr) {
+			t.Errorf("isRateLimitError(%T) = false", apiErr)
+		}
+		if got, err := latestVersionAfterAPIError(apiErr); got != "1.4.0" || err != nil {
+			t.Errorf("latestVersionAfterAPIError(%T) = %q, %v, want the releases page's 1.4.0", apiErr, got, err)
+		}
+	}
+	for _, apiErr := range other {
+		if isRateLimitError(apiErr) {
+			t.Errorf("isRateLimitError(%v) = true", apiErr)
+		}
+		if _, err := latestVersionAfterAPIError(apiErr); !errors.Is(err, apiErr) {
+			t.Errorf("latestVersionAfterAPIError(%v) = %v, want the API error", apiErr, err)
+		}
+	}
+
+	// Both lookups failing is reported with a hint rather than the API error
+	latestPage = http.StatusServiceUnavailable
+	_, err := latestVersionAfterAPIError(rateLimited[0])
+	if err == nil || !strings.Contains(err.Error(), "rate limit was exceeded") || !strings.Contains(err.Error(), "GITHUB_TOKEN") {
+		t.Errorf("latestVersionAfterAPIError with the releases page down = %v, want a rate limit message", err)
+	}
+}
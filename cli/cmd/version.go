@@ -1,21 +1,69 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
 
 	"github.com/spf13/cobra"
+	"github.com/velgardey/yok/cli/internal/utils"
 )
 
+// versionInfo is the build information printed by version --json
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Display the version of Yok CLI",
 	Long:  `Display the current version of Yok CLI.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("Yok CLI v%s\n", version)
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		if !jsonOutput {
+			fmt.Printf("Yok CLI v%s\n", getCurrentVersion())
+			return
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		utils.HandleError(encoder.Encode(getVersionInfo()), "Error encoding JSON")
 	},
 }
 
+// getVersionInfo returns the CLI's build information. Builds made without GoReleaser, e.g. with
+// go build, report the commit and time recorded by the Go toolchain instead, if any.
+func getVersionInfo() versionInfo {
+	info := versionInfo{
+		Version:   getCurrentVersion(),
+		Commit:    commit,
+		BuildDate: date,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	return info
+}
+
 func init() {
+	versionCmd.Flags().Bool("json", false, "Output the version and build information as JSON")
 	RootCmd.AddCommand(versionCmd)
 }
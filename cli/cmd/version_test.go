@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+// captureStdout returns what fn prints to standard output
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestVersionIsReportedTheSameWay(t *testing.T) {
+	injected := version
+	t.Cleanup(func() { version = injected })
+
+	tests := []struct {
+		injected string
+		want     string
+	}{
+		{"v1.2.3", "1.2.3"},
+		{"1.2.3", "1.2.3"},
+		{"v2.0.0-rc.1", "2.0.0-rc.1"},
+		{"dev", "dev"},
+	}
+	for _, tt := range tests {
+		version = tt.injected
+
+		if got := getCurrentVersion(); got != tt.want {
+			t.Errorf("version %q: getCurrentVersion() = %q, want %q", tt.injected, got, tt.want)
+		}
+		if got := getVersionInfo().Version; got != tt.want {
+			t.Errorf("version %q: version --json reports %q, want %q", tt.injected, got, tt.want)
+		}
+		out := captureStdout(t, func() { versionCmd.Run(versionCmd, nil) })
+		if want := "Yok CLI v" + tt.want + "\n"; out != want {
+			t.Errorf("version %q: version prints %q, want %q", tt.injected, out, want)
+		}
+	}
+}
@@ -7,35 +7,55 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
-	"slices"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/AlecAivazis/survey/v2"
-	"github.com/velgardey/yok/cli/internal/git"
 	"github.com/velgardey/yok/cli/internal/types"
 	"github.com/velgardey/yok/cli/internal/utils"
 )
 
-// HTTP client with reasonable timeout
-var httpClient = utils.CreateHTTPClient()
+// Client talks to the Yok API server
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
 
-// Default log renderer
-var defaultLogRenderer *utils.LogRenderer
+	// projects holds the projects fetched by GetProject, so a command looking up the same
+	// project several times only asks the API server once
+	projectsMu sync.Mutex
+	projects   map[string]*types.Project
+}
+
+// ClientOption configures a Client created by NewClient
+type ClientOption func(*Client)
+
+// WithHTTPClient makes the client send its requests with the given HTTP client
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
 
-// SetLogRenderer sets the log renderer to use for streaming logs
-func SetLogRenderer(renderer *utils.LogRenderer) {
-	defaultLogRenderer = renderer
+// NewClient creates a client for the API server at baseURL
+func NewClient(baseURL string, opts ...ClientOption) *Client {
+	c := &Client{
+		httpClient: utils.CreateHTTPClient(), // HTTP client with reasonable timeout
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		projects:   make(map[string]*types.Project),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.httpClient = withRateLimitRetries(c.httpClient)
+	return c
 }
 
 // FindProjectByName checks if a project with the given name already exists
-func FindProjectByName(name string) (*types.Project, error) {
+func (c *Client) FindProjectByName(name string) (*types.Project, error) {
 	escapedName := url.QueryEscape(name)
-	url := fmt.Sprintf("%s/project/check?name=%s", utils.ApiURL, escapedName)
+	url := fmt.Sprintf("%s/project/check?name=%s", c.baseURL, escapedName)
 
-	resp, err := httpClient.Get(url)
+	resp, err := c.httpClient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check project: %w", err)
 	}
@@ -48,7 +68,7 @@ func FindProjectByName(name string) (*types.Project, error) {
 	case http.StatusNotFound:
 		return nil, nil // Project not found or endpoint doesn't exist
 	default:
-		return nil, fmt.Errorf("API returned status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to check project: %w", decodeAPIError(resp))
 	}
 
 	var checkResp types.ProjectCheckResponse
@@ -64,50 +84,49 @@ func FindProjectByName(name string) (*types.Project, error) {
 }
 
 // GetOrCreateProject creates or gets a project
-func GetOrCreateProject(name, repoURL, framework string) (*types.Project, error) {
+// rootDir is the repository subdirectory a new project is built from, empty for the root
+func (c *Client) GetOrCreateProject(name, repoURL, framework, rootDir string) (*types.Project, error) {
 	// Check if project already exists
-	if existingProject, err := FindProjectByName(name); err != nil {
+	if existingProject, err := c.FindProjectByName(name); err != nil {
 		return nil, fmt.Errorf("error checking for existing project: %w", err)
 	} else if existingProject != nil {
-		utils.InfoColor.Printf("Project '%s' already exists. Using existing project.\n", name)
 		return existingProject, nil
 	}
 
 	// Create new project
-	return createProject(name, repoURL, framework)
+	return c.createProject(name, repoURL, framework, rootDir)
 }
 
 // createProject creates a new project via API
-func createProject(name, repoURL, framework string) (*types.Project, error) {
-	s := utils.StartSpinner("Creating project on Yok...")
-	defer utils.StopSpinner(s)
-
+func (c *Client) createProject(name, repoURL, framework, rootDir string) (*types.Project, error) {
 	projectData := map[string]string{
 		"name":       name,
 		"gitRepoUrl": repoURL,
 		"framework":  framework,
 	}
+	if rootDir != "" {
+		projectData["rootDir"] = rootDir
+	}
 
 	jsonData, err := json.Marshal(projectData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal project data: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", utils.ApiURL+"/project", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", c.baseURL+"/project", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := httpClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to create project (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to create project: %w", decodeAPIError(resp))
 	}
 
 	var projectResp types.ProjectResponse
@@ -119,35 +138,28 @@ func createProject(name, repoURL, framework string) (*types.Project, error) {
 }
 
 // DeployProject deploys a project to Yok
-func DeployProject(projectID string) (*types.DeploymentResponse, error) {
-	s := utils.StartSpinner("Deploying project to Yok...")
-	defer utils.StopSpinner(s)
-
-	deployData := map[string]string{
-		"projectId": projectID,
-	}
-
-	jsonData, err := json.Marshal(deployData)
+func (c *Client) DeployProject(deployRequest types.DeployRequest) (*types.DeploymentResponse, error) {
+	jsonData, err := json.Marshal(deployRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal deploy data: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", utils.ApiURL+"/deploy", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", c.baseURL+"/deploy", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := httpClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusAccepted {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to deploy project (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to deploy project: %w", decodeAPIError(resp))
 	}
+	c.forgetProjects(deployRequest.ProjectID)
 
 	var deploymentResp types.DeploymentResponse
 	if err := utils.DecodeJSON(resp.Body, &deploymentResp); err != nil {
@@ -158,17 +170,17 @@ func DeployProject(projectID string) (*types.DeploymentResponse, error) {
 }
 
 // GetDeploymentStatus gets the status of a deployment
-func GetDeploymentStatus(deploymentID string) (*types.Deployment, error) {
-	url := fmt.Sprintf("%s/deployment/%s", utils.ApiURL, deploymentID)
+func (c *Client) GetDeploymentStatus(deploymentID string) (*types.Deployment, error) {
+	url := fmt.Sprintf("%s/deployment/%s", c.baseURL, deploymentID)
 
-	resp, err := httpClient.Get(url)
+	resp, err := c.httpClient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get deployment status: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to get deployment status: %w", decodeAPIError(resp))
 	}
 
 	var statusResp types.DeploymentStatusResponse
@@ -180,17 +192,17 @@ func GetDeploymentStatus(deploymentID string) (*types.Deployment, error) {
 }
 
 // ListDeployments lists deployments for a project
-func ListDeployments(projectID string) ([]types.Deployment, error) {
-	url := fmt.Sprintf("%s/project/%s/deployments", utils.ApiURL, projectID)
+func (c *Client) ListDeployments(projectID string) ([]types.Deployment, error) {
+	url := fmt.Sprintf("%s/project/%s/deployments", c.baseURL, projectID)
 
-	resp, err := httpClient.Get(url)
+	resp, err := c.httpClient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list deployments: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to list deployments: %w", decodeAPIError(resp))
 	}
 
 	var listResp types.DeploymentListResponse
@@ -202,7 +214,7 @@ func ListDeployments(projectID string) ([]types.Deployment, error) {
 }
 
 // CancelDeployment cancels a deployment
-func CancelDeployment(deploymentID string) error {
+func (c *Client) CancelDeployment(deploymentID string) error {
 	cancelData := map[string]string{
 		"deploymentId": deploymentID,
 	}
@@ -212,30 +224,140 @@ func CancelDeployment(deploymentID string) error {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", utils.ApiURL+"/deployment/"+deploymentID+"/cancel", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", c.baseURL+"/deployment/"+deploymentID+"/cancel", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := httpClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to cancel deployment: %s", string(body))
+		return fmt.Errorf("failed to cancel deployment: %w", decodeAPIError(resp))
 	}
 
 	return nil
 }
 
-// GetProject gets a project by ID
-func GetProject(projectID string) (*types.Project, error) {
+// PromoteDeployment points the project's slug at the given deployment
+func (c *Client) PromoteDeployment(projectID string, deploymentID string) error {
+	promoteData := map[string]string{
+		"deploymentId": deploymentID,
+	}
+
+	jsonData, err := json.Marshal(promoteData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal promote data: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+"/project/"+projectID+"/promote", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to promote deployment: %w", decodeAPIError(resp))
+	}
+	c.forgetProjects(projectID)
+
+	return nil
+}
+
+// UnpromoteDeployment removes the project's promoted deployment, so its slug serves the latest
+// deployment again
+func (c *Client) UnpromoteDeployment(projectID string) error {
+	req, err := http.NewRequest("DELETE", c.baseURL+"/project/"+projectID+"/promote", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to remove promotion: %w", decodeAPIError(resp))
+	}
+	c.forgetProjects(projectID)
+
+	return nil
+}
+
+// DeleteDeployment deletes a deployment
+func (c *Client) DeleteDeployment(deploymentID string) error {
+	req, err := http.NewRequest("DELETE", c.baseURL+"/deployment/"+deploymentID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to delete deployment: %w", decodeAPIError(resp))
+	}
+	// The deployment's project isn't known here, and may have been pointing at it
+	c.forgetProjects()
+
+	return nil
+}
+
+// GetProject gets a project by ID. Projects are fetched once per client and then reused, until
+// a deployment of theirs is triggered, promoted or deleted. If the API server can't serve the
+// project but it has deployments, a project with only its ID and Partial set is returned.
+func (c *Client) GetProject(projectID string) (*types.Project, error) {
+	c.projectsMu.Lock()
+	defer c.projectsMu.Unlock()
+
+	project, ok := c.projects[projectID]
+	if !ok {
+		var err error
+		if project, err = c.fetchProject(projectID); err != nil {
+			return nil, err
+		}
+		c.projects[projectID] = project
+	}
+
+	// Callers get their own copy, so they can't change the cached project
+	projectCopy := *project
+	return &projectCopy, nil
+}
+
+// forgetProjects drops the given projects fetched by GetProject, or all of them if none are
+// given, so they are fetched again after they changed
+func (c *Client) forgetProjects(projectIDs ...string) {
+	c.projectsMu.Lock()
+	defer c.projectsMu.Unlock()
+
+	if len(projectIDs) == 0 {
+		clear(c.projects)
+		return
+	}
+	for _, projectID := range projectIDs {
+		delete(c.projects, projectID)
+	}
+}
+
+// fetchProject gets a project by ID from the API server
+func (c *Client) fetchProject(projectID string) (*types.Project, error) {
 	// Try to get the project directly by ID first
-	resp, err := httpClient.Get(utils.ApiURL + "/project/" + projectID)
+	resp, err := c.httpClient.Get(c.baseURL + "/project/" + projectID)
 	if err != nil {
 		return nil, err
 	}
@@ -247,14 +369,14 @@ func GetProject(projectID string) (*types.Project, error) {
 		resp.Body.Close()
 
 		// Get the deployments for this project
-		deploymentsResp, err := httpClient.Get(utils.ApiURL + "/project/" + projectID + "/deployments")
+		deploymentsResp, err := c.httpClient.Get(c.baseURL + "/project/" + projectID + "/deployments")
 		if err != nil {
 			return nil, err
 		}
 		defer deploymentsResp.Body.Close()
 
 		if deploymentsResp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("failed to get project or deployments, API returned status code: %d", deploymentsResp.StatusCode)
+			return nil, fmt.Errorf("failed to get project or deployments: %w", decodeAPIError(deploymentsResp))
 		}
 
 		body, err := io.ReadAll(deploymentsResp.Body)
@@ -269,10 +391,10 @@ func GetProject(projectID string) (*types.Project, error) {
 
 		if len(listResp.Data.Deployments) > 0 {
 			// We have a deployment, but we still don't have the project slug
-			// Return a project with just the ID filled in
+			// Return a project with just the ID filled in, marked as partial
 			return &types.Project{
-				ID: projectID,
-				// Other fields will be empty
+				ID:      projectID,
+				Partial: true,
 			}, nil
 		}
 
@@ -294,293 +416,92 @@ func GetProject(projectID string) (*types.Project, error) {
 	return &projectResp.Data.Project, nil
 }
 
-// FollowDeploymentStatus follows the status of a deployment until completion or failure
-func FollowDeploymentStatus(deploymentID string, deploymentURL string, projectID string) {
-	// Create spinner with specific configuration to prevent clearing previous lines
-	s := utils.StartSpinner("Waiting for deployment to complete...")
-
-	for {
-		time.Sleep(3 * time.Second) // Check every 3 seconds
-
-		status, err := GetDeploymentStatus(deploymentID)
-		if err != nil {
-			utils.StopSpinner(s)
-			utils.WarnColor.Printf("\nFailed to get deployment status: %v\n", err)
-			break
-		}
-
-		switch status.Status {
-		case "COMPLETED":
-			utils.StopSpinner(s)
-			utils.SuccessColor.Printf("\n[OK] Deployment completed successfully!\n")
-			showDeploymentURLs(projectID, deploymentURL)
-			return
-		case "FAILED":
-			utils.StopSpinner(s)
-			utils.ErrorColor.Printf("\n[X] Deployment failed\n")
-			return
-		}
-		// Continue waiting for other status values
-	}
-}
-
-// showDeploymentURLs displays the URLs for a project (used by FollowDeploymentStatus)
-func showDeploymentURLs(projectID string, deploymentURL string) {
-	project, err := GetProject(projectID)
-	if err == nil && project.Slug != "" {
-		utils.InfoColor.Printf("[i] Your site is available at:\n")
-		fmt.Printf("- https://%s.yok.ninja\n", project.Slug)
-		if deploymentURL != "" {
-			fmt.Printf("- %s\n", deploymentURL)
-		}
-	} else if deploymentURL != "" {
-		utils.InfoColor.Printf("[i] Your site is now available at: %s\n", deploymentURL)
-	}
-}
-
-// SelectDeploymentFromList prompts the user to select a deployment from a list
-// filter can be used to filter deployments by status (e.g. only in-progress deployments)
-// if filter is nil, all deployments are shown
-func SelectDeploymentFromList(projectID string, filter func(types.Deployment) bool) (string, error) {
-	// Get recent deployments
-	deployments, err := ListDeployments(projectID)
+// GetServerVersion gets the version of the API server
+// It returns an empty version if the server doesn't report one
+func (c *Client) GetServerVersion() (string, error) {
+	resp, err := c.httpClient.Get(c.baseURL + "/version")
 	if err != nil {
-		return "", fmt.Errorf("error fetching deployments: %v", err)
-	}
-
-	// Filter deployments if a filter is provided
-	filteredDeployments := []types.Deployment{}
-	if filter != nil {
-		for _, d := range deployments {
-			if filter(d) {
-				filteredDeployments = append(filteredDeployments, d)
-			}
-		}
-	} else {
-		filteredDeployments = deployments
-	}
-
-	if len(filteredDeployments) == 0 {
-		return "", fmt.Errorf("no matching deployments found")
-	}
-
-	// Create options for selection
-	options := make([]string, len(filteredDeployments))
-	for i, d := range filteredDeployments {
-		timeAgo := time.Since(d.CreatedAt).Round(time.Second)
-		options[i] = fmt.Sprintf("%s (%s) - %s - %s ago",
-			d.ID[:8], d.Status, d.CreatedAt.Format("Jan 02 15:04"), timeAgo)
-	}
-
-	var selected int
-	prompt := &survey.Select{
-		Message: "Select a deployment:",
-		Options: options,
-	}
-	opts := utils.GetSurveyOptions()
-	survey.AskOne(prompt, &selected, opts)
-
-	return filteredDeployments[selected].ID, nil
-}
-
-// DetectFramework detects the framework used in the repository
-func DetectFramework() string {
-	files, _ := filepath.Glob("*")
-
-	// Check for package.json and analyze dependencies
-	for _, file := range files {
-		if file == "package.json" {
-			if framework := detectFrameworkFromPackageJSON(file); framework != "" {
-				return framework
-			}
-		}
+		return "", fmt.Errorf("failed to get server version: %w", err)
 	}
+	defer resp.Body.Close()
 
-	// Check for static sites
-	if hasIndexHTML(files) {
-		return "STATIC"
-	}
-
-	return "OTHER"
-}
-
-// hasIndexHTML checks if files slice contains index.html
-func hasIndexHTML(files []string) bool {
-	return slices.Contains(files, "index.html")
-}
-
-// autoDetectRepoURL automatically detects the repository URL from the current directory
-func autoDetectRepoURL() (string, error) {
-	// Ensure we have a git repository
-	if err := git.EnsureRepo(); err != nil {
-		return "", err
+	// Handle different status codes
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Continue processing
+	case http.StatusNotFound:
+		return "", nil // Older servers don't have the endpoint
+	default:
+		return "", fmt.Errorf("failed to get server version: %w", decodeAPIError(resp))
 	}
 
-	// Try to get remote URL using git command
-	remoteURL, err := git.GetRemoteURL()
-	if err != nil {
-		return "", fmt.Errorf("failed to detect git remote URL: %w", err)
+	var versionResp types.ServerVersionResponse
+	if err := utils.DecodeJSON(resp.Body, &versionResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return remoteURL, nil
+	return versionResp.Data.Version, nil
 }
 
-// detectFrameworkFromPackageJSON analyzes package.json to detect framework
-func detectFrameworkFromPackageJSON(filename string) string {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return ""
-	}
-
-	content := string(data)
-
-	// Check for frameworks in order of specificity
-	frameworks := map[string]string{
-		"next":    "NEXT",
-		"vite":    "VITE",
-		"svelte":  "SVELTE",
-		"react":   "REACT",
-		"vue":     "VUE",
-		"angular": "ANGULAR",
-	}
-
-	for keyword, framework := range frameworks {
-		if strings.Contains(content, keyword) {
-			return framework
-		}
-	}
-
-	return "OTHER"
+// FollowDeploymentStatus polls the status of a deployment until it reaches a terminal state
+// It returns the final status, or an empty string if stopChan received a value first
+func (c *Client) FollowDeploymentStatus(deploymentID string, stopChan chan bool) (string, error) {
+	return c.WatchDeploymentStatus(deploymentID, stopChan, nil)
 }
 
-// PromptForProjectCreationDetails asks the user for a project name, checks if it exists, and
-// gets Git repo info. Returns project details and a flag indicating if the user is using an existing project.
-func PromptForProjectCreationDetails() (string, string, string, *types.Project, bool, error) {
-	// Use centralized survey options to fix PowerShell echo issues
-	opts := utils.GetSurveyOptions()
-
-	// Get project name
-	var projectName string
-	prompt := &survey.Input{
-		Message: "Enter a name for your project:",
-	}
-
-	if err := survey.AskOne(prompt, &projectName, opts); err != nil {
-		return "", "", "", nil, false, fmt.Errorf("error getting project name: %v", err)
-	}
-
-	if projectName == "" {
-		return "", "", "", nil, false, fmt.Errorf("project name cannot be empty")
-	}
-
-	// Check if a project with this name already exists
-	existingProject, err := FindProjectByName(projectName)
-	if err != nil {
-		utils.WarnColor.Printf("Warning: Could not check if project exists: %v\n", err)
-		// Continue anyway, the creation step will fail if there's a duplicate
-	} else if existingProject != nil {
-		utils.InfoColor.Printf("Project with name '%s' already exists!\n", projectName)
-
-		// Ask if user wants to use the existing project
-		useExisting := false
-		confirmPrompt := &survey.Confirm{
-			Message: "Do you want to use this existing project?",
-			Default: true,
-		}
-		survey.AskOne(confirmPrompt, &useExisting, opts)
-
-		if useExisting {
-			// User wants to use the existing project
-			return projectName, existingProject.GitRepoURL, existingProject.Framework, existingProject, true, nil
-		}
-		// User chose not to use existing project, ask for a different name
-		return "", "", "", nil, false, fmt.Errorf("a project with this name already exists, please choose a different name")
-	}
-
-	// Ask user how they want to specify the Git repository
-	repoOptions := []string{
-		"Auto-detect Git repository from current directory",
-		"Manually enter Git repository URL",
-	}
-	repoOptionIndex := 0
-	repoPrompt := &survey.Select{
-		Message: "How would you like to specify the Git repository?",
-		Options: repoOptions,
-		Default: 0,
-	}
-
-	if err := survey.AskOne(repoPrompt, &repoOptionIndex, opts); err != nil {
-		return "", "", "", nil, false, fmt.Errorf("error getting repository option: %v", err)
-	}
-
-	var repoURL string
-
-	if repoOptionIndex == 1 {
-		// Manual entry - prompt for URL
-		var repoURLInput string
-		repoPromptInput := &survey.Input{
-			Message: "Enter your Git repository URL:",
-		}
-
-		if err := survey.AskOne(repoPromptInput, &repoURLInput, opts); err != nil {
-			return "", "", "", nil, false, fmt.Errorf("error getting repository URL: %v", err)
-		}
-
-		if strings.TrimSpace(repoURLInput) == "" {
-			return "", "", "", nil, false, fmt.Errorf("repository URL cannot be empty")
-		}
+// WatchDeploymentStatus polls the status of a deployment like FollowDeploymentStatus, calling
+// onChange, if set, with the deployment whenever its status or queue position changes, including
+// the final status
+func (c *Client) WatchDeploymentStatus(deploymentID string, stopChan chan bool, onChange func(*types.Deployment)) (string, error) {
+	ticker := time.NewTicker(3 * time.Second) // Check every 3 seconds
+	defer ticker.Stop()
 
-		repoURL = strings.TrimSpace(repoURLInput)
-	} else {
-		// Auto-detect from current directory
-		var autoErr error
-		repoURL, autoErr = autoDetectRepoURL()
-		if autoErr != nil {
-			// If auto-detect fails, prompt user to enter URL manually
-			utils.WarnColor.Printf("Auto-detection failed: %v\n", autoErr)
-			utils.InfoColor.Println("Please enter your Git repository URL manually:")
-
-			var repoURLInput string
-			repoPromptInput := &survey.Input{
-				Message: "Enter your Git repository URL:",
+	lastStatus := ""
+	lastQueuePosition := 0
+	for {
+		select {
+		case <-ticker.C:
+			status, err := c.GetDeploymentStatus(deploymentID)
+			if err != nil {
+				return "", fmt.Errorf("failed to get deployment status: %w", err)
 			}
 
-			if err := survey.AskOne(repoPromptInput, &repoURLInput, opts); err != nil {
-				return "", "", "", nil, false, fmt.Errorf("error getting repository URL: %v", err)
+			if onChange != nil && (status.Status != lastStatus || status.QueuePosition != lastQueuePosition) {
+				onChange(status)
 			}
+			lastStatus = status.Status
+			lastQueuePosition = status.QueuePosition
 
-			if strings.TrimSpace(repoURLInput) == "" {
-				return "", "", "", nil, false, fmt.Errorf("repository URL cannot be empty")
+			switch status.Status {
+			case "COMPLETED", "FAILED", "CANCELLED":
+				return status.Status, nil
 			}
+			// Continue waiting for other status values
 
-			repoURL = strings.TrimSpace(repoURLInput)
+		case <-stopChan:
+			// User interrupted or the wait timed out
+			return "", nil
 		}
 	}
-
-	// Detect framework
-	framework := DetectFramework()
-
-	return projectName, repoURL, framework, nil, false, nil
 }
 
 // GetDeploymentLogs fetches logs for a specific deployment
-func GetDeploymentLogs(deploymentID string, lastEventID string) (*types.LogsResponse, error) {
-	url := fmt.Sprintf("%s/logs/%s", utils.ApiURL, deploymentID)
+func (c *Client) GetDeploymentLogs(deploymentID string, lastEventID string) (*types.LogsResponse, error) {
+	url := fmt.Sprintf("%s/logs/%s", c.baseURL, deploymentID)
 
 	// Add lastEventID as query parameter if it exists
 	if lastEventID != "" {
 		url = fmt.Sprintf("%s?lastEventID=%s", url, lastEventID)
 	}
 
-	resp, err := httpClient.Get(url)
+	resp, err := c.httpClient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get deployment logs: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status code %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to get deployment logs: %w", decodeAPIError(resp))
 	}
 
 	var logsResp types.LogsResponse
@@ -590,101 +511,3 @@ func GetDeploymentLogs(deploymentID string, lastEventID string) (*types.LogsResp
 
 	return &logsResp, nil
 }
-
-// StreamDeploymentLogs continuously fetches and displays logs for a deployment
-// It polls until the deployment is complete or stopChan receives a value
-func StreamDeploymentLogs(deploymentID string, stopChan chan bool) bool {
-	var lastEventID string
-	var lastErrorMessage string
-
-	// Use the configured renderer or create a new default one
-	logRenderer := defaultLogRenderer
-	if logRenderer == nil {
-		logRenderer = utils.NewLogRenderer()
-	}
-
-	// Keep track of logs we've already seen to avoid duplicates
-	seenLogs := make(map[string]bool)
-
-	// First fetch to get initial logs
-	logs, err := GetDeploymentLogs(deploymentID, "")
-	if err != nil {
-		utils.ErrorColor.Printf("Error fetching logs: %v\n", err)
-		return false
-	}
-
-	// Display initial logs
-	for _, logEntry := range logs.Data.Logs {
-		seenLogs[logEntry.EventID] = true
-		logRenderer.RenderLogEntry(logEntry)
-		lastEventID = logEntry.EventID
-
-		// Keep track of the last error message
-		if strings.Contains(logEntry.Log, "Error:") || strings.Contains(logEntry.Log, "Failed:") {
-			lastErrorMessage = logEntry.Log
-		}
-
-		// Check for completion marker
-		if strings.Contains(logEntry.Log, "Build output uploaded to S3 successfully") {
-			utils.InfoColor.Println("\nDeployment completed successfully!")
-			return true
-		}
-	}
-
-	// Start polling for new logs
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			// Fetch new logs since the last event ID
-			newLogs, err := GetDeploymentLogs(deploymentID, lastEventID)
-			if err != nil {
-				utils.ErrorColor.Printf("Error fetching logs: %v\n", err)
-				continue
-			}
-
-			// Process new logs
-			for _, logEntry := range newLogs.Data.Logs {
-				if seenLogs[logEntry.EventID] {
-					continue
-				}
-
-				seenLogs[logEntry.EventID] = true
-				logRenderer.RenderLogEntry(logEntry)
-				lastEventID = logEntry.EventID
-
-				if strings.Contains(logEntry.Log, "Error:") || strings.Contains(logEntry.Log, "Failed:") {
-					lastErrorMessage = logEntry.Log
-				}
-
-				// Check for completion marker
-				if strings.Contains(logEntry.Log, "Build output uploaded to S3 successfully") {
-					utils.InfoColor.Println("\nDeployment completed successfully!")
-					return true
-				}
-			}
-
-			// Check deployment status to catch completion/failure without log entry
-			deployment, err := GetDeploymentStatus(deploymentID)
-			if err == nil {
-				switch deployment.Status {
-				case "COMPLETED":
-					// Let's check once more for the final log in case it just came in
-					ticker.Reset(3 * time.Second)
-				case "FAILED":
-					utils.ErrorColor.Println("\nDeployment failed.")
-					if lastErrorMessage != "" {
-						utils.ErrorColor.Printf("Last error: %s\n", lastErrorMessage)
-					}
-					return false
-				}
-			}
-
-		case <-stopChan:
-			// User interrupted
-			return false
-		}
-	}
-}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/velgardey/yok/cli/internal/types"
+)
+
+// newTestClient returns a client for an API server answering with handler
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL+"/", WithHTTPClient(server.Client()))
+}
+
+func TestClientDeployProject(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /deploy", func(w http.ResponseWriter, r *http.Request) {
+		var req types.DeployRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding the request: %v", err)
+		}
+		if req.ProjectID != "project-1" || req.Note != "pricing page rework" {
+			t.Errorf("request = %+v", req)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"status":"success","data":{"deploymentId":"deployment-1","deploymentUrl":"https://deployment-1.yok.ninja/"}}`))
+	})
+	client := newTestClient(t, mux)
+
+	resp, err := client.DeployProject(types.DeployRequest{ProjectID: "project-1", Note: "pricing page rework"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.DeploymentId != "deployment-1" || resp.Data.DeploymentUrl != "https://deployment-1.yok.ninja/" {
+		t.Errorf("response = %+v", resp.Data)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /deployment/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","message":"Deployment not found"}`))
+	})
+	mux.HandleFunc("POST /deployment/done/cancel", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"Cannot cancel deployment with status COMPLETED"}`))
+	})
+	mux.HandleFunc("DELETE /deployment/private", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("forbidden\n"))
+	})
+	client := newTestClient(t, mux)
+
+	_, err := client.GetDeploymentStatus("missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "Deployment not found" || !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetDeploymentStatus error = %v, want a not found APIError", err)
+	}
+
+	err = client.CancelDeployment("done")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Message != "Cannot cancel deployment with status COMPLETED" {
+		t.Errorf("CancelDeployment error = %v, want a bad request APIError", err)
+	}
+
+	err = client.DeleteDeployment("private")
+	if !errors.As(err, &apiErr) || apiErr.Message != "forbidden" || !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("DeleteDeployment error = %v, want an unauthorized APIError with the raw body", err)
+	}
+}
+
+func TestClientGetProject(t *testing.T) {
+	var fetches atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /project/project-1", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(`{"status":"success","data":{"project":{"id":"project-1","name":"site","slug":"brave-fox"}}}`))
+	})
+	mux.HandleFunc("POST /project/project-1/promote", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success"}`))
+	})
+	mux.HandleFunc("GET /project/project-2", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /project/project-2/deployments", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","data":{"deployments":[{"id":"deployment-1","status":"COMPLETED"}]}}`))
+	})
+	client := newTestClient(t, mux)
+
+	for range 2 {
+		project, err := client.GetProject("project-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.Slug != "brave-fox" || project.Partial {
+			t.Errorf("project = %+v", project)
+		}
+		// Changing the returned project doesn't change the cached one
+		project.Slug = "changed"
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetched the project %d times, want once", got)
+	}
+
+	// Promoting changes the project, so it is fetched again
+	if err := client.PromoteDeployment("project-1", "deployment-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetProject("project-1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("fetched the project %d times after promoting, want twice", got)
+	}
+
+	project, err := client.GetProject("project-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.ID != "project-2" || !project.Partial {
+		t.Errorf("project = %+v, want a partial project-2", project)
+	}
+}
+
+func TestClientPromoteDeployment(t *testing.T) {
+	promoted := ""
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /project/project-1/promote", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			DeploymentID string `json:"deploymentId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding the request: %v", err)
+		}
+		if body.DeploymentID == "running" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","message":"Cannot promote deployment with status IN_PROGRESS"}`))
+			return
+		}
+		promoted = body.DeploymentID
+		w.Write([]byte(`{"status":"success"}`))
+	})
+	mux.HandleFunc("DELETE /project/project-1/promote", func(w http.ResponseWriter, r *http.Request) {
+		promoted = ""
+		w.Write([]byte(`{"status":"success"}`))
+	})
+	mux.HandleFunc("GET /project/project-1", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data":   map[string]any{"project": map[string]any{"id": "project-1", "slug": "brave-fox", "promotedDeploymentId": promoted}},
+		})
+	})
+	client := newTestClient(t, mux)
+
+	err := client.PromoteDeployment("project-1", "running")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "Cannot promote deployment with status IN_PROGRESS" {
+		t.Errorf("promoting a running deployment: err = %v, want the server's message", err)
+	}
+
+	if err := client.PromoteDeployment("project-1", "deployment-1"); err != nil {
+		t.Fatal(err)
+	}
+	project, err := client.GetProject("project-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.PromotedDeploymentID != "deployment-1" {
+		t.Errorf("PromotedDeploymentID = %q, want deployment-1", project.PromotedDeploymentID)
+	}
+
+	// Removing the promotion is seen by the next fetch of the project
+	if err := client.UnpromoteDeployment("project-1"); err != nil {
+		t.Fatal(err)
+	}
+	project, err = client.GetProject("project-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.PromotedDeploymentID != "" {
+		t.Errorf("PromotedDeploymentID = %q after removing the promotion, want none", project.PromotedDeploymentID)
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"github.com/velgardey/yok/cli/internal/types"
+	"github.com/velgardey/yok/cli/internal/utils"
+)
+
+// defaultClient is used by the package-level functions
+var defaultClient = NewClient(utils.ApiURL)
+
+// DefaultClient returns the client used by the package-level functions
+func DefaultClient() *Client {
+	return defaultClient
+}
+
+// SetDefaultClient replaces the client used by the package-level functions
+func SetDefaultClient(c *Client) {
+	defaultClient = c
+}
+
+// FindProjectByName checks if a project with the given name already exists using the default client
+func FindProjectByName(name string) (*types.Project, error) {
+	return defaultClient.FindProjectByName(name)
+}
+
+// GetOrCreateProject creates or gets a project using the default client
+func GetOrCreateProject(name, repoURL, framework, rootDir string) (*types.Project, error) {
+	return defaultClient.GetOrCreateProject(name, repoURL, framework, rootDir)
+}
+
+// DeployProject deploys a project to Yok using the default client
+func DeployProject(deployRequest types.DeployRequest) (*types.DeploymentResponse, error) {
+	return defaultClient.DeployProject(deployRequest)
+}
+
+// GetDeploymentStatus gets the status of a deployment using the default client
+func GetDeploymentStatus(deploymentID string) (*types.Deployment, error) {
+	return defaultClient.GetDeploymentStatus(deploymentID)
+}
+
+// ListDeployments lists deployments for a project using the default client
+func ListDeployments(projectID string) ([]types.Deployment, error) {
+	return defaultClient.ListDeployments(projectID)
+}
+
+// CancelDeployment cancels a deployment using the default client
+func CancelDeployment(deploymentID string) error {
+	return defaultClient.CancelDeployment(deploymentID)
+}
+
+// PromoteDeployment points the project's slug at the given deployment using the default client
+func PromoteDeployment(projectID string, deploymentID string) error {
+	return defaultClient.PromoteDeployment(projectID, deploymentID)
+}
+
+// UnpromoteDeployment makes the project's slug serve its latest deployment again using the default client
+func UnpromoteDeployment(projectID string) error {
+	return defaultClient.UnpromoteDeployment(projectID)
+}
+
+// DeleteDeployment deletes a deployment and its build output using the default client
+func DeleteDeployment(deploymentID string) error {
+	return defaultClient.DeleteDeployment(deploymentID)
+}
+
+// GetProject gets a project by ID using the default client
+func GetProject(projectID string) (*types.Project, error) {
+	return defaultClient.GetProject(projectID)
+}
+
+// FollowDeploymentStatus polls the status of a deployment until it reaches a terminal state
+// using the default client
+func FollowDeploymentStatus(deploymentID string, stopChan chan bool) (string, error) {
+	return defaultClient.FollowDeploymentStatus(deploymentID, stopChan)
+}
+
+// WatchDeploymentStatus polls the status of a deployment, reporting every change to onChange,
+// using the default client
+func WatchDeploymentStatus(deploymentID string, stopChan chan bool, onChange func(*types.Deployment)) (string, error) {
+	return defaultClient.WatchDeploymentStatus(deploymentID, stopChan, onChange)
+}
+
+// GetDeploymentLogs fetches logs for a specific deployment using the default client
+func GetDeploymentLogs(deploymentID string, lastEventID string) (*types.LogsResponse, error) {
+	return defaultClient.GetDeploymentLogs(deploymentID, lastEventID)
+}
+
+// GetServerVersion gets the version of the API server using the default client
+func GetServerVersion() (string, error) {
+	return defaultClient.GetServerVersion()
+}
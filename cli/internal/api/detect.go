@@ -0,0 +1,126 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DetectFramework detects the framework used in the repository
+func DetectFramework() string {
+	return DetectFrameworkIn(".")
+}
+
+// DetectFrameworkIn detects the framework of the app in dir
+func DetectFrameworkIn(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	for i, file := range files {
+		files[i] = filepath.Base(file)
+	}
+
+	// Check for package.json and analyze dependencies
+	for _, file := range files {
+		if file == "package.json" {
+			if framework := detectFrameworkFromPackageJSON(filepath.Join(dir, file)); framework != "" {
+				return framework
+			}
+		}
+	}
+
+	// Check for static sites
+	if hasIndexHTML(files) {
+		return "STATIC"
+	}
+
+	return "OTHER"
+}
+
+// DetectOutputDir detects the directory containing the built site, preferring the common
+// build output directories of the supported frameworks and falling back to the current directory
+func DetectOutputDir() string {
+	candidates := []string{"dist", "build", "out", "public", "_site"}
+
+	for _, dir := range candidates {
+		if _, err := os.Stat(filepath.Join(dir, "index.html")); err == nil {
+			return dir
+		}
+	}
+
+	return "."
+}
+
+// appDirScanDepth is how many directory levels below the current directory FindAppDirs looks
+// into, so that the scan stays fast in large repositories
+const appDirScanDepth = 2
+
+// IsAppDir reports whether dir contains a package.json or an index.html
+func IsAppDir(dir string) bool {
+	for _, name := range []string{"package.json", "index.html"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// FindAppDirs returns the subdirectories of dir, at most appDirScanDepth levels deep, that
+// contain an app, as slash-separated paths relative to dir. Hidden directories and
+// node_modules are skipped, and so are the subdirectories of an app.
+func FindAppDirs(dir string) []string {
+	var found []string
+	var scan func(rel string, depth int)
+	scan = func(rel string, depth int) {
+		entries, err := os.ReadDir(filepath.Join(dir, rel))
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() || strings.HasPrefix(name, ".") || name == "node_modules" {
+				continue
+			}
+			sub := filepath.Join(rel, name)
+			if IsAppDir(filepath.Join(dir, sub)) {
+				found = append(found, filepath.ToSlash(sub))
+			} else if depth < appDirScanDepth {
+				scan(sub, depth+1)
+			}
+		}
+	}
+	scan("", 1)
+	return found
+}
+
+// hasIndexHTML checks if files slice contains index.html
+func hasIndexHTML(files []string) bool {
+	return slices.Contains(files, "index.html")
+}
+
+// detectFrameworkFromPackageJSON analyzes package.json to detect framework
+func detectFrameworkFromPackageJSON(filename string) string {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return ""
+	}
+
+	content := string(data)
+
+	// Check for frameworks in order of specificity
+	frameworks := map[string]string{
+		"next":    "NEXT",
+		"vite":    "VITE",
+		"svelte":  "SVELTE",
+		"react":   "REACT",
+		"vue":     "VUE",
+		"angular": "ANGULAR",
+	}
+
+	for keyword, framework := range frameworks {
+		if strings.Contains(content, keyword) {
+			return framework
+		}
+	}
+
+	return "OTHER"
+}
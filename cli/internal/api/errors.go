@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/velgardey/yok/cli/internal/types"
+)
+
+// Errors matched by API errors so commands can tell common failures apart
+var (
+	ErrUnauthorized = errors.New("not authorized")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// APIError is returned when the API server responds with an unexpected status code
+type APIError struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is how long the API server asked to wait before retrying, if it said
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == http.StatusTooManyRequests {
+		if e.RetryAfter > 0 {
+			return fmt.Sprintf("rate limited by the API server, retry after %s (status %d)", e.RetryAfter.Round(time.Second), e.StatusCode)
+		}
+		return fmt.Sprintf("rate limited by the API server, try again later (status %d)", e.StatusCode)
+	}
+	if e.Message == "" {
+		return fmt.Sprintf("API returned status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s (status %d)", e.Message, e.StatusCode)
+}
+
+// Is makes errors.Is match ErrUnauthorized, ErrNotFound or ErrRateLimited when the status code
+// calls for it
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// decodeAPIError builds the error for an unexpected API response, taking the message from the
+// JSON error body and falling back to the raw body when it isn't one
+func decodeAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return apiErr
+	}
+
+	var errorResp types.ErrorResponse
+	if err := json.Unmarshal(body, &errorResp); err == nil {
+		switch {
+		case errorResp.Message != "":
+			apiErr.Message = errorResp.Message
+			return apiErr
+		case errorResp.Error != "":
+			apiErr.Message = errorResp.Error
+			return apiErr
+		}
+	}
+
+	apiErr.Message = strings.TrimSpace(string(body))
+	return apiErr
+}
+
+// parseRetryAfter returns the wait a Retry-After header asks for, given in seconds or as an HTTP
+// date, or 0 if there is none
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
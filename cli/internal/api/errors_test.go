@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	sentinels := []error{ErrUnauthorized, ErrNotFound, ErrRateLimited}
+	tests := []struct {
+		statusCode int
+		want       error
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrUnauthorized},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusBadRequest, nil},
+		{http.StatusInternalServerError, nil},
+	}
+	for _, tt := range tests {
+		// Wrapped, as commands usually get them
+		err := fmt.Errorf("fetching the project: %w", &APIError{StatusCode: tt.statusCode})
+		for _, sentinel := range sentinels {
+			if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+				t.Errorf("status %d: errors.Is(err, %v) = %v", tt.statusCode, sentinel, got)
+			}
+		}
+	}
+}
+
+func TestDecodeAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		retryAfter string
+		body       string
+		want       string
+		wantWait   time.Duration
+	}{
+		{"message", http.StatusNotFound, "", `{"status":"error","message":"Project not found"}`, "Project not found (status 404)", 0},
+		{"error", http.StatusBadRequest, "", `{"error":"Invalid request"}`, "Invalid request (status 400)", 0},
+		{"raw body", http.StatusBadGateway, "", "upstream unavailable\n", "upstream unavailable (status 502)", 0},
+		{"empty body", http.StatusInternalServerError, "", "", "API returned status code 500", 0},
+		{"rate limited", http.StatusTooManyRequests, "30", `{"error":"Too many requests"}`, "rate limited by the API server, retry after 30s (status 429)", 30 * time.Second},
+		{"rate limited without a wait", http.StatusTooManyRequests, "soon", "", "rate limited by the API server, try again later (status 429)", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.statusCode,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+
+			apiErr := decodeAPIError(resp)
+			if got := apiErr.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+			if apiErr.RetryAfter != tt.wantWait {
+				t.Errorf("RetryAfter = %v, want %v", apiErr.RetryAfter, tt.wantWait)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// Limits on waiting out the API server's rate limit before giving up with an ErrRateLimited error.
+// Waits are kept well below the HTTP client's timeout, which covers every attempt.
+const (
+	maxRateLimitRetries = 3
+	maxRateLimitWait    = 10 * time.Second
+	// rateLimitRetryMargin is left before the request's deadline for sending the retry
+	rateLimitRetryMargin = 5 * time.Second
+	// defaultRateLimitWait is waited when a 429 response has no Retry-After header
+	defaultRateLimitWait = time.Second
+)
+
+// rateLimitTransport retries GET and HEAD requests the API server answered with a 429 once the
+// wait it asked for is over, unless it is longer than maxRateLimitWait or would leave too little
+// of the request's deadline for the retry. Other requests may not be safe to send twice, so their
+// 429 responses are passed on as they are.
+type rateLimitTransport struct {
+	next http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.next.RoundTrip(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt > maxRateLimitRetries {
+			return resp, err
+		}
+
+		wait := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if wait == 0 {
+			wait = defaultRateLimitWait
+		}
+		if wait > maxRateLimitWait {
+			return resp, nil
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < wait+rateLimitRetryMargin {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// withRateLimitRetries returns a copy of httpClient that waits out rate limits with
+// rateLimitTransport
+func withRateLimitRetries(httpClient *http.Client) *http.Client {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	wrapped := *httpClient
+	wrapped.Transport = &rateLimitTransport{next: next}
+	return &wrapped
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rateLimitedServer answers the first limited requests with a 429 and retryAfter as the
+// Retry-After header, and the ones after with a 200
+func rateLimitedServer(t *testing.T, limited int32, retryAfter string) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= limited {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		timeout    time.Duration
+		limited    int32
+		retryAfter string
+		wantStatus int
+		wantSent   int32
+	}{
+		{"retries after the wait", http.MethodGet, 30 * time.Second, 1, "1", http.StatusOK, 2},
+		{"gives up after the retries", http.MethodGet, 30 * time.Second, 10, "0", http.StatusTooManyRequests, maxRateLimitRetries + 1},
+		{"doesn't wait longer than the limit", http.MethodGet, 30 * time.Second, 1, "60", http.StatusTooManyRequests, 1},
+		{"doesn't wait past the client timeout", http.MethodGet, 3 * time.Second, 1, "1", http.StatusTooManyRequests, 1},
+		{"doesn't retry a POST", http.MethodPost, 30 * time.Second, 1, "1", http.StatusTooManyRequests, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := rateLimitedServer(t, tt.limited, tt.retryAfter)
+			client := withRateLimitRetries(&http.Client{Timeout: tt.timeout})
+
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := requests.Load(); got != tt.wantSent {
+				t.Errorf("sent %d requests, want %d", got, tt.wantSent)
+			}
+		})
+	}
+}
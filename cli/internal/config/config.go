@@ -2,15 +2,45 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/velgardey/yok/cli/internal/types"
 	"github.com/velgardey/yok/cli/internal/utils"
+	"gopkg.in/yaml.v3"
 )
 
+// configPath is the file the configuration is loaded from and saved to
+var configPath = utils.ConfigFile
+
+// SetPath changes the configuration file used by this package; an empty path restores the default
+func SetPath(path string) {
+	if path == "" {
+		path = utils.ConfigFile
+	}
+	configPath = path
+}
+
+// Path returns the configuration file used by this package
+func Path() string {
+	return configPath
+}
+
+// isYAML reports whether the config file at path is YAML, based on its extension
+// Any other extension is treated as JSON
+func isYAML(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
 // SaveConfig saves the configuration to a local file
 func SaveConfig(config types.Config) error {
 	// Validate configuration before saving
@@ -18,12 +48,18 @@ func SaveConfig(config types.Config) error {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	jsonData, err := json.MarshalIndent(config, "", "  ")
+	var data []byte
+	var err error
+	if isYAML(configPath) {
+		data, err = yaml.Marshal(config)
+	} else {
+		data, err = json.MarshalIndent(config, "", "  ")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(utils.ConfigFile, jsonData, 0644); err != nil {
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -34,7 +70,7 @@ func SaveConfig(config types.Config) error {
 func LoadConfig() (types.Config, error) {
 	var config types.Config
 
-	data, err := os.ReadFile(utils.ConfigFile)
+	data, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return config, nil // Return empty config if file doesn't exist
@@ -42,34 +78,42 @@ func LoadConfig() (types.Config, error) {
 		return config, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	if err := json.Unmarshal(data, &config); err != nil {
+	if isYAML(configPath) {
+		err = yaml.Unmarshal(data, &config)
+	} else {
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
 		return config, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
 	return config, nil
 }
 
-// GetProjectIDOrExit loads the config and exits if no project ID is found
-func GetProjectIDOrExit() types.Config {
+// ErrNoProject is returned by LoadProjectConfig when no project has been configured
+var ErrNoProject = errors.New("no project configured, run 'yok create' or 'yok deploy' first")
+
+// LoadProjectConfig loads the config and returns ErrNoProject if no project ID is stored
+func LoadProjectConfig() (types.Config, error) {
 	config, err := LoadConfig()
-	utils.HandleError(err, "Error loading configuration")
+	if err != nil {
+		return config, err
+	}
 
 	if config.ProjectID == "" {
-		utils.ErrorColor.Println("No project configured. Run 'yok create' or 'yok deploy' first.")
-		os.Exit(1)
+		return config, ErrNoProject
 	}
 
-	return config
+	return config, nil
 }
 
 // RemoveConfig deletes the configuration file
 func RemoveConfig() error {
-	cwd, err := os.Getwd()
+	configFilePath, err := GetConfigPath()
 	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+		return err
 	}
 
-	configFilePath := filepath.Join(cwd, utils.ConfigFile)
 	if err := os.RemoveAll(configFilePath); err != nil {
 		return fmt.Errorf("failed to remove config file: %w", err)
 	}
@@ -87,17 +131,23 @@ func ValidateConfig(config types.Config) error {
 		return fmt.Errorf("repository name cannot be empty")
 	}
 
+	if config.Hooks != nil && config.Hooks.Timeout != "" {
+		if _, err := time.ParseDuration(config.Hooks.Timeout); err != nil {
+			return fmt.Errorf("invalid hook timeout %q: %w", config.Hooks.Timeout, err)
+		}
+	}
+
 	return nil
 }
 
 // GetConfigPath returns the full path to the configuration file
 func GetConfigPath() (string, error) {
-	cwd, err := os.Getwd()
+	configFilePath, err := filepath.Abs(configPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to get current directory: %w", err)
+		return "", fmt.Errorf("failed to resolve config path: %w", err)
 	}
 
-	return filepath.Join(cwd, utils.ConfigFile), nil
+	return configFilePath, nil
 }
 
 // ConfigExists checks if a configuration file exists
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/velgardey/yok/cli/internal/types"
+)
+
+// useConfigFile points the package at a file in a temporary directory
+func useConfigFile(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	SetPath(path)
+	t.Cleanup(func() { SetPath("") })
+	return path
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	config := types.Config{
+		ProjectID:     "project-1",
+		RepoName:      "site",
+		Hooks:         &types.HooksConfig{PreDeploy: []string{"npm run build"}, Timeout: "5m"},
+		SkipUnchanged: true,
+	}
+	for _, name := range []string{"yok.json", "yok.yaml", "yok.yml"} {
+		useConfigFile(t, name)
+		if err := SaveConfig(config); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := LoadProjectConfig()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, config) {
+			t.Errorf("%s: loaded %+v, want %+v", name, got, config)
+		}
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	path := useConfigFile(t, "yok.json")
+
+	// A missing file is an empty config rather than an error
+	if config, err := LoadConfig(); err != nil || config.ProjectID != "" {
+		t.Errorf("LoadConfig without a file = %+v, %v", config, err)
+	}
+	if _, err := LoadProjectConfig(); !errors.Is(err, ErrNoProject) {
+		t.Errorf("LoadProjectConfig without a file = %v, want ErrNoProject", err)
+	}
+
+	invalid := []types.Config{
+		{RepoName: "site"},
+		{ProjectID: "project-1", RepoName: " "},
+		{ProjectID: "project-1", RepoName: "site", Hooks: &types.HooksConfig{Timeout: "soon"}},
+	}
+	for _, config := range invalid {
+		if err := SaveConfig(config); err == nil {
+			t.Errorf("SaveConfig(%+v) succeeded", config)
+		}
+	}
+	if ConfigExists() {
+		t.Error("an invalid config was written")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"projectId":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig succeeded with malformed JSON")
+	}
+	if _, err := LoadProjectConfig(); err == nil || errors.Is(err, ErrNoProject) {
+		t.Errorf("LoadProjectConfig with malformed JSON = %v, want a parse error", err)
+	}
+
+	if err := RemoveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if ConfigExists() {
+		t.Error("ConfigExists() = true after RemoveConfig")
+	}
+}
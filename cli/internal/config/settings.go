@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SettingsFile is the per-directory file setting defaults for command flags
+const SettingsFile = ".yokrc"
+
+// LoadSettings reads the SettingsFile in dir and returns the default values it sets, keyed by
+// flag name, e.g. "no-sync-check" or "timeout". The file is either a JSON object or key=value
+// lines, where blank lines and lines starting with # are skipped. Keys are case-insensitive and
+// may use underscores instead of dashes. A missing file sets no defaults.
+func LoadSettings(dir string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, SettingsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", SettingsFile, err)
+	}
+
+	var settings map[string]string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		settings, err = parseJSONSettings(trimmed)
+	} else {
+		settings, err = parseKeyValueSettings(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", SettingsFile, err)
+	}
+	return settings, nil
+}
+
+// parseJSONSettings parses a JSON object of settings, whose values may be strings, numbers or
+// booleans
+func parseJSONSettings(data []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]string, len(raw))
+	for key, value := range raw {
+		var str string
+		if err := json.Unmarshal(value, &str); err == nil {
+			settings[settingKey(key)] = str
+			continue
+		}
+		var scalar any
+		if err := json.Unmarshal(value, &scalar); err != nil {
+			return nil, err
+		}
+		switch scalar.(type) {
+		case bool, float64:
+			settings[settingKey(key)] = string(value)
+		default:
+			return nil, fmt.Errorf("%s must be a string, number or boolean", key)
+		}
+	}
+	return settings, nil
+}
+
+// parseKeyValueSettings parses key=value lines of settings
+func parseKeyValueSettings(data []byte) (map[string]string, error) {
+	settings := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("line %d: expected key=value", lineNumber)
+		}
+		settings[settingKey(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return settings, scanner.Err()
+}
+
+// settingKey normalizes a setting name to the flag name it sets
+func settingKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "_", "-")
+}
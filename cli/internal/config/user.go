@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/velgardey/yok/cli/internal/types"
+)
+
+// userConfigFile is the name of the user configuration file within the user config directory
+const userConfigFile = "config.json"
+
+// Release channels that updates can come from
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta" // Includes prereleases
+)
+
+// userConfigMu serializes changes to the user configuration within the process, such as the
+// update notifier's running alongside a command
+var userConfigMu sync.Mutex
+
+// UserConfigPath returns the path of the user configuration file, which is shared by every project
+func UserConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config directory: %w", err)
+	}
+
+	return filepath.Join(configDir, "yok", userConfigFile), nil
+}
+
+// LoadUserConfig loads the user configuration, returning an empty one if it doesn't exist yet
+func LoadUserConfig() (types.UserConfig, error) {
+	var config types.UserConfig
+
+	path, err := UserConfigPath()
+	if err != nil {
+		return config, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, fmt.Errorf("failed to read user config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse user config file: %w", err)
+	}
+
+	return config, nil
+}
+
+// SaveUserConfig saves the user configuration, creating its directory if needed. Use
+// UpdateUserConfig to change settings, so changes made at the same time aren't lost.
+func SaveUserConfig(config types.UserConfig) error {
+	userConfigMu.Lock()
+	defer userConfigMu.Unlock()
+
+	return saveUserConfig(config)
+}
+
+// UpdateUserConfig loads the user configuration, changes it with update and saves it, unless
+// update fails. Other updates within the process wait until it is saved.
+func UpdateUserConfig(update func(config *types.UserConfig) error) error {
+	userConfigMu.Lock()
+	defer userConfigMu.Unlock()
+
+	config, err := LoadUserConfig()
+	if err != nil {
+		return err
+	}
+	if err := update(&config); err != nil {
+		return err
+	}
+	return saveUserConfig(config)
+}
+
+// saveUserConfig validates and writes the user configuration; the caller must hold userConfigMu.
+// The file is written next to the old one and renamed over it, so readers never see it half
+// written, even in another process.
+func saveUserConfig(config types.UserConfig) error {
+	if config.UpdateCheckInterval != "" {
+		if _, err := time.ParseDuration(config.UpdateCheckInterval); err != nil {
+			return fmt.Errorf("invalid update check interval %q: %w", config.UpdateCheckInterval, err)
+		}
+	}
+
+	if config.Channel != "" && config.Channel != ChannelStable && config.Channel != ChannelBeta {
+		return fmt.Errorf("invalid channel %q: must be %s or %s", config.Channel, ChannelStable, ChannelBeta)
+	}
+
+	path, err := UserConfigPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal user config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create user config directory: %w", err)
+	}
+
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write user config file: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of path and renames it to
+// path, so path holds either the old or the new contents
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/velgardey/yok/cli/internal/types"
+)
+
+func TestUpdateUserConfigConcurrently(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := SaveUserConfig(types.UserConfig{Channel: ChannelBeta}); err != nil {
+		t.Fatal(err)
+	}
+
+	const updates = 20
+	var wg sync.WaitGroup
+	for i := range updates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := UpdateUserConfig(func(config *types.UserConfig) error {
+				if config.DeployedCommits == nil {
+					config.DeployedCommits = make(map[string]string)
+				}
+				config.DeployedCommits[fmt.Sprintf("project-%d", i)] = "abc123"
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	config, err := LoadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.DeployedCommits) != updates || config.Channel != ChannelBeta {
+		t.Errorf("config = %+v, want every update and the channel kept", config)
+	}
+
+	// Only the config file is left behind
+	path, _ := UserConfigPath()
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != userConfigFile {
+		t.Errorf("config directory holds %v, want only %s", entries, userConfigFile)
+	}
+}
+
+func TestUpdateUserConfigKeepsFileOnError(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := SaveUserConfig(types.UserConfig{Channel: ChannelStable}); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err := UpdateUserConfig(func(config *types.UserConfig) error {
+		config.Channel = ChannelBeta
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("err = %v, want the update's error", err)
+	}
+	err = UpdateUserConfig(func(config *types.UserConfig) error {
+		config.Channel = "nightly"
+		return nil
+	})
+	if err == nil {
+		t.Error("an invalid channel was saved")
+	}
+
+	config, err := LoadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Channel != ChannelStable {
+		t.Errorf("Channel = %q, want the saved %q", config.Channel, ChannelStable)
+	}
+}
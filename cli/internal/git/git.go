@@ -2,13 +2,11 @@ package git
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
-
-	"github.com/AlecAivazis/survey/v2"
-	"github.com/velgardey/yok/cli/internal/utils"
 )
 
 // ExecuteCommand runs a git command and returns its output
@@ -25,6 +23,25 @@ func ExecuteCommand(args ...string) (string, error) {
 	return stdout.String(), nil
 }
 
+// RunAttached runs a git command attached to the terminal, so git pages and colors its output
+// and prompts as it does when run directly. It returns git's exit code.
+func RunAttached(args ...string) (int, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to run git: %w", err)
+	}
+	return 0, nil
+}
+
 // GetRepoInfo gets repository information from the current directory or prompts user
 // DEPRECATED: This function is no longer used. Use API client functions instead.
 func GetRepoInfo(useManualEntry bool) (string, string, error) {
@@ -64,168 +81,305 @@ func GetRemoteURL() (string, error) {
 	return remoteURL, nil
 }
 
-// EnsureRepo ensures that the current directory is a git repository
-func EnsureRepo() error {
+// IsRepo reports whether the current directory is a git repository
+func IsRepo() bool {
 	_, err := os.Stat(".git")
-	if os.IsNotExist(err) {
-		utils.InfoColor.Print("No Git repository found. Initializing... ")
-		_, err := ExecuteCommand("init")
-		if err != nil {
-			return fmt.Errorf("failed to initialize git repo: %v", err)
-		}
-		utils.SuccessColor.Println("Done")
+	return err == nil
+}
+
+// InitRepo initializes a git repository in the current directory
+func InitRepo() error {
+	if _, err := ExecuteCommand("init"); err != nil {
+		return fmt.Errorf("failed to initialize git repo: %v", err)
 	}
 	return nil
 }
 
-// CheckLocalRemoteSync checks if local changes match remote
-func CheckLocalRemoteSync() (bool, error) {
-	// First check if we have a remote
-	remoteURL, err := GetRemoteURL()
+// CurrentBranch returns the name of the checked out branch
+func CurrentBranch() (string, error) {
+	output, err := ExecuteCommand("rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
-		return false, fmt.Errorf("failed to get remote URL: %w", err)
-	}
-	if remoteURL == "" {
-		return false, fmt.Errorf("no remote repository configured")
+		return "", fmt.Errorf("failed to get current branch: %w", err)
 	}
+	return strings.TrimSpace(output), nil
+}
 
-	// Fetch latest from remote
-	if _, err := ExecuteCommand("fetch"); err != nil {
-		return false, fmt.Errorf("failed to fetch from remote: %w", err)
+// HeadCommit returns the SHA of the checked out commit
+func HeadCommit() (string, error) {
+	output, err := ExecuteCommand("rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get current commit: %w", err)
 	}
+	return strings.TrimSpace(output), nil
+}
 
-	// Check if we have an upstream branch
-	if _, err := ExecuteCommand("rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
-		return false, fmt.Errorf("no upstream branch configured")
+// UpstreamCommit returns the SHA of the commit the current branch's upstream was at when it was
+// last fetched or pushed to
+func UpstreamCommit() (string, error) {
+	output, err := ExecuteCommand("rev-parse", "@{upstream}")
+	if err != nil {
+		return "", fmt.Errorf("failed to get upstream commit: %w", err)
 	}
+	return strings.TrimSpace(output), nil
+}
 
-	// Check if we're behind the remote
-	behindOutput, err := ExecuteCommand("rev-list", "--count", "HEAD..@{upstream}")
+// RootDir returns the top-level directory of the repository
+func RootDir() (string, error) {
+	output, err := ExecuteCommand("rev-parse", "--show-toplevel")
 	if err != nil {
-		return false, fmt.Errorf("failed to check if behind remote: %w", err)
+		return "", fmt.Errorf("failed to get repository root: %w", err)
 	}
-	if behindCount := strings.TrimSpace(behindOutput); behindCount != "0" {
-		return false, fmt.Errorf("your local branch is %s commits behind the remote", behindCount)
+	return strings.TrimSpace(output), nil
+}
+
+// UnchangedSince reports whether HEAD is still at commitSHA and the working tree is clean. It
+// reports false when commitSHA is unknown (empty) or no longer exists in the local repository.
+func UnchangedSince(commitSHA string) (bool, error) {
+	if commitSHA == "" {
+		return false, nil
 	}
 
-	// Check if we're ahead of the remote
-	aheadOutput, err := ExecuteCommand("rev-list", "--count", "@{upstream}..HEAD")
+	// The commit may have been garbage collected or never fetched
+	if _, err := ExecuteCommand("cat-file", "-e", commitSHA+"^{commit}"); err != nil {
+		return false, nil
+	}
+
+	previous, err := ExecuteCommand("rev-parse", commitSHA+"^{commit}")
 	if err != nil {
-		return false, fmt.Errorf("failed to check if ahead of remote: %w", err)
+		return false, fmt.Errorf("failed to resolve commit %s: %w", commitSHA, err)
 	}
-	if aheadCount := strings.TrimSpace(aheadOutput); aheadCount != "0" {
-		return false, fmt.Errorf("your local branch is %s commits ahead of the remote", aheadCount)
+
+	head, err := HeadCommit()
+	if err != nil {
+		return false, err
+	}
+
+	if strings.TrimSpace(previous) != head {
+		return false, nil
 	}
 
 	// Check for uncommitted changes
-	if hasUncommittedChanges() {
-		return false, fmt.Errorf("you have uncommitted changes")
+	statusOutput, err := ExecuteCommand("status", "--porcelain", "--", ".")
+	if err != nil {
+		return false, fmt.Errorf("failed to check for uncommitted changes: %w", err)
 	}
 
-	return true, nil
+	return strings.TrimSpace(statusOutput) == "", nil
 }
 
-// hasUncommittedChanges checks if there are any uncommitted changes
-func hasUncommittedChanges() bool {
-	statusOutput, err := ExecuteCommand("status", "--porcelain")
-	if err != nil {
-		return false // Assume no changes if we can't check
-	}
-	return strings.TrimSpace(statusOutput) != ""
+// BehindRemoteError is returned by CheckLocalRemoteSync when the only difference from the
+// remote is commits the local branch is missing, which a fast-forward pull fixes
+type BehindRemoteError struct {
+	Commits string
 }
 
-// HandleUncommittedChanges checks for uncommitted changes and offers to commit and push them
-func HandleUncommittedChanges() error {
-	if !hasUncommittedChanges() {
-		return nil // No changes to handle
-	}
+func (e *BehindRemoteError) Error() string {
+	return fmt.Sprintf("your local branch is %s commits behind the remote", e.Commits)
+}
 
-	// Show uncommitted changes
-	statusOutput, err := ExecuteCommand("status", "--porcelain")
-	if err != nil {
-		return fmt.Errorf("failed to check for uncommitted changes: %w", err)
-	}
+// FetchFailure is the cause of a failed fetch from the remote
+type FetchFailure int
+
+const (
+	// FetchFailed is a fetch failure with no more specific cause
+	FetchFailed FetchFailure = iota
+	// FetchNetwork means the remote couldn't be reached
+	FetchNetwork
+	// FetchAuth means the remote rejected or asked for credentials
+	FetchAuth
+	// FetchRemoteNotFound means the remote repository doesn't exist or isn't visible
+	FetchRemoteNotFound
+)
 
-	fmt.Println("Uncommitted changes detected:")
-	fmt.Println(statusOutput)
+// fetchFailurePatterns match the stderr of git fetch to the cause of the failure. Authentication
+// is checked first, since those failures also mention the URL that couldn't be accessed.
+var fetchFailurePatterns = []struct {
+	failure  FetchFailure
+	patterns []string
+}{
+	{FetchAuth, []string{
+		"authentication failed",
+		"could not read username",
+		"could not read password",
+		"terminal prompts disabled",
+		"permission denied (publickey",
+		"invalid username or password",
+		"the requested url returned error: 401",
+		"the requested url returned error: 403",
+	}},
+	{FetchRemoteNotFound, []string{
+		"repository not found",
+		"does not appear to be a git repository",
+		"the requested url returned error: 404",
+	}},
+	{FetchNetwork, []string{
+		"could not resolve host",
+		"could not resolve hostname",
+		"temporary failure in name resolution",
+		"name or service not known",
+		"network is unreachable",
+		"no route to host",
+		"connection refused",
+		"connection timed out",
+		"operation timed out",
+		"connection reset",
+		"failed to connect to",
+	}},
+}
+
+// FetchError is returned by CheckLocalRemoteSync when fetching from the remote fails
+type FetchError struct {
+	Failure FetchFailure
+	Err     error
+}
 
-	// Ask user if they want to commit changes
-	if !confirmCommitChanges() {
-		return fmt.Errorf("you have uncommitted changes")
+func (e *FetchError) Error() string {
+	switch e.Failure {
+	case FetchNetwork:
+		return "couldn't reach the remote; check your network connection"
+	case FetchAuth:
+		return "the remote requires authentication; check your git credentials"
+	case FetchRemoteNotFound:
+		return "the remote repository was not found; check the remote URL and that you have access to it"
+	default:
+		return fmt.Sprintf("failed to fetch from remote: %v", e.Err)
 	}
+}
 
-	// Get commit message
-	commitMessage, err := getCommitMessage()
-	if err != nil {
-		return err
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// classifyFetchError returns the cause of a failed fetch from the error ExecuteCommand returned
+// for it, which includes git's stderr
+func classifyFetchError(err error) FetchFailure {
+	message := strings.ToLower(err.Error())
+	for _, entry := range fetchFailurePatterns {
+		for _, pattern := range entry.patterns {
+			if strings.Contains(message, pattern) {
+				return entry.failure
+			}
+		}
 	}
+	return FetchFailed
+}
 
-	// Perform git operations
-	return CommitAndPushChanges(commitMessage)
+// IsNetworkError reports whether err is a fetch that failed because the remote couldn't be reached
+func IsNetworkError(err error) bool {
+	var fetchErr *FetchError
+	return errors.As(err, &fetchErr) && fetchErr.Failure == FetchNetwork
 }
 
-// confirmCommitChanges asks user if they want to commit changes
-func confirmCommitChanges() bool {
-	opts := utils.GetSurveyOptions()
+// CheckLocalRemoteSync checks if local changes match remote. With a branch, that branch is
+// compared to the ref it tracks instead of the checked out one. Uncommitted changes and
+// BehindRemoteError, which pulling fixes, then only apply if it is the checked out branch.
+func CheckLocalRemoteSync(branch string) (bool, error) {
+	// First check if we have a remote
+	remoteURL, err := GetRemoteURL()
+	if err != nil {
+		return false, fmt.Errorf("failed to get remote URL: %w", err)
+	}
+	if remoteURL == "" {
+		return false, fmt.Errorf("no remote repository configured")
+	}
 
-	var commitChanges bool
-	prompt := &survey.Confirm{
-		Message: "Do you want to commit and push these changes before deploying?",
-		Default: true,
+	local, description, checkedOut := "HEAD", "your local branch", true
+	if branch != "" {
+		if _, err := ExecuteCommand("rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+			return false, fmt.Errorf("branch %q does not exist", branch)
+		}
+		current, err := CurrentBranch()
+		if err != nil {
+			return false, err
+		}
+		local, description, checkedOut = branch, "branch "+branch, current == branch
 	}
+	upstream := local + "@{upstream}"
 
-	if err := survey.AskOne(prompt, &commitChanges, opts); err != nil {
-		return false
+	// Fetch latest from remote
+	if _, err := ExecuteCommand("fetch"); err != nil {
+		return false, &FetchError{Failure: classifyFetchError(err), Err: err}
 	}
 
-	return commitChanges
-}
+	// Check if we have an upstream branch
+	if _, err := ExecuteCommand("rev-parse", "--abbrev-ref", upstream); err != nil {
+		if branch != "" {
+			return false, fmt.Errorf("no upstream branch configured for %s", branch)
+		}
+		return false, fmt.Errorf("no upstream branch configured")
+	}
 
-// getCommitMessage prompts user for a commit message
-func getCommitMessage() (string, error) {
-	opts := utils.GetSurveyOptions()
+	// Check if we're behind the remote
+	behindOutput, err := ExecuteCommand("rev-list", "--count", local+".."+upstream)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if behind remote: %w", err)
+	}
+	behindCount := strings.TrimSpace(behindOutput)
 
-	var commitMessage string
-	prompt := &survey.Input{
-		Message: "Enter a commit message:",
+	// Check if we're ahead of the remote
+	aheadOutput, err := ExecuteCommand("rev-list", "--count", upstream+".."+local)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if ahead of remote: %w", err)
 	}
+	aheadCount := strings.TrimSpace(aheadOutput)
 
-	if err := survey.AskOne(prompt, &commitMessage, opts); err != nil {
-		return "", fmt.Errorf("error getting commit message: %w", err)
+	if behindCount != "0" {
+		// Being behind alone can be fixed by pulling
+		if checkedOut && aheadCount == "0" && !HasUncommittedChanges() {
+			return false, &BehindRemoteError{Commits: behindCount}
+		}
+		return false, fmt.Errorf("%s is %s commits behind the remote", description, behindCount)
+	}
+	if aheadCount != "0" {
+		return false, fmt.Errorf("%s is %s commits ahead of the remote", description, aheadCount)
 	}
 
-	if strings.TrimSpace(commitMessage) == "" {
-		return "", fmt.Errorf("commit message cannot be empty")
+	// Check for uncommitted changes
+	if checkedOut && HasUncommittedChanges() {
+		return false, fmt.Errorf("you have uncommitted changes")
 	}
 
-	return commitMessage, nil
+	return true, nil
 }
 
-// CommitAndPushChanges performs the git add, commit, and push operations
-func CommitAndPushChanges(commitMessage string) error {
-	// Git add
-	utils.InfoColor.Print("[+] Adding changes... ")
+// HasUncommittedChanges checks if there are any uncommitted changes
+func HasUncommittedChanges() bool {
+	statusOutput, err := ExecuteCommand("status", "--porcelain")
+	if err != nil {
+		return false // Assume no changes if we can't check
+	}
+	return strings.TrimSpace(statusOutput) != ""
+}
+
+// AddAll stages every change in the working tree
+func AddAll() error {
 	if _, err := ExecuteCommand("add", "."); err != nil {
-		fmt.Println()
 		return fmt.Errorf("error adding files: %w", err)
 	}
-	utils.SuccessColor.Println("Done")
+	return nil
+}
 
-	// Git commit
-	utils.InfoColor.Print("[*] Committing changes... ")
-	if _, err := ExecuteCommand("commit", "-m", commitMessage); err != nil {
-		fmt.Println()
+// Commit commits the staged changes with the given message
+func Commit(message string) error {
+	if _, err := ExecuteCommand("commit", "-m", message); err != nil {
 		return fmt.Errorf("error committing changes: %w", err)
 	}
-	utils.SuccessColor.Println("Done")
+	return nil
+}
+
+// PullFastForward pulls the current branch from its remote, failing rather than creating a
+// merge commit if the branches have diverged
+func PullFastForward() error {
+	if _, err := ExecuteCommand("pull", "--ff-only"); err != nil {
+		return fmt.Errorf("error pulling changes: %w", err)
+	}
+	return nil
+}
 
-	// Git push
-	utils.InfoColor.Print("[^] Pushing to remote... ")
+// Push pushes the current branch to its remote
+func Push() error {
 	if _, err := ExecuteCommand("push"); err != nil {
-		fmt.Println()
 		return fmt.Errorf("error pushing changes: %w", err)
 	}
-	utils.SuccessColor.Println("Done")
-
 	return nil
 }
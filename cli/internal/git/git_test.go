@@ -0,0 +1,164 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolateGit keeps the user's git configuration out of the test and sets an identity to commit with
+func isolateGit(t *testing.T) {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+}
+
+// mustRun runs a git command, failing the test if it fails
+func mustRun(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := ExecuteCommand(args...)
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return output
+}
+
+// commitFile writes a file and commits it
+func commitFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Commit("Change " + name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepoOutsideRepository(t *testing.T) {
+	isolateGit(t)
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(mustGetwd(t)))
+
+	if IsRepo() {
+		t.Error("IsRepo() = true outside a repository")
+	}
+	// Failures are returned rather than ending the process
+	if _, err := HeadCommit(); err == nil {
+		t.Error("HeadCommit succeeded outside a repository")
+	}
+	if _, err := CheckLocalRemoteSync(""); err == nil {
+		t.Error("CheckLocalRemoteSync succeeded outside a repository")
+	}
+	if err := Commit("nothing"); err == nil {
+		t.Error("Commit succeeded outside a repository")
+	}
+}
+
+func TestRepoChanges(t *testing.T) {
+	isolateGit(t)
+	t.Chdir(t.TempDir())
+
+	if err := InitRepo(); err != nil {
+		t.Fatal(err)
+	}
+	if !IsRepo() {
+		t.Fatal("IsRepo() = false after InitRepo")
+	}
+
+	commitFile(t, "index.html", "home")
+	head, err := HeadCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if HasUncommittedChanges() {
+		t.Error("HasUncommittedChanges() = true after committing")
+	}
+	if unchanged, err := UnchangedSince(head); err != nil || !unchanged {
+		t.Errorf("UnchangedSince(HEAD) = %v, %v, want true", unchanged, err)
+	}
+
+	if err := os.WriteFile("index.html", []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !HasUncommittedChanges() {
+		t.Error("HasUncommittedChanges() = false with a modified file")
+	}
+	if unchanged, _ := UnchangedSince(head); unchanged {
+		t.Error("UnchangedSince(HEAD) = true with a modified file")
+	}
+
+	mustRun(t, "checkout", "--", "index.html")
+	commitFile(t, "about.html", "about")
+	if unchanged, _ := UnchangedSince(head); unchanged {
+		t.Error("UnchangedSince(an older commit) = true")
+	}
+	if unchanged, err := UnchangedSince("0123456789abcdef0123456789abcdef01234567"); err != nil || unchanged {
+		t.Errorf("UnchangedSince(an unknown commit) = %v, %v, want false", unchanged, err)
+	}
+}
+
+func TestCheckLocalRemoteSync(t *testing.T) {
+	isolateGit(t)
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	mustRun(t, "init", "--bare", remote)
+
+	// A second clone pushes the commits the first one is then behind by
+	other := t.TempDir()
+	t.Chdir(other)
+	mustRun(t, "clone", remote, ".")
+	commitFile(t, "index.html", "home")
+	mustRun(t, "push", "origin", "HEAD")
+
+	t.Chdir(t.TempDir())
+	mustRun(t, "clone", remote, ".")
+	if synced, err := CheckLocalRemoteSync(""); err != nil || !synced {
+		t.Fatalf("CheckLocalRemoteSync after cloning = %v, %v, want synced", synced, err)
+	}
+	head, _ := HeadCommit()
+	if upstream, err := UpstreamCommit(); err != nil || upstream != head {
+		t.Errorf("UpstreamCommit after cloning = %q, %v, want HEAD %q", upstream, err, head)
+	}
+
+	commitFile(t, "about.html", "about")
+	if upstream, _ := UpstreamCommit(); upstream != head {
+		t.Errorf("UpstreamCommit = %q with an unpushed commit, want the pushed %q", upstream, head)
+	}
+	if _, err := CheckLocalRemoteSync(""); err == nil {
+		t.Error("CheckLocalRemoteSync succeeded with an unpushed commit")
+	}
+	if err := Push(); err != nil {
+		t.Fatal(err)
+	}
+	if upstream, _ := UpstreamCommit(); upstream == head {
+		t.Error("UpstreamCommit didn't move after pushing")
+	}
+
+	t.Chdir(other)
+	var behind *BehindRemoteError
+	if _, err := CheckLocalRemoteSync(""); !errors.As(err, &behind) || behind.Commits != "1" {
+		t.Errorf("CheckLocalRemoteSync behind the remote = %v, want a BehindRemoteError of 1 commit", err)
+	}
+	if err := PullFastForward(); err != nil {
+		t.Fatal(err)
+	}
+	if synced, err := CheckLocalRemoteSync(""); err != nil || !synced {
+		t.Errorf("CheckLocalRemoteSync after pulling = %v, %v, want synced", synced, err)
+	}
+}
+
+func mustGetwd(t *testing.T) string {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
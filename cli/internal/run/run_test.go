@@ -0,0 +1,58 @@
+package run
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the scripts are written for sh")
+	}
+	dir := t.TempDir()
+	tests := []struct {
+		command    string
+		wantStdout string
+		wantStderr string
+		wantErr    string
+	}{
+		{"echo built", "built\n", "", ""},
+		{"pwd", dir + "\n", "", ""},
+		{`echo "$YOK_DEPLOYMENT_ID"`, "d1\n", "", ""},
+		{"echo failing >&2; exit 3", "", "failing\n", "exited with status 3"},
+		{"echo first && false && echo second", "first\n", "", "exited with status 1"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr strings.Builder
+		err := Shell(tt.command, dir, []string{"YOK_DEPLOYMENT_ID=d1"}, time.Minute, &stdout, &stderr)
+		if stdout.String() != tt.wantStdout || stderr.String() != tt.wantStderr {
+			t.Errorf("Shell(%q) wrote %q and %q, want %q and %q", tt.command, stdout.String(), stderr.String(), tt.wantStdout, tt.wantStderr)
+		}
+		if gotErr := errorString(err); gotErr != tt.wantErr {
+			t.Errorf("Shell(%q) error = %q, want %q", tt.command, gotErr, tt.wantErr)
+		}
+	}
+}
+
+func TestShellTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the scripts are written for sh")
+	}
+	start := time.Now()
+	err := Shell("sleep 10", t.TempDir(), nil, 100*time.Millisecond, nil, nil)
+	if err == nil || err.Error() != "timed out after 100ms" {
+		t.Errorf("Shell error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the command was killed after %s", elapsed)
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -11,6 +11,13 @@ type Project struct {
 	GitRepoURL string `json:"gitRepoUrl"`
 	Slug       string `json:"slug"`
 	Framework  string `json:"framework"`
+	// RootDir is the repository subdirectory the app is built from, empty for the repository root
+	RootDir string `json:"rootDir,omitempty"`
+	// PromotedDeploymentID is the deployment the project slug currently points at
+	PromotedDeploymentID string `json:"promotedDeploymentId,omitempty"`
+	// Partial is set when only the project's ID is known, because the API server couldn't
+	// serve the project itself and only confirmed it has deployments
+	Partial bool `json:"-"`
 }
 
 // ProjectResponse wraps a project response from the API
@@ -30,10 +37,50 @@ type DeploymentResponse struct {
 	} `json:"data"`
 }
 
+// DeployRequest is the payload sent to the API to trigger a deployment
+type DeployRequest struct {
+	ProjectID string `json:"projectId"`
+	Note      string `json:"note,omitempty"`
+}
+
 // Config stores local configuration
 type Config struct {
-	ProjectID string `json:"projectId"`
-	RepoName  string `json:"repoName"`
+	ProjectID string       `json:"projectId" yaml:"projectId"`
+	RepoName  string       `json:"repoName" yaml:"repoName"`
+	Hooks     *HooksConfig `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	// SkipUnchanged makes deploy skip deploying when nothing changed since the last deployed commit
+	SkipUnchanged bool `json:"skipUnchanged,omitempty" yaml:"skipUnchanged,omitempty"`
+}
+
+// UserConfig holds the per-user settings and state shared by every project
+type UserConfig struct {
+	// CheckUpdates enables the notice printed after commands when a newer release is available
+	CheckUpdates bool `json:"checkUpdates,omitempty"`
+	// UpdateCheckInterval is how long a looked up latest release is reused, e.g. "24h"
+	UpdateCheckInterval string `json:"updateCheckInterval,omitempty"`
+	// Channel is the release channel updates come from: "stable" (the default) or "beta",
+	// which includes prereleases
+	Channel string `json:"channel,omitempty"`
+	// LatestVersion is the latest release found by the last update check
+	LatestVersion string `json:"latestVersion,omitempty"`
+	// LatestVersionChannel is the channel LatestVersion was looked up for; empty means stable
+	LatestVersionChannel string `json:"latestVersionChannel,omitempty"`
+	// LatestVersionCheckedAt is when LatestVersion was looked up
+	LatestVersionCheckedAt time.Time `json:"latestVersionCheckedAt,omitempty"`
+	// UpdateNoticeShownAt is when the update notice was last shown
+	UpdateNoticeShownAt time.Time `json:"updateNoticeShownAt,omitempty"`
+	// DeployedCommits maps project IDs to the commit of the last deployment that completed
+	// from this machine
+	DeployedCommits map[string]string `json:"deployedCommits,omitempty"`
+}
+
+// HooksConfig holds the shell commands run around a deployment
+type HooksConfig struct {
+	PreDeploy  []string `json:"preDeploy,omitempty" yaml:"preDeploy,omitempty"`
+	PostDeploy []string `json:"postDeploy,omitempty" yaml:"postDeploy,omitempty"` // Run after the deployment completes
+	OnFailure  []string `json:"onFailure,omitempty" yaml:"onFailure,omitempty"`   // Run after the deployment fails or is cancelled
+	// Timeout limits how long each hook may run, as a duration such as "5m"
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
 }
 
 // ProjectCheckResponse wraps a project check response
@@ -53,6 +100,20 @@ type Deployment struct {
 	UpdatedAt     time.Time  `json:"updatedAt"`
 	CompletedAt   *time.Time `json:"completedAt,omitempty"`
 	DeploymentUrl string     `json:"deploymentUrl,omitempty"`
+	Note          string     `json:"note,omitempty"`
+	CommitSHA     string     `json:"commitSha,omitempty"`
+	// QueuePosition is the deployment's place in the build queue while it is QUEUED, counting
+	// from 1, or 0 if the API doesn't report it
+	QueuePosition int `json:"queuePosition,omitempty"`
+	// Metrics is only present when the API reports build and upload statistics
+	Metrics *DeploymentMetrics `json:"metrics,omitempty"`
+}
+
+// DeploymentMetrics holds optional build and upload statistics for a finished deployment
+type DeploymentMetrics struct {
+	FilesUploaded   int   `json:"filesUploaded,omitempty"`
+	TotalSizeBytes  int64 `json:"totalSizeBytes,omitempty"`
+	BuildDurationMs int64 `json:"buildDurationMs,omitempty"`
 }
 
 // DeploymentListResponse wraps a deployment list response
@@ -71,6 +132,22 @@ type DeploymentStatusResponse struct {
 	} `json:"data"`
 }
 
+// ErrorResponse is the body the API returns with an error status code
+// Some endpoints set message and others error
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+// ServerVersionResponse wraps the API server version response
+type ServerVersionResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Version string `json:"version"`
+	} `json:"data"`
+}
+
 // GitHubRelease represents GitHub release information
 type GitHubRelease struct {
 	TagName    string `json:"tag_name"`
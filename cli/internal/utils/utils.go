@@ -1,19 +1,22 @@
 package utils
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
 	"runtime"
-	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/blang/semver"
 	"github.com/briandowns/spinner"
 	"github.com/gookit/color"
 	"github.com/velgardey/yok/cli/internal/types"
@@ -36,20 +39,68 @@ const (
 	ConfigFile  = ".yok-config.json"
 	HttpTimeout = 30 * time.Second
 	UserAgent   = "Yok-CLI-Updater"
+
+	// CACertEnvVar names the environment variable pointing at an extra PEM CA bundle to trust
+	CACertEnvVar = "YOK_CA_CERT"
+)
+
+// Exit codes returned by the CLI. Scripts and CI depend on these, so they must stay stable.
+const (
+	ExitSuccess          = 0  // Command succeeded
+	ExitError            = 1  // Generic error
+	ExitDeploymentFailed = 2  // The deployment failed
+	ExitInterrupted      = 3  // Interrupted or cancelled
+	ExitAuthError        = 4  // The API rejected the request as unauthorized
+	ExitNotFound         = 5  // The project or deployment doesn't exist
+	ExitTimedOut         = 6  // Stopped waiting for the deployment after --timeout
+	ExitUpdateAvailable  = 10 // self-update --check found a newer release
 )
 
 // CreateHTTPClient returns an HTTP client with appropriate timeouts and settings
+// It honors HTTP_PROXY/HTTPS_PROXY/NO_PROXY and trusts the extra CA bundle in YOK_CA_CERT, if set
 func CreateHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+
+	if caCertPath := os.Getenv(CACertEnvVar); caCertPath != "" {
+		rootCAs, err := loadCACertPool(caCertPath)
+		if err != nil {
+			WarnColor.Printf("Warning: Ignoring %s: %v\n", CACertEnvVar, err)
+		} else {
+			transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+		}
+	}
+
 	return &http.Client{
-		Timeout: time.Second * 30,
+		Timeout:   time.Second * 30,
+		Transport: transport,
+	}
+}
+
+// loadCACertPool returns the system certificate pool extended with the PEM certificates in path
+func loadCACertPool(path string) (*x509.CertPool, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil || rootCAs == nil {
+		rootCAs = x509.NewCertPool()
 	}
+
+	if !rootCAs.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("no valid PEM certificates found in %s", path)
+	}
+
+	return rootCAs, nil
 }
 
 // HandleError prints error messages and exits with non-zero code if err is not nil
 func HandleError(err error, message string) {
 	if err != nil {
 		ErrorColor.Printf("[ERROR] %s: %v\n", message, err)
-		os.Exit(1)
+		os.Exit(ExitError)
 	}
 }
 
@@ -68,6 +119,41 @@ func StopSpinner(s *spinner.Spinner) {
 	}
 }
 
+// ProgressWriter shows how much of a download has been written to it in a spinner's suffix
+type ProgressWriter struct {
+	spinner *spinner.Spinner
+	message string
+	total   int64 // Unknown if not positive
+	written int64
+}
+
+// NewProgressWriter returns a ProgressWriter updating s with message and the progress towards
+// total bytes; with an unknown total (e.g. no Content-Length) only the bytes written are shown
+func NewProgressWriter(s *spinner.Spinner, message string, total int64) *ProgressWriter {
+	p := &ProgressWriter{spinner: s, message: message, total: total}
+	p.update()
+	return p
+}
+
+// Write counts the bytes in b and updates the spinner
+func (p *ProgressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	p.update()
+	return len(b), nil
+}
+
+// update sets the spinner's suffix to the current progress
+func (p *ProgressWriter) update() {
+	progress := FormatBytes(p.written)
+	if p.total > 0 {
+		progress = fmt.Sprintf("%s / %s (%d%%)", progress, FormatBytes(p.total), p.written*100/p.total)
+	}
+
+	p.spinner.Lock()
+	p.spinner.Suffix = fmt.Sprintf(" %s %s", p.message, progress)
+	p.spinner.Unlock()
+}
+
 // FormatDeploymentStatus prints a deployment status with appropriate coloring
 func FormatDeploymentStatus(status string) {
 	switch status {
@@ -82,8 +168,37 @@ func FormatDeploymentStatus(status string) {
 	}
 }
 
+// ColorizeStatus returns a deployment status colored the same way as FormatDeploymentStatus
+func ColorizeStatus(status string) string {
+	switch status {
+	case "COMPLETED":
+		return SuccessColor.Sprint(status)
+	case "FAILED":
+		return ErrorColor.Sprint(status)
+	case "PENDING", "QUEUED", "IN_PROGRESS":
+		return InfoColor.Sprint(status)
+	default:
+		return status
+	}
+}
+
+// FormatTimeAgo formats how long ago t was, e.g. "5m ago" or "3d ago"
+func FormatTimeAgo(t time.Time) string {
+	elapsed := time.Since(t)
+	switch {
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm ago", int(elapsed.Minutes()))
+	case elapsed < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(elapsed.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(elapsed.Hours()/24))
+	}
+}
+
 // FormatTableRow prints a row in the deployments table with colored status
-func FormatTableRow(id string, status string, createdAt time.Time) {
+func FormatTableRow(id string, status string, createdAt time.Time, note string) {
 	// Display the full ID without truncation
 	fmt.Printf("%-36s ", id)
 	switch status {
@@ -96,10 +211,28 @@ func FormatTableRow(id string, status string, createdAt time.Time) {
 	default:
 		fmt.Printf("%-12s ", status)
 	}
-	fmt.Printf("%-20s\n", createdAt.Format("Jan 02 15:04:05"))
+	fmt.Printf("%-20s %s\n", createdAt.Format("Jan 02 15:04:05"), TruncateString(note, 30))
+}
+
+// FormatBytes formats a byte count as a human readable size (e.g. 1.5 MB)
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
 }
 
 // CompareVersions compares two version strings and returns true if latest is newer than current
+// Versions are compared using semantic versioning, so a release is newer than its pre-releases and
+// build metadata is ignored
 func CompareVersions(current, latest string) bool {
 	// Strip 'v' prefix if present
 	current = strings.TrimPrefix(current, "v")
@@ -115,46 +248,25 @@ func CompareVersions(current, latest string) bool {
 		return true // Empty current version should update
 	}
 
-	// Parse versions into components
-	currentParts := strings.Split(current, ".")
-	latestParts := strings.Split(latest, ".")
-
-	// Compare each version component
-	maxLen := max(len(currentParts), len(latestParts))
-
-	for i := range maxLen {
-		// If we run out of parts in one version, that version is older
-		if i >= len(currentParts) {
-			return true // Latest has more parts, so it's newer
-		}
-		if i >= len(latestParts) {
-			return false // Current has more parts, so it's newer
-		}
+	currentVersion, currentErr := semver.ParseTolerant(current)
+	latestVersion, latestErr := semver.ParseTolerant(latest)
+	if currentErr != nil || latestErr != nil {
+		// Versions that aren't semantic versions can only be told apart, not ordered
+		return current != latest
+	}
 
-		// Try to compare as integers
-		currentNum, currentErr := strconv.Atoi(currentParts[i])
-		latestNum, latestErr := strconv.Atoi(latestParts[i])
+	return latestVersion.GT(currentVersion)
+}
 
-		if currentErr == nil && latestErr == nil {
-			// Both are numeric, compare as numbers
-			if latestNum > currentNum {
-				return true
-			}
-			if latestNum < currentNum {
-				return false
-			}
-			// Equal components, continue to next component
-		} else {
-			// At least one is non-numeric, compare as strings
-			if currentParts[i] != latestParts[i] {
-				return latestParts[i] > currentParts[i]
-			}
-			// Equal components, continue to next component
-		}
+// MajorVersionsDiffer reports whether two semantic versions have different major versions
+// Versions that can't be parsed, such as development builds, never differ
+func MajorVersionsDiffer(a, b string) bool {
+	aVersion, aErr := semver.ParseTolerant(strings.TrimPrefix(a, "v"))
+	bVersion, bErr := semver.ParseTolerant(strings.TrimPrefix(b, "v"))
+	if aErr != nil || bErr != nil {
+		return false
 	}
-
-	// All components equal
-	return false
+	return aVersion.Major != bVersion.Major
 }
 
 // DecodeJSON decodes JSON from a reader into a target struct
@@ -259,7 +371,10 @@ type LogRenderer struct {
 	showTimestamps bool
 	useColors      bool
 	rawOutput      bool
+	levelColoring  bool
+	linePrefix     string
 	lastDate       string
+	writer         io.Writer
 }
 
 // NewLogRenderer creates a new LogRenderer with default settings
@@ -268,14 +383,27 @@ func NewLogRenderer() *LogRenderer {
 		showTimestamps: true,
 		useColors:      !IsWindows(), // Disable colors on Windows by default
 		rawOutput:      false,
+		levelColoring:  true,
+		writer:         os.Stdout,
 	}
 }
 
 // RenderLogEntry displays a log entry in the terminal
+// Each line is written with a single call so renderers for different deployments can share a writer
 func (lr *LogRenderer) RenderLogEntry(entry types.LogEntry) {
+	// Prefix identifying the deployment when several streams share the terminal
+	linePrefix := ""
+	if lr.linePrefix != "" {
+		if lr.useColors {
+			linePrefix = InfoColor.Sprintf("%s | ", lr.linePrefix)
+		} else {
+			linePrefix = fmt.Sprintf("%s | ", lr.linePrefix)
+		}
+	}
+
 	// If raw output is requested, just print the log without any formatting
 	if lr.rawOutput {
-		fmt.Println(entry.Log)
+		fmt.Fprintln(lr.writer, linePrefix+entry.Log)
 		return
 	}
 
@@ -287,16 +415,19 @@ func (lr *LogRenderer) RenderLogEntry(entry types.LogEntry) {
 
 		// Show date header if it's a new date
 		if lr.lastDate != date {
-			if lr.lastDate != "" {
-				// Add a line break before new date
-				fmt.Println()
+			header := fmt.Sprintf("─── %s ───────────────────────────────────", date)
+			if lr.useColors {
+				header = DimColor.Sprint(header)
 			}
 
-			if lr.useColors {
-				DimColor.Printf("─── %s ───────────────────────────────────\n", date)
+			if lr.lastDate != "" {
+				// Add a line break before new date
+				header = "\n" + linePrefix + header
 			} else {
-				fmt.Printf("─── %s ───────────────────────────────────\n", date)
+				header = linePrefix + header
 			}
+
+			fmt.Fprintln(lr.writer, header)
 			lr.lastDate = date
 		}
 
@@ -311,15 +442,54 @@ func (lr *LogRenderer) RenderLogEntry(entry types.LogEntry) {
 		}
 
 		// Process the log message
-		logMessage := entry.Log
+		logMessage := lr.colorizeByLevel(entry.Log)
 
 		// Print the log with appropriate styling
-		fmt.Print(prefix)
-		fmt.Println(logMessage)
+		fmt.Fprintln(lr.writer, linePrefix+prefix+logMessage)
 	} else {
 		// Fallback if timestamp format is unexpected
-		fmt.Println(entry.Log)
+		fmt.Fprintln(lr.writer, linePrefix+lr.colorizeByLevel(entry.Log))
+	}
+}
+
+// colorizeByLevel colors a log message that looks like an error or a warning
+func (lr *LogRenderer) colorizeByLevel(message string) string {
+	if !lr.useColors || !lr.levelColoring {
+		return message
+	}
+
+	trimmed := strings.TrimSpace(message)
+	upper := strings.ToUpper(trimmed)
+	switch {
+	case strings.HasPrefix(upper, "ERROR"), strings.HasPrefix(upper, "[ERROR]"),
+		strings.HasPrefix(trimmed, "npm ERR!"):
+		return ErrorColor.Sprint(message)
+	case strings.HasPrefix(upper, "WARN"), strings.HasPrefix(upper, "[WARN"),
+		strings.HasPrefix(trimmed, "npm WARN"):
+		return WarnColor.Sprint(message)
+	default:
+		return message
+	}
+}
+
+// TailLogEntries returns the last n log entries, or all of them if n is 0 or there are fewer than n
+func TailLogEntries(entries []types.LogEntry, n int) []types.LogEntry {
+	if n <= 0 || n >= len(entries) {
+		return entries
 	}
+	return entries[len(entries)-n:]
+}
+
+// WithLinePrefix configures a label printed at the start of every line, e.g. a short deployment ID
+func (lr *LogRenderer) WithLinePrefix(prefix string) *LogRenderer {
+	lr.linePrefix = prefix
+	return lr
+}
+
+// WithWriter configures where rendered log lines are written (os.Stdout by default)
+func (lr *LogRenderer) WithWriter(w io.Writer) *LogRenderer {
+	lr.writer = w
+	return lr
 }
 
 // WithTimestamps configures whether timestamps are shown
@@ -334,6 +504,12 @@ func (lr *LogRenderer) WithColors(use bool) *LogRenderer {
 	return lr
 }
 
+// WithLevelColoring configures whether error and warning lines are colored by their level
+func (lr *LogRenderer) WithLevelColoring(enabled bool) *LogRenderer {
+	lr.levelColoring = enabled
+	return lr
+}
+
 // WithRawOutput configures whether to display raw log output without formatting
 func (lr *LogRenderer) WithRawOutput(raw bool) *LogRenderer {
 	lr.rawOutput = raw
@@ -345,6 +521,20 @@ func IsWindows() bool {
 	return runtime.GOOS == "windows"
 }
 
+// OpenBrowser opens the given URL in the user's default browser
+func OpenBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default: // linux, freebsd, etc.
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
 // WaitForInterrupt waits for an interrupt signal (Ctrl+C) or until the given stop channel is closed
 // It returns true if the process completed naturally, false if it was interrupted
 func WaitForInterrupt(stopChan chan bool) bool {
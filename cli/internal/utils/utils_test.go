@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/velgardey/yok/cli/internal/types"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		current string
+		latest  string
+		want    bool
+	}{
+		{"1.2.0", "1.2.1", true},
+		{"1.2.1", "1.2.0", false},
+		{"1.2.0", "1.2.0", false},
+		{"v1.2.0", "1.10.0", true},
+		{"1.10.0", "v1.9.0", false},
+		{"1.2.0-rc.1", "1.2.0-rc.2", true},
+		{"1.2.0-rc.2", "1.2.0-rc.1", false},
+		{"1.2.0-rc.2", "1.2.0", true},
+		{"1.2.0", "1.2.0-rc.2", false},
+		{"1.2.0-rc.10", "1.2.0-rc.9", false},
+		{"1.2.0+build.1", "1.2.0+build.2", false},
+		{"1.2.0", "1.2.0+build.1", false},
+		{"1.2.0+build.5", "1.2.1+build.1", true},
+		{"1.2", "1.2.1", true},
+		{"dev", "1.0.0", true},
+		{"", "1.0.0", true},
+		{"1.0.0", "", false},
+		{"nightly-a", "nightly-b", true},
+		{"nightly-a", "nightly-a", false},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.current, tt.latest); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestLogRendererWriter(t *testing.T) {
+	entries := []types.LogEntry{
+		{Log: "Installing dependencies", Timestamp: "2024-05-01 10:00:00"},
+		{Log: "ERROR build failed", Timestamp: "2024-05-01 10:00:05"},
+		{Log: "Retrying", Timestamp: "2024-05-02 09:00:00"},
+		{Log: "no timestamp"},
+	}
+	tests := []struct {
+		name     string
+		renderer func(*LogRenderer) *LogRenderer
+		want     string
+	}{
+		{
+			"formatted",
+			func(lr *LogRenderer) *LogRenderer { return lr },
+			"─── 2024-05-01 ───────────────────────────────────\n" +
+				"[10:00:00] Installing dependencies\n" +
+				"[10:00:05] ERROR build failed\n" +
+				"\n─── 2024-05-02 ───────────────────────────────────\n" +
+				"[09:00:00] Retrying\n" +
+				"no timestamp\n",
+		},
+		{
+			"raw with a prefix",
+			func(lr *LogRenderer) *LogRenderer { return lr.WithRawOutput(true).WithLinePrefix("d1") },
+			"d1 | Installing dependencies\nd1 | ERROR build failed\nd1 | Retrying\nd1 | no timestamp\n",
+		},
+		{
+			"without timestamps",
+			func(lr *LogRenderer) *LogRenderer { return lr.WithTimestamps(false) },
+			"─── 2024-05-01 ───────────────────────────────────\n" +
+				"Installing dependencies\nERROR build failed\n" +
+				"\n─── 2024-05-02 ───────────────────────────────────\n" +
+				"Retrying\nno timestamp\n",
+		},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		renderer := tt.renderer(NewLogRenderer().WithColors(false).WithWriter(&out))
+		for _, entry := range entries {
+			renderer.RenderLogEntry(entry)
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("%s: rendered\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package proxy
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cacheStatusHeader tells clients whether a response was served from the asset cache
+const cacheStatusHeader = "X-Yok-Cache"
+
+// AssetCache is a size-bounded, least recently used cache of successful GET responses. Objects
+// of a deployment never change, so responses are keyed by the deployment's URL and the path.
+type AssetCache struct {
+	maxBytes       int64
+	maxObjectBytes int64
+	defaultTTL     time.Duration
+
+	mu      sync.Mutex
+	size    int64
+	entries map[string]*list.Element
+	lru     *list.List // Most recently used at the front
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// cachedAsset is a response stored in the asset cache
+type cachedAsset struct {
+	key       string
+	header    http.Header
+	body      []byte
+	expiresAt time.Time
+}
+
+// AssetCacheStats are the counters and current size of an AssetCache
+type AssetCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+}
+
+// NewAssetCache returns a cache holding up to maxBytes of response bodies, each at most
+// maxObjectBytes. Responses without a max-age in their Cache-Control are kept for defaultTTL.
+func NewAssetCache(maxBytes, maxObjectBytes int64, defaultTTL time.Duration) *AssetCache {
+	return &AssetCache{
+		maxBytes:       maxBytes,
+		maxObjectBytes: maxObjectBytes,
+		defaultTTL:     defaultTTL,
+		entries:        make(map[string]*list.Element),
+		lru:            list.New(),
+	}
+}
+
+// Stats returns the cache's counters and current size
+func (c *AssetCache) Stats() AssetCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return AssetCacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   len(c.entries),
+		Bytes:     c.size,
+	}
+}
+
+// get returns the cached response for key, if there is one that hasn't expired
+func (c *AssetCache) get(key string) (*cachedAsset, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	asset := element.Value.(*cachedAsset)
+	if time.Now().After(asset.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+	return asset, true
+}
+
+// put stores a response, evicting the least recently used ones to make room for it
+func (c *AssetCache) put(asset *cachedAsset) {
+	size := int64(len(asset.body))
+	if size > c.maxObjectBytes || size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[asset.key]; ok {
+		c.remove(element)
+	}
+
+	for c.size+size > c.maxBytes {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+
+	c.entries[asset.key] = c.lru.PushFront(asset)
+	c.size += size
+}
+
+// remove drops an entry; the caller must hold c.mu
+func (c *AssetCache) remove(element *list.Element) {
+	asset := c.lru.Remove(element).(*cachedAsset)
+	delete(c.entries, asset.key)
+	c.size -= int64(len(asset.body))
+}
+
+// serve answers a GET or HEAD request from the cache, reporting whether it could. Conditional
+// requests matching the cached ETag or Last-Modified get a 304.
+func (c *AssetCache) serve(w http.ResponseWriter, r *http.Request, key string) bool {
+	asset, ok := c.get(key)
+	if !ok {
+		c.misses.Add(1)
+		return false
+	}
+	c.hits.Add(1)
+
+	for name, values := range asset.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(cacheStatusHeader, "HIT")
+	if notModified(r, asset.header) {
+		// A 304 has no body, so the headers describing one are dropped like http.ServeContent does
+		w.Header().Del("Content-Type")
+		w.Header().Del("Content-Length")
+		w.Header().Del("Content-Encoding")
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(asset.body)
+	}
+	return true
+}
+
+// notModified reports whether the client's copy of a response with header is still current,
+// going by If-None-Match if the request has it and If-Modified-Since otherwise (RFC 9110 13.2.2)
+func notModified(r *http.Request, header http.Header) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		etag := header.Get("ETag")
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || (etag != "" && weakETag(candidate) == weakETag(etag)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ifModifiedSince)
+}
+
+// weakETag returns an entity tag without its weakness indicator, since If-None-Match compares
+// them weakly
+func weakETag(etag string) string {
+	return strings.TrimPrefix(etag, "W/")
+}
+
+// store makes a successful GET response be cached once its body has been read completely,
+// unless the response can't be cached
+func (c *AssetCache) store(resp *http.Response, key string) {
+	if resp.StatusCode != http.StatusOK || resp.Request.Method != http.MethodGet {
+		return
+	}
+	if resp.ContentLength < 0 || resp.ContentLength > c.maxObjectBytes {
+		return
+	}
+	if resp.Header.Get("Vary") != "" || resp.Header.Get("Set-Cookie") != "" {
+		return
+	}
+
+	ttl, cacheable := cacheTTL(resp.Header.Get("Cache-Control"), c.defaultTTL)
+	if !cacheable {
+		return
+	}
+
+	resp.Header.Set(cacheStatusHeader, "MISS")
+	header := resp.Header.Clone()
+	header.Del(cacheStatusHeader)
+	contentLength := resp.ContentLength
+
+	resp.Body = &cachingBody{
+		ReadCloser: resp.Body,
+		onComplete: func(body []byte) {
+			if int64(len(body)) != contentLength {
+				return
+			}
+			c.put(&cachedAsset{key: key, header: header, body: body, expiresAt: time.Now().Add(ttl)})
+		},
+	}
+}
+
+// cacheTTL returns how long a response may be cached according to its Cache-Control header
+func cacheTTL(cacheControl string, defaultTTL time.Duration) (time.Duration, bool) {
+	ttl := defaultTTL
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+		switch name {
+		case "no-store", "no-cache", "private":
+			return 0, false
+		case "max-age", "s-maxage":
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			if seconds <= 0 {
+				return 0, false
+			}
+			ttl = time.Duration(seconds) * time.Second
+		}
+	}
+	return ttl, true
+}
+
+// cachingBody passes a response body through while keeping a copy, which is handed to
+// onComplete once the body has been read to the end
+type cachingBody struct {
+	io.ReadCloser
+	buf        bytes.Buffer
+	onComplete func(body []byte)
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF && b.onComplete != nil {
+		b.onComplete(b.buf.Bytes())
+		b.onComplete = nil
+	}
+	return n, err
+}
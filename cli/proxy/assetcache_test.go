@@ -0,0 +1,229 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAssetCacheConditionalRequests(t *testing.T) {
+	lastModified := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	var fetches atomic.Int32
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Header().Set("ETag", `"abc"`)
+		http.ServeContent(w, r, "app.js", lastModified, strings.NewReader("console.log('app')"))
+	}))
+	t.Cleanup(bucket.Close)
+
+	handler := Handler(func(r *http.Request) (Target, error) {
+		return Target{DeploymentID: "d1", BasePath: bucket.URL + "/__outputs/d1/"}, nil
+	}, WithAssetCache(NewAssetCache(1<<20, 1<<10, time.Hour)))
+
+	serve := func(method string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "http://brave-fox.yok.ninja/assets/app.js", nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(http.MethodGet, nil); rec.Code != http.StatusOK || rec.Header().Get(cacheStatusHeader) != "MISS" {
+		t.Fatalf("first GET = %d %s, want a 200 MISS", rec.Code, rec.Header().Get(cacheStatusHeader))
+	}
+
+	ifModifiedSince := lastModified.Format(http.TimeFormat)
+	before := lastModified.Add(-time.Hour).Format(http.TimeFormat)
+	tests := []struct {
+		name     string
+		method   string
+		header   http.Header
+		wantCode int
+	}{
+		{"unconditional", http.MethodGet, nil, http.StatusOK},
+		{"HEAD", http.MethodHead, nil, http.StatusOK},
+		{"matching ETag", http.MethodGet, http.Header{"If-None-Match": {`"abc"`}}, http.StatusNotModified},
+		{"matching weak ETag in a list", http.MethodGet, http.Header{"If-None-Match": {`"old", W/"abc"`}}, http.StatusNotModified},
+		{"any ETag", http.MethodHead, http.Header{"If-None-Match": {"*"}}, http.StatusNotModified},
+		{"other ETag", http.MethodGet, http.Header{"If-None-Match": {`"old"`}}, http.StatusOK},
+		{"not modified since", http.MethodGet, http.Header{"If-Modified-Since": {ifModifiedSince}}, http.StatusNotModified},
+		{"modified since", http.MethodGet, http.Header{"If-Modified-Since": {before}}, http.StatusOK},
+		{"malformed date", http.MethodGet, http.Header{"If-Modified-Since": {"yesterday"}}, http.StatusOK},
+		{"ETag wins over the date", http.MethodGet, http.Header{"If-None-Match": {`"old"`}, "If-Modified-Since": {ifModifiedSince}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.method, tt.header)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("%s = %d, want %d", tt.method, rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get(cacheStatusHeader); got != "HIT" {
+				t.Errorf("%s = %q, want HIT", cacheStatusHeader, got)
+			}
+			wantBody := ""
+			if tt.method == http.MethodGet && tt.wantCode == http.StatusOK {
+				wantBody = "console.log('app')"
+			}
+			if got := rec.Body.String(); got != wantBody {
+				t.Errorf("body = %q, want %q", got, wantBody)
+			}
+			if tt.wantCode == http.StatusNotModified && rec.Header().Get("Content-Length") != "" {
+				t.Error("a 304 has a Content-Length")
+			}
+			if got := rec.Header().Get("ETag"); got != `"abc"` {
+				t.Errorf("ETag = %q, want the cached one", got)
+			}
+		})
+	}
+
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetched the object %d times, want once", got)
+	}
+}
+
+func TestAssetCacheIgnoresQuery(t *testing.T) {
+	var fetches atomic.Int32
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte("console.log('app')"))
+	}))
+	t.Cleanup(bucket.Close)
+
+	cache := NewAssetCache(1<<20, 1<<10, time.Hour)
+	handler := Handler(func(r *http.Request) (Target, error) {
+		return Target{DeploymentID: "d1", BasePath: bucket.URL + "/__outputs/d1/"}, nil
+	}, WithAssetCache(cache))
+
+	for i, url := range []string{"/assets/app.js?v=1", "/assets/app.js?v=2", "/assets/app.js", "/assets/app.js?"} {
+		rec := get(handler, url)
+		want := "HIT"
+		if i == 0 {
+			want = "MISS"
+		}
+		if got := rec.Header().Get(cacheStatusHeader); rec.Code != http.StatusOK || got != want {
+			t.Errorf("GET %s = %d %s, want a 200 %s", url, rec.Code, got, want)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetched the object %d times, want once", got)
+	}
+	if stats := cache.Stats(); stats.Entries != 1 {
+		t.Errorf("Entries = %d, want one for every query", stats.Entries)
+	}
+}
+
+func TestAssetCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewAssetCache(10, 6, time.Hour)
+	add := func(key, body string) {
+		cache.put(&cachedAsset{key: key, header: http.Header{}, body: []byte(body), expiresAt: time.Now().Add(time.Hour)})
+	}
+
+	add("a", "aaaa")
+	add("b", "bbbb")
+	cache.get("a")
+	add("c", "cccc")
+	if _, ok := cache.get("b"); ok {
+		t.Error("the least recently used entry wasn't evicted")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Error("a recently used entry was evicted")
+	}
+
+	add("d", "too large")
+	if _, ok := cache.get("d"); ok {
+		t.Error("an object over the size limit was cached")
+	}
+
+	cache.put(&cachedAsset{key: "e", header: http.Header{}, body: []byte("e"), expiresAt: time.Now().Add(-time.Second)})
+	if _, ok := cache.get("e"); ok {
+		t.Error("an expired entry was served")
+	}
+
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Bytes != 8 || stats.Evictions != 1 {
+		t.Errorf("stats = %+v, want 2 entries of 8 bytes and 1 eviction", stats)
+	}
+}
+
+func TestAssetCacheConcurrentAccess(t *testing.T) {
+	objects := map[string]string{}
+	for i := range 8 {
+		objects[fmt.Sprintf("/__outputs/d1/assets/%d.js", i)] = strings.Repeat(strconv.Itoa(i), 100)
+	}
+	bucket := httptest.NewServer(&objectStore{objects: objects})
+	t.Cleanup(bucket.Close)
+
+	// Room for half of the assets, so entries are evicted while others are read
+	cache := NewAssetCache(400, 200, time.Hour)
+	handler := Handler(func(r *http.Request) (Target, error) {
+		return Target{DeploymentID: "d1", BasePath: bucket.URL + "/__outputs/d1/"}, nil
+	}, WithAssetCache(cache))
+
+	var wg sync.WaitGroup
+	for worker := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				asset := (worker + i) % 8
+				method := http.MethodGet
+				if i%3 == 0 {
+					method = http.MethodHead
+				}
+				req := httptest.NewRequest(method, fmt.Sprintf("http://brave-fox.yok.ninja/assets/%d.js", asset), nil)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+
+				wantBody := strings.Repeat(strconv.Itoa(asset), 100)
+				if method == http.MethodHead {
+					wantBody = ""
+				}
+				if rec.Code != http.StatusOK || rec.Body.String() != wantBody {
+					t.Errorf("%s /assets/%d.js = %d %q", method, asset, rec.Code, rec.Body.String())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	if stats.Bytes > 400 || stats.Entries > 4 {
+		t.Errorf("stats = %+v, want at most 4 entries of 400 bytes", stats)
+	}
+	if stats.Hits == 0 || stats.Evictions == 0 {
+		t.Errorf("stats = %+v, want hits and evictions", stats)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	tests := []struct {
+		cacheControl string
+		want         time.Duration
+		wantOK       bool
+	}{
+		{"", time.Minute, true},
+		{"public, max-age=3600", time.Hour, true},
+		{"public, max-age=31536000, immutable", 31536000 * time.Second, true},
+		{"s-maxage=30", 30 * time.Second, true},
+		{"max-age=0", 0, false},
+		{"no-cache", 0, false},
+		{"No-Store", 0, false},
+		{"private, max-age=60", 0, false},
+		{"max-age=soon", time.Minute, true},
+	}
+	for _, tt := range tests {
+		got, ok := cacheTTL(tt.cacheControl, time.Minute)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("cacheTTL(%q) = %v, %v, want %v, %v", tt.cacheControl, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"mime"
+	"net/http"
+	"regexp"
+)
+
+// DefaultHashedAssetPattern matches the paths of content-hashed assets, such as
+// /assets/index-8f3ab2cd.js, and everything Next.js emits under /_next/static/
+const DefaultHashedAssetPattern = `-[0-9a-f]{8,}\.|/_next/static/`
+
+const (
+	// immutableCacheControl lets browsers keep hashed assets, whose content never changes under
+	// the same name, without revalidating them
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// revalidateCacheControl makes browsers check for a new deployment on every visit
+	revalidateCacheControl = "no-cache"
+)
+
+// CacheControl sets Cache-Control on the successful responses of next that don't have one.
+// Assets whose path matches hashedAssets are cached for a year; everything else, HTML in
+// particular, is revalidated so new deployments show up right away. A Cache-Control the
+// deployment's objects were served with wins.
+func CacheControl(hashedAssets *regexp.Regexp, next http.Handler) http.Handler {
+	if hashedAssets == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The handler rewrites the path, so match the one the client asked for
+		hashed := hashedAssets.MatchString(r.URL.Path)
+		next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, hashed: hashed}, r)
+	})
+}
+
+// cacheControlWriter adds a missing Cache-Control just before the response is sent
+type cacheControlWriter struct {
+	http.ResponseWriter
+	hashed      bool
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if _, ok := w.Header()["Cache-Control"]; !ok && cacheableStatus(status) {
+			w.Header().Set("Cache-Control", w.cacheControl())
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// cacheControl returns the Cache-Control for the response. Pages are never immutable, even on a
+// hashed path, since they may be the SPA fallback's index.html.
+func (w *cacheControlWriter) cacheControl() string {
+	if !w.hashed {
+		return revalidateCacheControl
+	}
+	if mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type")); mediaType == "text/html" {
+		return revalidateCacheControl
+	}
+	return immutableCacheControl
+}
+
+// cacheableStatus reports whether a response with status is the deployment's object, so its
+// Cache-Control should be set. Errors and redirects keep their default caching.
+func cacheableStatus(status int) bool {
+	return status == http.StatusOK || status == http.StatusPartialContent || status == http.StatusNotModified
+}
+
+func (w *cacheControlWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *cacheControlWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
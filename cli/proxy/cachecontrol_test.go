@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestCacheControl(t *testing.T) {
+	handler := CacheControl(regexp.MustCompile(DefaultHashedAssetPattern), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/assets/index-8f3ab2cd.js", "/_next/static/chunks/main.js":
+			w.Header().Set("Content-Type", "text/javascript")
+		case "/dashboard-0123abcd.html", "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case "/assets/custom-8f3ab2cd.js":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/missing-8f3ab2cd.js":
+			http.NotFound(w, r)
+			return
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte("body"))
+	}))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/assets/index-8f3ab2cd.js", immutableCacheControl},
+		{"/_next/static/chunks/main.js", immutableCacheControl},
+		{"/", revalidateCacheControl},
+		{"/assets/app.js", revalidateCacheControl},
+		// Pages are revalidated even on a hashed path
+		{"/dashboard-0123abcd.html", revalidateCacheControl},
+		// The deployment's own Cache-Control wins
+		{"/assets/custom-8f3ab2cd.js", "max-age=60"},
+		// Errors and redirects are left alone
+		{"/missing-8f3ab2cd.js", ""},
+		{"/old", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja"+tt.path, nil))
+		if got := rec.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("%s: Cache-Control = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
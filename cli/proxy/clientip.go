@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// forwardedHeaders are the headers proxies in front of this one describe the client with
+var forwardedHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto"}
+
+type clientIPKey struct{}
+
+// ClientIPFrom returns the client address of the request ctx belongs to as found by RealIP, or
+// an invalid address if it has none
+func ClientIPFrom(ctx context.Context) netip.Addr {
+	addr, _ := ctx.Value(clientIPKey{}).(netip.Addr)
+	return addr
+}
+
+// ContextWithClientIP returns a copy of ctx carrying the client address addr
+func ContextWithClientIP(ctx context.Context, addr netip.Addr) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, addr)
+}
+
+// RealIP adds the address of the client that sent each request, as found by ClientIP, to the
+// request's context before passing it to next. The forwarded headers of requests that don't come
+// from one of trustedProxies are dropped, so they aren't logged or passed upstream either.
+func RealIP(trustedProxies []netip.Prefix, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ContainsAddr(trustedProxies, PeerAddr(r)) {
+			for _, name := range forwardedHeaders {
+				r.Header.Del(name)
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithClientIP(r.Context(), ClientIP(r, trustedProxies))))
+	})
+}
+
+// ClientIP returns the address of the client that sent r. The Forwarded header, or without one
+// X-Forwarded-For, is only believed when the request comes from one of trustedProxies, and then
+// read from the right, skipping the trusted proxies it went through, so a client can't pick its
+// address by sending the header.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) netip.Addr {
+	addr := PeerAddr(r)
+	if !addr.IsValid() || !ContainsAddr(trustedProxies, addr) {
+		return addr
+	}
+
+	hops := forwardedFor(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			// Anything left of a malformed or obfuscated entry can't be trusted either
+			break
+		}
+		addr = hop.Unmap()
+		if !ContainsAddr(trustedProxies, addr) {
+			break
+		}
+	}
+	return addr
+}
+
+// PeerAddr returns the address of the peer r came from, or an invalid address if it isn't known
+func PeerAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// forwardedFor returns the addresses the request was forwarded for, from the client to the last
+// proxy: the for= parameters of the RFC 7239 Forwarded header if there is one, and the entries
+// of X-Forwarded-For otherwise. Ports and the brackets of IPv6 addresses are dropped.
+func forwardedFor(header http.Header) []string {
+	var hops []string
+	if forwarded := header.Values("Forwarded"); len(forwarded) > 0 {
+		for _, element := range strings.Split(strings.Join(forwarded, ","), ",") {
+			node := ""
+			for _, pair := range strings.Split(element, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(name, "for") {
+					node = strings.Trim(value, `"`)
+				}
+			}
+			hops = append(hops, forwardedNode(node))
+		}
+		return hops
+	}
+
+	for _, hop := range strings.Split(strings.Join(header.Values("X-Forwarded-For"), ","), ",") {
+		hops = append(hops, strings.TrimSpace(hop))
+	}
+	return hops
+}
+
+// forwardedNode returns the address of a Forwarded for= node such as 192.0.2.60:8080 or
+// [2001:db8::1]:4711, without its port
+func forwardedNode(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
+
+// ContainsAddr reports whether addr is in any of prefixes
+func ContainsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8:ffff::/48"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		want       string
+	}{
+		{"direct", "192.0.2.1:4711", nil, "192.0.2.1"},
+		{"direct IPv4-mapped", "[::ffff:192.0.2.1]:4711", nil, "192.0.2.1"},
+		{"untrusted peer's header is ignored", "192.0.2.1:4711", http.Header{"X-Forwarded-For": {"198.51.100.7"}}, "192.0.2.1"},
+		{"trusted peer", "10.0.0.2:4711", http.Header{"X-Forwarded-For": {"198.51.100.7"}}, "198.51.100.7"},
+		{"spoofed entries left of the client", "10.0.0.2:4711", http.Header{"X-Forwarded-For": {"203.0.113.9, 198.51.100.7"}}, "198.51.100.7"},
+		{"chain of trusted proxies", "10.0.0.2:4711", http.Header{"X-Forwarded-For": {"198.51.100.7, 10.1.1.1", "10.2.2.2"}}, "198.51.100.7"},
+		{"malformed entry", "10.0.0.2:4711", http.Header{"X-Forwarded-For": {"198.51.100.7, unknown, 10.1.1.1"}}, "10.1.1.1"},
+		{"only trusted proxies", "10.0.0.2:4711", http.Header{"X-Forwarded-For": {"10.1.1.1"}}, "10.1.1.1"},
+		{"trusted peer without a header", "10.0.0.2:4711", nil, "10.0.0.2"},
+		{"Forwarded", "10.0.0.2:4711", http.Header{"Forwarded": {`for=198.51.100.7;proto=https, for="10.1.1.1:8080"`}}, "198.51.100.7"},
+		{"Forwarded IPv6", "[2001:db8:ffff::1]:4711", http.Header{"Forwarded": {`for="[2001:db8::17]:4711"`}}, "2001:db8::17"},
+		{"Forwarded wins over X-Forwarded-For", "10.0.0.2:4711", http.Header{
+			"Forwarded":       {"for=198.51.100.7"},
+			"X-Forwarded-For": {"203.0.113.9"},
+		}, "198.51.100.7"},
+		{"obfuscated Forwarded node", "10.0.0.2:4711", http.Header{"Forwarded": {"for=_hidden"}}, "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			if got := ClientIP(req, trusted); got.String() != tt.want {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRealIPDropsUntrustedForwardedHeaders(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	var got http.Header
+	var gotIP netip.Addr
+	handler := RealIP(trusted, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		gotIP = ClientIPFrom(r.Context())
+	}))
+
+	for _, tt := range []struct {
+		remoteAddr string
+		wantKept   bool
+		wantIP     string
+	}{
+		{"192.0.2.1:4711", false, "192.0.2.1"},
+		{"10.0.0.2:4711", true, "198.51.100.7"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set("Forwarded", "for=198.51.100.7")
+		req.Header.Set("X-Forwarded-For", "198.51.100.7")
+		req.Header.Set("X-Forwarded-Host", "brave-fox.yok.ninja")
+		req.Header.Set("X-Forwarded-Proto", "https")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		for _, name := range forwardedHeaders {
+			if kept := got.Get(name) != ""; kept != tt.wantKept {
+				t.Errorf("from %s: %s kept = %v, want %v", tt.remoteAddr, name, kept, tt.wantKept)
+			}
+		}
+		if gotIP.String() != tt.wantIP {
+			t.Errorf("from %s: client IP = %s, want %s", tt.remoteAddr, gotIP, tt.wantIP)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// contentTypeOverrides are the types served for extensions that mime.TypeByExtension doesn't
+// know or maps differently depending on the system's MIME tables
+var contentTypeOverrides = map[string]string{
+	".html":        "text/html",
+	".htm":         "text/html",
+	".js":          "text/javascript",
+	".mjs":         "text/javascript",
+	".cjs":         "text/javascript",
+	".css":         "text/css",
+	".svg":         "image/svg+xml",
+	".wasm":        "application/wasm",
+	".json":        "application/json",
+	".map":         "application/json",
+	".webmanifest": "application/manifest+json",
+	".txt":         "text/plain",
+	".xml":         "application/xml",
+	".ico":         "image/x-icon",
+	".webp":        "image/webp",
+	".avif":        "image/avif",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ttf":         "font/ttf",
+	".otf":         "font/otf",
+	".eot":         "application/vnd.ms-fontobject",
+}
+
+// textContentTypes are the non-text/* types that are served with a UTF-8 charset
+var textContentTypes = map[string]bool{
+	"application/json":          true,
+	"application/manifest+json": true,
+	"application/xml":           true,
+	"image/svg+xml":             true,
+}
+
+// isGenericContentType reports whether the object store sent a content type that says nothing
+// about the object, as S3 does for objects uploaded without one
+func isGenericContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/octet-stream" || mediaType == "binary/octet-stream"
+}
+
+// inferContentType returns the content type for an object from the extension of its path, with
+// a UTF-8 charset for text types, or "" if the extension is unknown
+func inferContentType(objectPath string) string {
+	ext := strings.ToLower(path.Ext(objectPath))
+	if ext == "" {
+		return ""
+	}
+
+	contentType, ok := contentTypeOverrides[ext]
+	if !ok {
+		contentType = mime.TypeByExtension(ext)
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	if _, ok := params["charset"]; !ok && (strings.HasPrefix(mediaType, "text/") || textContentTypes[mediaType]) {
+		params["charset"] = "utf-8"
+	}
+	return mime.FormatMediaType(mediaType, params)
+}
+
+// fixContentType replaces a missing or generic content type on resp with the one inferred from
+// objectPath, so browsers render pages and run module scripts instead of downloading them.
+// Specific types sent by the object store are kept.
+func fixContentType(resp *http.Response, objectPath string) {
+	if !isGenericContentType(resp.Header.Get("Content-Type")) {
+		return
+	}
+	if contentType := inferContentType(objectPath); contentType != "" {
+		resp.Header.Set("Content-Type", contentType)
+	}
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFixContentType(t *testing.T) {
+	tests := []struct {
+		objectPath string
+		sent       string
+		want       string
+	}{
+		{"/index.html", "application/octet-stream", "text/html; charset=utf-8"},
+		{"/assets/app.MJS", "binary/octet-stream", "text/javascript; charset=utf-8"},
+		{"/assets/app.css", "", "text/css; charset=utf-8"},
+		{"/manifest.webmanifest", "application/octet-stream", "application/manifest+json; charset=utf-8"},
+		{"/logo.svg", "application/octet-stream", "image/svg+xml; charset=utf-8"},
+		{"/app.wasm", "application/octet-stream", "application/wasm"},
+		{"/fonts/a.woff2", "application/octet-stream", "font/woff2"},
+		{"/photo.png", "application/octet-stream", "image/png"},
+		// Types set on upload are kept
+		{"/data.json", "application/vnd.api+json", "application/vnd.api+json"},
+		{"/index.html", "text/html; charset=iso-8859-1", "text/html; charset=iso-8859-1"},
+		// Unknown extensions and extensionless objects stay as they were sent
+		{"/archive.unknownext", "application/octet-stream", "application/octet-stream"},
+		{"/LICENSE", "application/octet-stream", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.sent != "" {
+			resp.Header.Set("Content-Type", tt.sent)
+		}
+		fixContentType(resp, tt.objectPath)
+		if got := resp.Header.Get("Content-Type"); got != tt.want {
+			t.Errorf("%s sent as %q: Content-Type = %q, want %q", tt.objectPath, tt.sent, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DirectoryIndex sets how the handler serves extensionless paths such as /docs that have no
+// object but a /docs/index.html
+type DirectoryIndex string
+
+const (
+	// DirectoryIndexRedirect redirects to the path with a trailing slash, so relative links in
+	// the page resolve against the directory
+	DirectoryIndexRedirect DirectoryIndex = "redirect"
+	// DirectoryIndexRewrite serves the directory's index.html at the path itself
+	DirectoryIndexRewrite DirectoryIndex = "rewrite"
+	// DirectoryIndexOff treats the path as missing
+	DirectoryIndexOff DirectoryIndex = "off"
+)
+
+// wantsDirectoryIndex reports whether a missing object could be a directory with an index.html.
+// Paths ending in a slash already had index.html appended, and files have an extension.
+func wantsDirectoryIndex(mode DirectoryIndex, objectPath string) bool {
+	if mode != DirectoryIndexRedirect && mode != DirectoryIndexRewrite {
+		return false
+	}
+	if objectPath == "" || strings.HasSuffix(objectPath, "/") {
+		return false
+	}
+	return path.Ext(objectPath) == ""
+}
+
+// serveDirectoryIndex replaces a response for a missing object with the index.html of the
+// directory at objectPath, or a redirect to the directory for DirectoryIndexRedirect, reporting
+// whether it did. requestPath is the path the client asked for, which the redirect adds a slash to.
+func serveDirectoryIndex(mode DirectoryIndex, transport http.RoundTripper, resp *http.Response, targetUrl *url.URL, objectPath string, requestPath string) bool {
+	indexName := strings.TrimPrefix(objectPath, "/") + "/index.html"
+	// Redirecting only needs to know that the index exists
+	method := resp.Request.Method
+	if mode == DirectoryIndexRedirect {
+		method = http.MethodHead
+	}
+	indexResp, err := fetchObject(transport, resp, targetUrl, method, indexName)
+	if err != nil {
+		slog.WarnContext(resp.Request.Context(), "Failed to fetch directory index", "target", targetUrl.String(), "object", indexName, "error", err)
+		return false
+	}
+	if indexResp.StatusCode != http.StatusOK {
+		indexResp.Body.Close()
+		return false
+	}
+	resp.Body.Close()
+
+	if mode == DirectoryIndexRedirect {
+		indexResp.Body.Close()
+		// Trimming the leading slashes keeps a path like //example.com from redirecting off-site
+		location := "/" + strings.TrimLeft(requestPath, "/") + "/"
+		if resp.Request.URL.RawQuery != "" {
+			location += "?" + resp.Request.URL.RawQuery
+		}
+		slog.DebugContext(resp.Request.Context(), "Redirecting to directory", "path", requestPath, "location", location)
+		resp.Status = "301 Moved Permanently"
+		resp.StatusCode = http.StatusMovedPermanently
+		resp.Header = http.Header{}
+		resp.Header.Set("Location", location)
+		resp.Body = http.NoBody
+		resp.ContentLength = 0
+		return true
+	}
+
+	slog.DebugContext(resp.Request.Context(), "Serving directory index", "path", requestPath, "object", indexName)
+	resp.Status = indexResp.Status
+	resp.StatusCode = indexResp.StatusCode
+	resp.Header = indexResp.Header
+	resp.Body = indexResp.Body
+	resp.ContentLength = indexResp.ContentLength
+	return true
+}
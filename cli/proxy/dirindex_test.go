@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDirectoryIndex(t *testing.T) {
+	store := &objectStore{objects: map[string]string{
+		"/__outputs/d1/index.html":      "home",
+		"/__outputs/d1/docs/index.html": "docs",
+		"/__outputs/d1/404.html":        "not found page",
+		// Object keys may have empty segments, so this one matches //evil.example/docs
+		"/__outputs/d1//evil.example/docs/index.html": "not a redirect off-site",
+	}}
+	server := httptest.NewServer(store)
+	t.Cleanup(server.Close)
+	handlers := map[DirectoryIndex]http.Handler{}
+	for _, mode := range []DirectoryIndex{DirectoryIndexRedirect, DirectoryIndexRewrite, DirectoryIndexOff} {
+		handlers[mode] = Handler(func(r *http.Request) (Target, error) {
+			return Target{DeploymentID: "d1", BasePath: server.URL + "/__outputs/d1/", PathPrefixes: []string{"d1"}}, nil
+		}, WithDirectoryIndex(mode))
+	}
+
+	tests := []struct {
+		mode         DirectoryIndex
+		path         string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		{DirectoryIndexRedirect, "/docs", http.StatusMovedPermanently, "", "/docs/"},
+		{DirectoryIndexRedirect, "/docs?tab=api", http.StatusMovedPermanently, "", "/docs/?tab=api"},
+		{DirectoryIndexRedirect, "/d1/docs", http.StatusMovedPermanently, "", "/d1/docs/"},
+		{DirectoryIndexRedirect, "//evil.example/docs", http.StatusMovedPermanently, "", "/evil.example/docs/"},
+		{DirectoryIndexRedirect, "/docs/", http.StatusOK, "docs", ""},
+		{DirectoryIndexRedirect, "/blog", http.StatusNotFound, "not found page", ""},
+		{DirectoryIndexRewrite, "/docs", http.StatusOK, "docs", ""},
+		{DirectoryIndexRewrite, "/docs/", http.StatusOK, "docs", ""},
+		{DirectoryIndexOff, "/docs", http.StatusNotFound, "not found page", ""},
+		{DirectoryIndexOff, "/docs/", http.StatusOK, "docs", ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja/", nil)
+			req.URL.Path, req.URL.RawQuery, _ = strings.Cut(tt.path, "?")
+			rec := httptest.NewRecorder()
+			handlers[tt.mode].ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rec.Body.String()) != tt.wantBody {
+				t.Errorf("GET %s body = %q, want %q", tt.path, rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("GET %s Location = %q, want %q", tt.path, got, tt.wantLocation)
+			}
+		})
+	}
+}
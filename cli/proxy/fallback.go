@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// wantsSPAFallback reports whether a request is a browser navigation to a page route, which
+// should get the deployment's index.html when no object exists for it. Requests for files,
+// recognised by their extension, must still get a real 404.
+func wantsSPAFallback(r *http.Request, urlPath string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if path.Ext(urlPath) != "" {
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// isMissingObject reports whether the object store answered that the object doesn't exist.
+// S3 answers 403 rather than 404 for missing objects when listing the bucket isn't allowed.
+func isMissingObject(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden
+}
+
+// fetchObject requests another object of the deployment with method, GET or HEAD, and the same
+// client headers as the request that got resp, sent with transport. The caller must close the
+// returned response's body.
+func fetchObject(transport http.RoundTripper, resp *http.Response, targetUrl *url.URL, method, name string) (*http.Response, error) {
+	objectReq := resp.Request.Clone(resp.Request.Context())
+	objectReq.Method = method
+	objectReq.URL.Path = strings.TrimSuffix(targetUrl.Path, "/") + "/" + name
+	objectReq.URL.RawPath = ""
+	objectReq.URL.RawQuery = ""
+	// A conditional request could be answered with a 304 for a page the client never cached
+	objectReq.Header.Del("If-None-Match")
+	objectReq.Header.Del("If-Modified-Since")
+	// The whole object is served in place of the missing one, so a range of it makes no sense
+	objectReq.Header.Del("Range")
+	objectReq.Header.Del("If-Range")
+
+	return transport.RoundTrip(objectReq)
+}
+
+// serveAlternatePath replaces a response for a missing object with the response for the object
+// at name, the other shape of the requested path, reporting whether that object exists. Unlike
+// fetchObject, the client's conditional and range headers are kept, since it is the object the
+// client asked for.
+func serveAlternatePath(transport http.RoundTripper, resp *http.Response, targetUrl *url.URL, name string) bool {
+	objectReq := resp.Request.Clone(resp.Request.Context())
+	objectReq.URL.Path = strings.TrimSuffix(targetUrl.Path, "/") + "/" + name
+	objectReq.URL.RawPath = ""
+	altResp, err := transport.RoundTrip(objectReq)
+	if err != nil {
+		slog.WarnContext(resp.Request.Context(), "Failed to fetch object", "target", targetUrl.String(), "object", name, "error", err)
+		return false
+	}
+	if isMissingObject(altResp) {
+		altResp.Body.Close()
+		return false
+	}
+
+	slog.DebugContext(resp.Request.Context(), "Serving alternate path", "path", resp.Request.URL.Path, "object", name)
+	resp.Body.Close()
+	resp.Status = altResp.Status
+	resp.StatusCode = altResp.StatusCode
+	resp.Header = altResp.Header
+	resp.Body = altResp.Body
+	resp.ContentLength = altResp.ContentLength
+	return true
+}
+
+// serveIndex replaces a response for a missing object with the deployment's index.html served
+// with a 200, reporting whether it did. The original response is kept if index.html can't be
+// fetched either. HEAD requests get its headers without fetching its body.
+func serveIndex(transport http.RoundTripper, resp *http.Response, targetUrl *url.URL) bool {
+	indexResp, err := fetchObject(transport, resp, targetUrl, resp.Request.Method, "index.html")
+	if err != nil {
+		slog.WarnContext(resp.Request.Context(), "Failed to fetch index.html for SPA fallback", "target", targetUrl.String(), "error", err)
+		return false
+	}
+	if indexResp.StatusCode != http.StatusOK {
+		indexResp.Body.Close()
+		return false
+	}
+
+	slog.DebugContext(resp.Request.Context(), "Serving index.html for SPA fallback", "path", resp.Request.URL.Path)
+	resp.Body.Close()
+	resp.Status = indexResp.Status
+	resp.StatusCode = indexResp.StatusCode
+	resp.Header = indexResp.Header
+	resp.Body = indexResp.Body
+	resp.ContentLength = indexResp.ContentLength
+	return true
+}
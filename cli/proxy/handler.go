@@ -0,0 +1,255 @@
+package proxy
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Target describes the deployment a request is served from
+type Target struct {
+	// DeploymentID identifies the deployment in logs
+	DeploymentID string
+	// BasePath is the base URL that the objects of the deployment live under
+	BasePath string
+	// PathPrefixes are leading path segments stripped from request paths, such as the
+	// deployment ID and the project slug
+	PathPrefixes []string
+	// SPAFallback serves the deployment's index.html for unknown page routes, so client-side
+	// routed apps can be refreshed on any route
+	SPAFallback bool
+}
+
+// TargetResolver returns the deployment serving a request, usually based on its host
+type TargetResolver func(r *http.Request) (Target, error)
+
+// ResolveError is returned by a TargetResolver to control the response sent to the client
+type ResolveError struct {
+	StatusCode int
+	Message    string
+	Err        error
+	// RetryAfter is sent as the Retry-After header if set, e.g. with a 429
+	RetryAfter time.Duration
+}
+
+func (e *ResolveError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// HandlerOption configures the handler returned by Handler
+type HandlerOption func(*handlerOptions)
+
+// handlerOptions holds the settings applied by HandlerOptions
+type handlerOptions struct {
+	assetCache     *AssetCache
+	transport      http.RoundTripper
+	directoryIndex DirectoryIndex
+}
+
+// WithAssetCache makes the handler serve repeated GET and HEAD requests from cache
+func WithAssetCache(cache *AssetCache) HandlerOption {
+	return func(o *handlerOptions) {
+		o.assetCache = cache
+	}
+}
+
+// WithTransport makes the handler send its requests to the object store with transport instead
+// of http.DefaultTransport, e.g. to instrument them
+func WithTransport(transport http.RoundTripper) HandlerOption {
+	return func(o *handlerOptions) {
+		o.transport = transport
+	}
+}
+
+// WithDirectoryIndex sets how extensionless paths that are directories of the deployment are
+// served. The default is DirectoryIndexRedirect.
+func WithDirectoryIndex(mode DirectoryIndex) HandlerOption {
+	return func(o *handlerOptions) {
+		o.directoryIndex = mode
+	}
+}
+
+// Handler returns an http.Handler that proxies each request to the deployment resolved for its host
+func Handler(resolveTarget TargetResolver, opts ...HandlerOption) http.Handler {
+	options := handlerOptions{transport: http.DefaultTransport, directoryIndex: DirectoryIndexRedirect}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	assetCache := options.assetCache
+	transport := options.transport
+	directoryIndex := options.directoryIndex
+	notFoundPages := newNotFoundPages()
+	shapes := newPathShapes()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rejectRequest(w, r) {
+			return
+		}
+
+		target, err := resolveTarget(r)
+		if err != nil {
+			var resolveErr *ResolveError
+			if errors.As(err, &resolveErr) {
+				if resolveErr.RetryAfter > 0 {
+					setRetryAfter(w, resolveErr.RetryAfter)
+				}
+				httpError(w, r, resolveErr.Message, resolveErr.StatusCode)
+				return
+			}
+			slog.ErrorContext(r.Context(), "Failed to resolve deployment", "host", r.Host, "error", err)
+			httpError(w, r, "Failed to resolve deployment", http.StatusInternalServerError)
+			return
+		}
+
+		resolvesTo := target.BasePath
+		slog.DebugContext(r.Context(), "Resolved deployment", "host", r.Host, "target", resolvesTo)
+		targetUrl, err := url.Parse(resolvesTo)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to parse target URL", "target", resolvesTo, "error", err)
+			httpError(w, r, "Failed to parse resolvesTo URL", http.StatusInternalServerError)
+			return
+		}
+
+		// Map the request path to the object path within the deployment. A leading segment
+		// matching one of the prefixes may also be a real directory of the deployment, so both
+		// shapes of the path are tried, starting with the one that last worked.
+		urlPath := r.URL.Path
+		strippedPath, prefix := stripPrefix(urlPath, target.PathPrefixes)
+		firstPath := indexPath(strippedPath)
+		alternatePath, shapeKey := "", ""
+		if prefix != "" {
+			shapeKey = resolvesTo + "\x00" + prefix
+			alternatePath = indexPath(urlPath)
+			if !shapes.prefersStripped(shapeKey) {
+				firstPath, alternatePath = alternatePath, firstPath
+			}
+		}
+		r.URL.Path = firstPath
+		r.URL.RawPath = ""
+		if r.URL.Path != urlPath {
+			slog.DebugContext(r.Context(), "Rewriting path", "from", urlPath, "to", r.URL.Path)
+		}
+
+		// Answer from the cache if possible; partial requests always go to the object store.
+		// Which object a path maps to depends on whether its first segment may be stripped. The
+		// query doesn't pick the object, so cache busters like ?v=2 share the path's entry.
+		cacheKey := ""
+		if assetCache != nil && (r.Method == http.MethodGet || r.Method == http.MethodHead) && r.Header.Get("Range") == "" {
+			cacheKey = resolvesTo + strings.TrimPrefix(indexPath(urlPath), "/")
+			if prefix != "" {
+				cacheKey += "#" + prefix
+			}
+			if assetCache.serve(w, r, cacheKey) {
+				return
+			}
+		}
+
+		// Create a reverse proxy to the target URL. Hop-by-hop headers, including those named in
+		// Connection, are dropped from the request and the response by httputil.ReverseProxy.
+		reverseProxy := &httputil.ReverseProxy{
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				pr.SetURL(targetUrl)
+				pr.Out.Host = targetUrl.Host
+				setForwardedHeaders(pr)
+			},
+			Transport: transport,
+		}
+		reverseProxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.ErrorContext(r.Context(), "Failed to fetch from the object store", "target", resolvesTo, "error", err)
+			httpError(w, r, "Failed to fetch from the object store", http.StatusBadGateway)
+		}
+
+		// Serve the index.html of directories requested without a trailing slash, index.html
+		// for page routes the deployment has no object for, and the deployment's 404 page for
+		// anything else that is missing
+		fallbackPath := indexPath(strippedPath)
+		dirIndex := wantsDirectoryIndex(directoryIndex, fallbackPath)
+		spaFallback := target.SPAFallback && wantsSPAFallback(r, urlPath)
+		reverseProxy.ModifyResponse = func(resp *http.Response) error {
+			objectPath := resp.Request.URL.Path
+			if alternatePath != "" {
+				switch {
+				case !isMissingObject(resp):
+					shapes.remember(shapeKey, firstPath == fallbackPath)
+				case serveAlternatePath(transport, resp, targetUrl, strings.TrimPrefix(alternatePath, "/")):
+					shapes.remember(shapeKey, alternatePath == fallbackPath)
+					objectPath = alternatePath
+				}
+			}
+			if isMissingObject(resp) {
+				switch {
+				case dirIndex && serveDirectoryIndex(directoryIndex, transport, resp, targetUrl, fallbackPath, urlPath):
+					if resp.StatusCode != http.StatusOK {
+						return nil
+					}
+				case spaFallback && serveIndex(transport, resp, targetUrl):
+				default:
+					serveNotFoundPage(transport, resp, targetUrl, notFoundPages)
+					return nil
+				}
+				objectPath = "index.html"
+			}
+			fixContentType(resp, objectPath)
+			if cacheKey != "" {
+				assetCache.store(resp, cacheKey)
+			}
+			return nil
+		}
+		reverseProxy.ServeHTTP(w, r)
+	})
+}
+
+// setForwardedHeaders sets the X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto headers
+// of a request sent upstream. The forwarded headers the client's request arrived with are only
+// kept when it went through RealIP, which drops them unless a trusted proxy sent them.
+func setForwardedHeaders(pr *httputil.ProxyRequest) {
+	vetted := ClientIPFrom(pr.In.Context()).IsValid()
+	if vetted {
+		pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+	}
+	pr.SetXForwarded()
+	if !vetted {
+		return
+	}
+	if host := pr.In.Header.Get("X-Forwarded-Host"); host != "" {
+		pr.Out.Header.Set("X-Forwarded-Host", host)
+	}
+	if proto := pr.In.Header.Get("X-Forwarded-Proto"); proto != "" {
+		pr.Out.Header.Set("X-Forwarded-Proto", proto)
+	}
+}
+
+// rejectRequest answers requests that a static site can't serve, reporting whether it did.
+// Only GET and HEAD requests without a body are proxied.
+func rejectRequest(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		httpError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return true
+	}
+	if r.ContentLength != 0 || len(r.TransferEncoding) > 0 {
+		httpError(w, r, "Request bodies are not accepted", http.StatusRequestEntityTooLarge)
+		return true
+	}
+	return false
+}
+
+// httpError replies to a request with an error message, followed by the request's ID so users
+// can include it when reporting the error
+func httpError(w http.ResponseWriter, r *http.Request, message string, code int) {
+	if id := RequestIDFrom(r.Context()); id != "" {
+		message += "\nRequest ID: " + id
+	}
+	http.Error(w, message, code)
+}
@@ -0,0 +1,340 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// objectStore serves objects from a map of paths to contents like S3 does, with range and
+// conditional requests, counting requests and recording their methods
+type objectStore struct {
+	objects  map[string]string
+	requests atomic.Int32
+
+	mu      sync.Mutex
+	methods []string
+}
+
+func (s *objectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	s.mu.Lock()
+	s.methods = append(s.methods, r.Method)
+	s.mu.Unlock()
+
+	content, ok := s.objects[r.URL.Path]
+	if !ok {
+		http.Error(w, "NoSuchKey", http.StatusNotFound)
+		return
+	}
+	http.ServeContent(w, r, r.URL.Path, time.Time{}, strings.NewReader(content))
+}
+
+// takeMethods returns the methods of the requests received since the last call
+func (s *objectStore) takeMethods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	methods := s.methods
+	s.methods = nil
+	return methods
+}
+
+// newTestHandler returns a Handler serving the deployment d1 of project brave-fox from objects,
+// with the target changed by configure if given
+func newTestHandler(t *testing.T, objects map[string]string, configure ...func(*Target)) (http.Handler, *objectStore) {
+	store := &objectStore{objects: objects}
+	server := httptest.NewServer(store)
+	t.Cleanup(server.Close)
+
+	handler := Handler(func(r *http.Request) (Target, error) {
+		target := Target{
+			DeploymentID: "d1",
+			BasePath:     server.URL + "/__outputs/d1/",
+			PathPrefixes: []string{"brave-fox", "d1"},
+		}
+		for _, fn := range configure {
+			fn(&target)
+		}
+		return target, nil
+	})
+	return handler, store
+}
+
+func get(handler http.Handler, urlPath string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja"+urlPath, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerPaths(t *testing.T) {
+	handler, _ := newTestHandler(t, map[string]string{
+		"/__outputs/d1/index.html":                "home",
+		"/__outputs/d1/404.html":                  "not found page",
+		"/__outputs/d1/static/js/vendor/chunk.js": "chunk",
+		"/__outputs/d1/fonts/a.woff2":             "font",
+		"/__outputs/d1/blog/post.html":            "post",
+		"/__outputs/d1/docs/index.html":           "docs",
+		"/__outputs/d1/brave-fox/index.html":      "brave-fox folder",
+		"/__outputs/d1/brave-fox/team/index.html": "team",
+		"/__outputs/d1/brave-fox/about.html":      "about in folder",
+		"/__outputs/d1/about.html":                "about at root",
+	})
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/", http.StatusOK, "home"},
+		{"/static/js/vendor/chunk.js", http.StatusOK, "chunk"},
+		{"/fonts/a.woff2", http.StatusOK, "font"},
+		{"/blog/post.html", http.StatusOK, "post"},
+		{"/docs/", http.StatusOK, "docs"},
+		{"/docs", http.StatusMovedPermanently, ""},
+		// A real directory named like the slug is served as it is
+		{"/brave-fox/", http.StatusOK, "brave-fox folder"},
+		{"/brave-fox/team/", http.StatusOK, "team"},
+		{"/brave-fox/about.html", http.StatusOK, "about in folder"},
+		// Paths that only exist without the prefix have it stripped
+		{"/d1/", http.StatusOK, "home"},
+		{"/d1/static/js/vendor/chunk.js", http.StatusOK, "chunk"},
+		{"/brave-fox/fonts/a.woff2", http.StatusOK, "font"},
+		{"/d1/about.html", http.StatusOK, "about at root"},
+		// Missing files get the deployment's 404 page
+		{"/missing.js", http.StatusNotFound, "not found page"},
+		{"/d1/missing.js", http.StatusNotFound, "not found page"},
+		{"/brave-fox/missing.html", http.StatusNotFound, "not found page"},
+		{"/static/js/missing.js", http.StatusNotFound, "not found page"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := get(handler, tt.path)
+			if rec.Code != tt.wantCode {
+				t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rec.Body.String()) != tt.wantBody {
+				t.Errorf("GET %s body = %q, want %q", tt.path, rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHandlerRemembersPathShape(t *testing.T) {
+	handler, store := newTestHandler(t, map[string]string{
+		"/__outputs/d1/index.html":       "home",
+		"/__outputs/d1/assets/app.js":    "app",
+		"/__outputs/d1/assets/app.css":   "css",
+		"/__outputs/d1/brave-fox/a.html": "a",
+		"/__outputs/d1/brave-fox/b.html": "b",
+	})
+
+	requests := func(urlPath string) int32 {
+		t.Helper()
+		before := store.requests.Load()
+		if rec := get(handler, urlPath); rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d", urlPath, rec.Code)
+		}
+		return store.requests.Load() - before
+	}
+
+	// The path is tried as requested, then with the deployment ID stripped, which is then
+	// tried first
+	if got := requests("/d1/assets/app.js"); got != 2 {
+		t.Errorf("first request took %d round trips, want 2", got)
+	}
+	if got := requests("/d1/assets/app.css"); got != 1 {
+		t.Errorf("second request took %d round trips, want 1", got)
+	}
+
+	// Prefixes are remembered separately
+	if got := requests("/brave-fox/a.html"); got != 1 {
+		t.Errorf("request for a real directory took %d round trips, want 1", got)
+	}
+	if got := requests("/brave-fox/b.html"); got != 1 {
+		t.Errorf("second request for a real directory took %d round trips, want 1", got)
+	}
+}
+
+func TestHandlerHeadAndRange(t *testing.T) {
+	handler, store := newTestHandler(t, map[string]string{
+		"/__outputs/d1/index.html":      "home page",
+		"/__outputs/d1/404.html":        "not found page",
+		"/__outputs/d1/docs/index.html": "docs",
+		"/__outputs/d1/assets/app.js":   "console.log('app')",
+	}, func(target *Target) { target.SPAFallback = true })
+	// Served for real, so HEAD responses lose their body as they would in production
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		header      http.Header
+		wantCode    int
+		wantBody    string
+		wantMethods string
+	}{
+		{"HEAD of an object", http.MethodHead, "/assets/app.js", nil, http.StatusOK, "", "HEAD"},
+		// The object, then the directory index, then the SPA's index.html
+		{"HEAD of a page route", http.MethodHead, "/pricing", http.Header{"Accept": {"text/html"}}, http.StatusOK, "", "HEAD HEAD HEAD"},
+		{"HEAD of a directory", http.MethodHead, "/docs/", nil, http.StatusOK, "", "HEAD"},
+		// The 404 page is fetched whole, since it is cached for GET requests too
+		{"HEAD of a missing file", http.MethodHead, "/missing.js", nil, http.StatusNotFound, "", "HEAD GET"},
+		{"range", http.MethodGet, "/assets/app.js", http.Header{"Range": {"bytes=0-6"}}, http.StatusPartialContent, "console", "GET"},
+		{"range through a prefix", http.MethodGet, "/d1/assets/app.js", http.Header{"Range": {"bytes=8-10"}}, http.StatusPartialContent, "log", "GET GET"},
+		// Fallbacks serve a whole other object, so the range is dropped
+		{"range of a page route", http.MethodGet, "/pricing", http.Header{"Accept": {"text/html"}, "Range": {"bytes=0-3"}}, http.StatusOK, "home page", "GET HEAD GET"},
+		{"range of a missing file", http.MethodGet, "/missing.js", http.Header{"Range": {"bytes=0-3"}}, http.StatusNotFound, "not found page", "GET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Host = "brave-fox.yok.ninja"
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			store.takeMethods()
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.wantCode)
+			}
+			if got := strings.TrimSpace(string(body)); got != tt.wantBody {
+				t.Errorf("%s %s body = %q, want %q", tt.method, tt.path, got, tt.wantBody)
+			}
+			if got := strings.Join(store.takeMethods(), " "); got != tt.wantMethods {
+				t.Errorf("%s %s sent %q to the object store, want %q", tt.method, tt.path, got, tt.wantMethods)
+			}
+		})
+	}
+}
+
+func TestHandlerSPAFallback(t *testing.T) {
+	objects := map[string]string{
+		"/__outputs/d1/index.html":       "app shell",
+		"/__outputs/d1/404.html":         "not found page",
+		"/__outputs/d1/app.js":           "app",
+		"/__outputs/d1/about/index.html": "about",
+	}
+	spa, _ := newTestHandler(t, objects, func(target *Target) { target.SPAFallback = true })
+	static, _ := newTestHandler(t, objects)
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		method   string
+		path     string
+		accept   string
+		wantCode int
+		wantBody string
+	}{
+		{"page route", spa, http.MethodGet, "/dashboard/settings", "text/html,application/xhtml+xml", http.StatusOK, "app shell"},
+		{"page route through a prefix", spa, http.MethodGet, "/d1/dashboard", "text/html", http.StatusOK, "app shell"},
+		{"existing object", spa, http.MethodGet, "/app.js", "*/*", http.StatusOK, "app"},
+		{"directory index wins", spa, http.MethodGet, "/about/", "text/html", http.StatusOK, "about"},
+		{"missing file", spa, http.MethodGet, "/missing.js", "text/html", http.StatusNotFound, "not found page"},
+		{"not a navigation", spa, http.MethodGet, "/api/data", "application/json", http.StatusNotFound, "not found page"},
+		{"turned off", static, http.MethodGet, "/dashboard", "text/html", http.StatusNotFound, "not found page"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://brave-fox.yok.ninja"+tt.path, nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.wantCode)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("%s %s body = %q, want %q", tt.method, tt.path, got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHandlerNotFoundPage(t *testing.T) {
+	custom, store := newTestHandler(t, map[string]string{
+		"/__outputs/d1/index.html": "home",
+		"/__outputs/d1/404.html":   "<h1>Lost?</h1>",
+	})
+
+	rec := get(custom, "/missing.png")
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "<h1>Lost?</h1>" {
+		t.Errorf("GET /missing.png = %d %q, want the deployment's 404.html", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/html", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", got)
+	}
+
+	// The 404.html lookup is reused
+	before := store.requests.Load()
+	get(custom, "/other-missing.png")
+	if got := store.requests.Load() - before; got != 1 {
+		t.Errorf("second missing object took %d round trips, want 1", got)
+	}
+
+	// Deployments without one get the default page, with the request's ID
+	plain, _ := newTestHandler(t, map[string]string{"/__outputs/d1/index.html": "home"})
+	req := httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja/missing.png", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	rec = httptest.NewRecorder()
+	RequestID(plain).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /missing.png = %d, want 404", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "404 - Page not found") || !strings.Contains(body, "Request ID: req-123") {
+		t.Errorf("body = %q, want the default page with the request ID", body)
+	}
+}
+
+func TestHandlerKeepsQuery(t *testing.T) {
+	var gotPath, gotQuery string
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		w.Write([]byte("post"))
+	}))
+	t.Cleanup(bucket.Close)
+	handler := Handler(func(r *http.Request) (Target, error) {
+		return Target{DeploymentID: "d1", BasePath: bucket.URL + "/__outputs/d1/", PathPrefixes: []string{"brave-fox", "d1"}}, nil
+	})
+
+	tests := []struct {
+		url       string
+		wantPath  string
+		wantQuery string
+	}{
+		{"/about?ref=home", "/__outputs/d1/about", "ref=home"},
+		{"/blog/post?page=2&sort=new", "/__outputs/d1/blog/post", "page=2&sort=new"},
+		// Tried as requested first, which this bucket has an object for
+		{"/d1/blog/post?page=2", "/__outputs/d1/d1/blog/post", "page=2"},
+		{"/assets/app.js?v=3", "/__outputs/d1/assets/app.js", "v=3"},
+		{"/?utm_source=x", "/__outputs/d1/index.html", "utm_source=x"},
+	}
+	for _, tt := range tests {
+		if rec := get(handler, tt.url); rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d", tt.url, rec.Code)
+		}
+		if gotPath != tt.wantPath || gotQuery != tt.wantQuery {
+			t.Errorf("GET %s fetched %s?%s, want %s?%s", tt.url, gotPath, gotQuery, tt.wantPath, tt.wantQuery)
+		}
+	}
+}
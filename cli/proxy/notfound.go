@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// notFoundPageTTL is how long the lookup of a deployment's 404.html is reused, so missing
+// objects don't double the requests sent to the object store
+const notFoundPageTTL = 5 * time.Minute
+
+// maxNotFoundPageSize is the largest 404.html that is served and cached
+const maxNotFoundPageSize = 1 << 20
+
+// defaultNotFoundPage is served for missing objects of deployments without a 404.html, showing
+// the request's ID if it has one
+var defaultNotFoundPage = template.Must(template.New("404").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>404 - Page not found</title>
+<style>
+body { font-family: system-ui, sans-serif; display: flex; align-items: center; justify-content: center; min-height: 100vh; margin: 0; color: #222; }
+main { text-align: center; }
+h1 { font-size: 4rem; margin: 0; }
+p { color: #666; }
+</style>
+</head>
+<body>
+<main>
+<h1>404</h1>
+<p>The page you're looking for doesn't exist.</p>
+<p><small>Served by Yok{{if .}} &middot; Request ID: {{.}}{{end}}</small></p>
+</main>
+</body>
+</html>
+`))
+
+// notFoundPage is a looked up 404.html; a nil body means the deployment doesn't have one
+type notFoundPage struct {
+	body      []byte
+	checkedAt time.Time
+}
+
+// notFoundPages caches the 404.html of each deployment by its URL
+type notFoundPages struct {
+	mu    sync.Mutex
+	pages map[string]notFoundPage
+}
+
+func newNotFoundPages() *notFoundPages {
+	return &notFoundPages{pages: make(map[string]notFoundPage)}
+}
+
+// get returns the deployment's 404.html, fetching it unless a recent lookup is cached. It
+// returns nil if the deployment doesn't have one.
+func (p *notFoundPages) get(transport http.RoundTripper, resp *http.Response, targetUrl *url.URL) []byte {
+	key := targetUrl.String()
+
+	p.mu.Lock()
+	page, ok := p.pages[key]
+	p.mu.Unlock()
+	if ok && time.Since(page.checkedAt) < notFoundPageTTL {
+		return page.body
+	}
+
+	body := fetchNotFoundPage(transport, resp, targetUrl)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Drop expired lookups so deployments that are no longer visited don't pile up
+	for k, cached := range p.pages {
+		if time.Since(cached.checkedAt) >= notFoundPageTTL {
+			delete(p.pages, k)
+		}
+	}
+	p.pages[key] = notFoundPage{body: body, checkedAt: time.Now()}
+	return body
+}
+
+// fetchNotFoundPage fetches the deployment's 404.html, returning nil if it doesn't have one
+func fetchNotFoundPage(transport http.RoundTripper, resp *http.Response, targetUrl *url.URL) []byte {
+	pageResp, err := fetchObject(transport, resp, targetUrl, http.MethodGet, "404.html")
+	if err != nil {
+		slog.WarnContext(resp.Request.Context(), "Failed to fetch 404.html", "target", targetUrl.String(), "error", err)
+		return nil
+	}
+	defer pageResp.Body.Close()
+
+	if pageResp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(pageResp.Body, maxNotFoundPageSize+1))
+	if err != nil || len(body) > maxNotFoundPageSize {
+		slog.WarnContext(resp.Request.Context(), "Not serving 404.html: too large or unreadable", "target", targetUrl.String())
+		return nil
+	}
+	return body
+}
+
+// serveNotFoundPage replaces a response for a missing object with the deployment's 404.html, or
+// the default page if it doesn't have one, served with a 404
+func serveNotFoundPage(transport http.RoundTripper, resp *http.Response, targetUrl *url.URL, pages *notFoundPages) {
+	body := pages.get(transport, resp, targetUrl)
+	if body == nil {
+		var page bytes.Buffer
+		defaultNotFoundPage.Execute(&page, RequestIDFrom(resp.Request.Context()))
+		body = page.Bytes()
+	}
+
+	resp.Body.Close()
+	resp.Status = "404 Not Found"
+	resp.StatusCode = http.StatusNotFound
+	resp.Header = http.Header{}
+	resp.Header.Set("Content-Type", "text/html; charset=utf-8")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Header.Set("Cache-Control", "no-cache")
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+}
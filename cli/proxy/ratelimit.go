@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimiterSweepInterval is how often a RateLimiter drops the buckets of idle clients
+const rateLimiterSweepInterval = time.Minute
+
+// RateLimiter is a token bucket per client: each client may make burst requests at once, and
+// rate more every second after that. Buckets that refilled completely are dropped once in a
+// while, since a full bucket is the same as none, so memory stays bounded by the clients active
+// within the time a bucket takes to refill.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+
+	limited atomic.Uint64
+}
+
+// RateLimiterStats are the counters of a RateLimiter
+type RateLimiterStats struct {
+	Limited uint64 // Requests refused for exceeding the limit
+	Clients int    // Clients with a bucket that isn't full
+}
+
+// tokenBucket holds the tokens a client had left at its last request
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter allowing rate requests per second per client, in bursts of
+// up to burst requests. A rate of 0 disables it, and a burst below 1 is raised to 1.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:      rate,
+		burst:     math.Max(float64(burst), 1),
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket of client, reporting whether there was one and, if not,
+// how long until there is. A nil or disabled RateLimiter allows everything.
+func (l *RateLimiter) Allow(client string) (bool, time.Duration) {
+	if l == nil || l.rate <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimiterSweepInterval {
+		l.sweep(now)
+	}
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	} else {
+		bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+		bucket.last = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	l.limited.Add(1)
+	return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep drops the buckets that have refilled completely by now; the caller must hold l.mu
+func (l *RateLimiter) sweep(now time.Time) {
+	l.lastSweep = now
+	for client, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// Stats returns the limiter's counters
+func (l *RateLimiter) Stats() RateLimiterStats {
+	l.mu.Lock()
+	clients := len(l.buckets)
+	l.mu.Unlock()
+	return RateLimiterStats{Limited: l.limited.Load(), Clients: clients}
+}
+
+// RateLimit refuses the requests of clients exceeding limiter with a 429. client returns the key
+// a request is limited by, or an empty string to let it through unlimited.
+func RateLimit(limiter *RateLimiter, client func(*http.Request) string, next http.Handler) http.Handler {
+	if limiter == nil || limiter.rate <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := client(r); key != "" {
+			if ok, retryAfter := limiter.Allow(key); !ok {
+				TooManyRequests(w, r, retryAfter)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TooManyRequests responds with a 429 telling the client to retry after retryAfter, rounded up
+// to whole seconds as Retry-After requires
+func TooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	setRetryAfter(w, retryAfter)
+	httpError(w, r, "Too many requests", http.StatusTooManyRequests)
+}
+
+// setRetryAfter sets the Retry-After header to retryAfter in whole seconds, at least 1
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := NewRateLimiter(2, 3)
+
+	for i := range 3 {
+		if ok, _ := limiter.Allow("192.0.2.1"); !ok {
+			t.Fatalf("request %d of the burst was refused", i+1)
+		}
+	}
+	ok, retryAfter := limiter.Allow("192.0.2.1")
+	if ok {
+		t.Fatal("request past the burst was allowed")
+	}
+	if retryAfter <= 0 || retryAfter > 500*time.Millisecond {
+		t.Errorf("retryAfter = %v, want at most the 500ms a token takes at 2 per second", retryAfter)
+	}
+
+	// Other clients have their own bucket
+	if ok, _ := limiter.Allow("192.0.2.2"); !ok {
+		t.Error("another client was refused")
+	}
+
+	// Tokens come back at the rate
+	limiter.mu.Lock()
+	limiter.buckets["192.0.2.1"].last = time.Now().Add(-time.Second)
+	limiter.mu.Unlock()
+	for i := range 2 {
+		if ok, _ := limiter.Allow("192.0.2.1"); !ok {
+			t.Fatalf("refilled request %d was refused", i+1)
+		}
+	}
+	if ok, _ := limiter.Allow("192.0.2.1"); ok {
+		t.Error("allowed more requests than refilled in a second")
+	}
+
+	if stats := limiter.Stats(); stats.Limited != 2 || stats.Clients != 2 {
+		t.Errorf("stats = %+v, want 2 limited and 2 clients", stats)
+	}
+
+	// Buckets that refilled are dropped by the next sweep
+	limiter.mu.Lock()
+	limiter.buckets["192.0.2.2"].last = time.Now().Add(-time.Minute)
+	limiter.lastSweep = time.Now().Add(-rateLimiterSweepInterval)
+	limiter.mu.Unlock()
+	limiter.Allow("192.0.2.1")
+	if stats := limiter.Stats(); stats.Clients != 1 {
+		t.Errorf("Clients after a sweep = %d, want 1", stats.Clients)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	for name, limiter := range map[string]*RateLimiter{"nil": nil, "zero rate": NewRateLimiter(0, 1)} {
+		for range 10 {
+			if ok, _ := limiter.Allow("192.0.2.1"); !ok {
+				t.Errorf("%s limiter refused a request", name)
+				break
+			}
+		}
+	}
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := RateLimit(NewRateLimiter(0.1, 1), func(r *http.Request) string {
+		return r.Header.Get("X-Client")
+	}, next)
+
+	serve := func(client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja/", nil)
+		if client != "" {
+			req.Header.Set("X-Client", client)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("192.0.2.1"); rec.Code != http.StatusNoContent {
+		t.Errorf("first request = %d, want 204", rec.Code)
+	}
+	rec := serve("192.0.2.1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("second request = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Retry-After = %q, want 10", got)
+	}
+	// Requests without a client key aren't limited
+	for range 3 {
+		if rec := serve(""); rec.Code != http.StatusNoContent {
+			t.Errorf("unlimited request = %d, want 204", rec.Code)
+		}
+	}
+}
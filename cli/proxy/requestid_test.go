@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var upstreamID, contextID string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamID = r.Header.Get(RequestIDHeader)
+		contextID = RequestIDFrom(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		reused   bool
+	}{
+		{"none", "", false},
+		{"valid", "lb-7f3a:01.2_x", true},
+		{"invalid characters", "id with spaces<script>", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			if tt.reused && id != tt.incoming {
+				t.Errorf("ID = %q, want the incoming %q", id, tt.incoming)
+			}
+			if !tt.reused && (id == tt.incoming || len(id) != 32) {
+				t.Errorf("ID = %q, want a new 32 character ID", id)
+			}
+			if upstreamID != id || contextID != id {
+				t.Errorf("upstream got %q and the context %q, want the response's %q", upstreamID, contextID, id)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorIncludesRequestID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja/", nil)
+	req = req.WithContext(ContextWithRequestID(req.Context(), "req-123"))
+	rec := httptest.NewRecorder()
+	httpError(rec, req, "Deployment not found", http.StatusNotFound)
+
+	if got, want := rec.Body.String(), "Deployment not found\nRequest ID: req-123\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
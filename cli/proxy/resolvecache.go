@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResolveCache caches the deployment resolved for each key, such as a project slug, so serving
+// a request doesn't need a call to the API server every time. Entries older than the TTL are
+// still served while they are refreshed in the background, and concurrent misses for the same
+// key share a single call to resolve. Keys that resolve to no deployment are remembered for a
+// shorter negative TTL, so requests for unknown keys don't all reach the API server.
+type ResolveCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	resolve     func(ctx context.Context, key string) (Target, error)
+
+	mu       sync.Mutex
+	entries  map[string]*resolveEntry
+	missing  map[string]*missingEntry
+	inflight map[string]*resolveCall
+
+	hits         atomic.Uint64
+	misses       atomic.Uint64
+	negativeHits atomic.Uint64
+}
+
+// maxMissingEntries bounds the keys remembered as missing, since anyone can make up new ones
+const maxMissingEntries = 10000
+
+// ResolveCacheStats are the counters of a ResolveCache
+type ResolveCacheStats struct {
+	Hits         uint64 // Served from the cache, including stale entries
+	Misses       uint64 // Resolved while the caller waited, including bypasses
+	NegativeHits uint64 // Answered with a cached "no deployment" error
+}
+
+// resolveEntry is a cached resolution
+type resolveEntry struct {
+	target     Target
+	resolvedAt time.Time
+}
+
+// missingEntry remembers that a key resolved to no deployment
+type missingEntry struct {
+	err       error
+	expiresAt time.Time
+}
+
+// resolveCall is a resolution in progress that callers for the same key wait on
+type resolveCall struct {
+	done   chan struct{}
+	target Target
+	err    error
+}
+
+// NewResolveCache returns a cache that resolves keys with resolve and refreshes them after ttl.
+// Keys resolve fails for with a 404 ResolveError are answered with that error for negativeTTL,
+// or resolved again every time if negativeTTL is 0. resolve gets the context of the request that
+// started the resolution, without its cancellation, since other requests may share the result.
+func NewResolveCache(ttl time.Duration, negativeTTL time.Duration, resolve func(ctx context.Context, key string) (Target, error)) *ResolveCache {
+	return &ResolveCache{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		resolve:     resolve,
+		entries:     make(map[string]*resolveEntry),
+		missing:     make(map[string]*missingEntry),
+		inflight:    make(map[string]*resolveCall),
+	}
+}
+
+// Stats returns the cache's counters
+func (c *ResolveCache) Stats() ResolveCacheStats {
+	return ResolveCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), NegativeHits: c.negativeHits.Load()}
+}
+
+// Purge forgets what key resolved to, so the next request resolves it again. It is used after
+// a project is created, so its slug isn't answered as missing until the negative TTL runs out.
+func (c *ResolveCache) Purge(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+	delete(c.missing, key)
+}
+
+// Cached reports whether Resolve would answer for key from the cache, with a deployment or
+// with a remembered 404, rather than wait for the API server
+func (c *ResolveCache) Cached(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		return true
+	}
+	missing, ok := c.missing[key]
+	return ok && !time.Now().After(missing.expiresAt)
+}
+
+// Resolve returns the deployment for key, from the cache unless bypass is set. Failed
+// resolutions aren't cached.
+func (c *ResolveCache) Resolve(ctx context.Context, key string, bypass bool) (Target, error) {
+	if bypass {
+		c.misses.Add(1)
+		return c.resolveShared(ctx, key)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	missing, isMissing := c.missing[key]
+	if isMissing && time.Now().After(missing.expiresAt) {
+		delete(c.missing, key)
+		isMissing = false
+	}
+	c.mu.Unlock()
+
+	if !ok && isMissing {
+		c.negativeHits.Add(1)
+		return Target{}, missing.err
+	}
+	if !ok {
+		c.misses.Add(1)
+		return c.resolveShared(ctx, key)
+	}
+	c.hits.Add(1)
+
+	// Serve the stale entry while it is refreshed
+	if time.Since(entry.resolvedAt) >= c.ttl {
+		c.refreshInBackground(ctx, key)
+	}
+	return entry.target, nil
+}
+
+// resolveShared resolves key and caches the result, sharing the call with any other caller
+// resolving the same key at the same time
+func (c *ResolveCache) resolveShared(ctx context.Context, key string) (Target, error) {
+	call := c.startResolve(ctx, key)
+	<-call.done
+	return call.target, call.err
+}
+
+// refreshInBackground resolves key again without waiting for it, unless it is already being
+// resolved. Until it finishes, or if it fails, the stale entry keeps being served; if it finds the
+// key no longer resolves to a deployment, the entry is dropped.
+func (c *ResolveCache) refreshInBackground(ctx context.Context, key string) {
+	c.startResolve(ctx, key)
+}
+
+// startResolve returns the resolution of key in progress, starting one if there is none
+func (c *ResolveCache) startResolve(ctx context.Context, key string) *resolveCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if call, ok := c.inflight[key]; ok {
+		return call
+	}
+
+	call := &resolveCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	go c.finishResolve(context.WithoutCancel(ctx), key, call)
+	return call
+}
+
+// finishResolve runs the resolution of key and caches its result if it succeeded, or forgets
+// what key resolved to if it now resolves to no deployment
+func (c *ResolveCache) finishResolve(ctx context.Context, key string, call *resolveCall) {
+	call.target, call.err = c.resolve(ctx, key)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.err == nil {
+		c.entries[key] = &resolveEntry{target: call.target, resolvedAt: time.Now()}
+		delete(c.missing, key)
+	} else if isNotFound(call.err) {
+		// The project or its deployment is gone, so a stale entry mustn't keep being served
+		delete(c.entries, key)
+		if c.negativeTTL > 0 {
+			c.rememberMissing(key, call.err)
+		}
+	}
+	c.mu.Unlock()
+
+	close(call.done)
+}
+
+// rememberMissing caches that key resolved to no deployment, unless too many keys are already
+// remembered as missing; the caller must hold c.mu
+func (c *ResolveCache) rememberMissing(key string, err error) {
+	if len(c.missing) >= maxMissingEntries {
+		now := time.Now()
+		for missingKey, entry := range c.missing {
+			if now.After(entry.expiresAt) {
+				delete(c.missing, missingKey)
+			}
+		}
+		if len(c.missing) >= maxMissingEntries {
+			return
+		}
+	}
+	c.missing[key] = &missingEntry{err: err, expiresAt: time.Now().Add(c.negativeTTL)}
+}
+
+// isNotFound reports whether err means the key resolves to no deployment
+func isNotFound(err error) bool {
+	var resolveErr *ResolveError
+	return errors.As(err, &resolveErr) && resolveErr.StatusCode == http.StatusNotFound
+}
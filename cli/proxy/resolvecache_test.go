@@ -0,0 +1,213 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingResolver resolves every key to a deployment named after the number of the call,
+// or fails with err if it is set
+type countingResolver struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (r *countingResolver) resolve(ctx context.Context, key string) (Target, error) {
+	call := r.calls.Add(1)
+	if r.err != nil {
+		return Target{}, r.err
+	}
+	return Target{DeploymentID: key + "-" + string(rune('0'+call))}, nil
+}
+
+// waitFor polls cond until it holds, failing the test if it doesn't within a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestResolveCacheHit(t *testing.T) {
+	resolver := &countingResolver{}
+	cache := NewResolveCache(time.Hour, time.Hour, resolver.resolve)
+	ctx := context.Background()
+
+	for range 3 {
+		target, err := cache.Resolve(ctx, "brave-fox", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target.DeploymentID != "brave-fox-1" {
+			t.Errorf("DeploymentID = %q, want brave-fox-1", target.DeploymentID)
+		}
+	}
+	if got := resolver.calls.Load(); got != 1 {
+		t.Errorf("resolved %d times, want once", got)
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, want 2 hits and 1 miss", stats)
+	}
+
+	// A bypass resolves again and caches the result
+	target, _ := cache.Resolve(ctx, "brave-fox", true)
+	if target.DeploymentID != "brave-fox-2" {
+		t.Errorf("bypass DeploymentID = %q, want brave-fox-2", target.DeploymentID)
+	}
+	if target, _ := cache.Resolve(ctx, "brave-fox", false); target.DeploymentID != "brave-fox-2" {
+		t.Errorf("DeploymentID after bypass = %q, want brave-fox-2", target.DeploymentID)
+	}
+
+	cache.Purge("brave-fox")
+	if cache.Cached("brave-fox") {
+		t.Error("purged key is still cached")
+	}
+	if target, _ := cache.Resolve(ctx, "brave-fox", false); target.DeploymentID != "brave-fox-3" {
+		t.Errorf("DeploymentID after purge = %q, want brave-fox-3", target.DeploymentID)
+	}
+}
+
+func TestResolveCacheServesStaleWhileRefreshing(t *testing.T) {
+	resolver := &countingResolver{}
+	cache := NewResolveCache(time.Nanosecond, time.Hour, resolver.resolve)
+	ctx := context.Background()
+
+	cache.Resolve(ctx, "brave-fox", false)
+	target, err := cache.Resolve(ctx, "brave-fox", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.DeploymentID != "brave-fox-1" {
+		t.Errorf("DeploymentID = %q, want the stale brave-fox-1", target.DeploymentID)
+	}
+	waitFor(t, "the background refresh", func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		entry := cache.entries["brave-fox"]
+		return entry != nil && entry.target.DeploymentID == "brave-fox-2"
+	})
+
+	// A failed refresh keeps the stale entry
+	refreshDone := func(calls int32) {
+		waitFor(t, "the failed refresh", func() bool { return resolver.calls.Load() == calls })
+		waitFor(t, "the refresh to finish", func() bool {
+			cache.mu.Lock()
+			defer cache.mu.Unlock()
+			return len(cache.inflight) == 0
+		})
+	}
+	resolver.err = &ResolveError{StatusCode: http.StatusServiceUnavailable, Message: "Unavailable"}
+	cache.Resolve(ctx, "brave-fox", false)
+	refreshDone(3)
+	target, err = cache.Resolve(ctx, "brave-fox", false)
+	if err != nil || target.DeploymentID != "brave-fox-2" {
+		t.Errorf("Resolve after a failed refresh = %+v, %v, want the stale brave-fox-2", target, err)
+	}
+	refreshDone(4)
+
+	// A 404 means the project or deployment is gone, so the entry is replaced by the 404
+	notFound := &ResolveError{StatusCode: http.StatusNotFound, Message: "Not found"}
+	resolver.err = notFound
+	cache.Resolve(ctx, "brave-fox", false)
+	refreshDone(5)
+	if _, err := cache.Resolve(ctx, "brave-fox", false); !errors.Is(err, notFound) {
+		t.Errorf("Resolve after a 404 refresh = %v, want the 404", err)
+	}
+	if got := resolver.calls.Load(); got != 5 {
+		t.Errorf("resolved %d times, want the 404 answered from the cache", got)
+	}
+}
+
+func TestResolveCacheNegativeTTL(t *testing.T) {
+	notFound := &ResolveError{StatusCode: http.StatusNotFound, Message: "Not found"}
+	resolver := &countingResolver{err: notFound}
+	cache := NewResolveCache(time.Hour, 50*time.Millisecond, resolver.resolve)
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := cache.Resolve(ctx, "unknown", false); !errors.Is(err, notFound) {
+			t.Fatalf("err = %v, want the 404", err)
+		}
+	}
+	if got := resolver.calls.Load(); got != 1 {
+		t.Errorf("resolved %d times within the negative TTL, want once", got)
+	}
+	if stats := cache.Stats(); stats.NegativeHits != 2 {
+		t.Errorf("NegativeHits = %d, want 2", stats.NegativeHits)
+	}
+	if !cache.Cached("unknown") {
+		t.Error("the 404 isn't cached")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if cache.Cached("unknown") {
+		t.Error("the 404 is cached after the negative TTL")
+	}
+	cache.Resolve(ctx, "unknown", false)
+	if got := resolver.calls.Load(); got != 2 {
+		t.Errorf("resolved %d times after the negative TTL, want twice", got)
+	}
+
+	// Other failures aren't cached at all
+	resolver.err = &ResolveError{StatusCode: http.StatusServiceUnavailable, Message: "Unavailable"}
+	cache.Resolve(ctx, "down", false)
+	cache.Resolve(ctx, "down", false)
+	if got := resolver.calls.Load(); got != 4 {
+		t.Errorf("resolved %d times, want a 503 resolved every time", got)
+	}
+}
+
+func TestResolveCacheSharesConcurrentMisses(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	cache := NewResolveCache(time.Hour, time.Hour, func(ctx context.Context, key string) (Target, error) {
+		calls.Add(1)
+		<-release
+		// Canceling the request that started the resolution doesn't cancel it for the others
+		if err := ctx.Err(); err != nil {
+			return Target{}, err
+		}
+		return Target{DeploymentID: "d1"}, nil
+	})
+
+	const callers = 10
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			target, err := cache.Resolve(ctx, "brave-fox", false)
+			if err == nil && target.DeploymentID != "d1" {
+				err = errors.New("resolved to " + target.DeploymentID)
+			}
+			errs <- err
+		}()
+	}
+	waitFor(t, "every caller to miss", func() bool { return cache.Stats().Misses == callers })
+	// Give the last callers time to join the call in progress
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("resolved %d times, want once for all %d callers", got, callers)
+	}
+}
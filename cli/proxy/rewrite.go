@@ -0,0 +1,80 @@
+// Package proxy contains the request path handling shared by the reverse proxy and the
+// CLI's local preview server, so both resolve deployment paths the same way
+package proxy
+
+import (
+	"strings"
+	"sync"
+)
+
+// RewritePath maps a request path to the path of the object to serve from a deployment
+// A leading segment is only stripped when it exactly matches one of prefixes, such as the
+// deployment ID or the project slug; every other path is served unchanged
+func RewritePath(urlPath string, prefixes ...string) string {
+	stripped, _ := stripPrefix(urlPath, prefixes)
+	return indexPath(stripped)
+}
+
+// stripPrefix returns urlPath without its leading segment if it exactly matches one of
+// prefixes, and the prefix it matched, or urlPath and an empty prefix if none matches
+func stripPrefix(urlPath string, prefixes []string) (string, string) {
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			continue
+		}
+		if urlPath == "/"+prefix {
+			return "/", prefix
+		}
+		if strings.HasPrefix(urlPath, "/"+prefix+"/") {
+			return strings.TrimPrefix(urlPath, "/"+prefix), prefix
+		}
+	}
+	return urlPath, ""
+}
+
+// indexPath returns the object path of urlPath. Directories, including the root, are served
+// from their index.html.
+func indexPath(urlPath string) string {
+	if urlPath == "" {
+		return "/index.html"
+	}
+	if strings.HasSuffix(urlPath, "/") {
+		return urlPath + "index.html"
+	}
+	return urlPath
+}
+
+// maxPathShapes bounds the number of deployments and prefixes pathShapes remembers
+const maxPathShapes = 10000
+
+// pathShapes remembers, per deployment and prefix, whether the objects of paths starting with
+// the prefix were last found with it stripped or kept, since a deployment may have a real
+// directory named like its slug. The shape that worked is tried first, so requests usually only
+// take one round trip to the object store.
+type pathShapes struct {
+	mu       sync.Mutex
+	stripped map[string]bool
+}
+
+func newPathShapes() *pathShapes {
+	return &pathShapes{stripped: make(map[string]bool)}
+}
+
+// prefersStripped reports whether objects were last found with the prefix of key stripped.
+// Until an object is found, the path is tried as requested first.
+func (s *pathShapes) prefersStripped(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stripped[key]
+}
+
+// remember records the shape an object was found with for key
+func (s *pathShapes) remember(key string, stripped bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.stripped[key]; !ok && len(s.stripped) >= maxPathShapes {
+		// Deployments never change, so forgetting only costs an extra round trip
+		clear(s.stripped)
+	}
+	s.stripped[key] = stripped
+}
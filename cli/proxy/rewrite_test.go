@@ -0,0 +1,35 @@
+package proxy
+
+import "testing"
+
+func TestRewritePath(t *testing.T) {
+	tests := []struct {
+		urlPath  string
+		prefixes []string
+		want     string
+	}{
+		{"/", nil, "/index.html"},
+		{"", nil, "/index.html"},
+		{"/about", nil, "/about"},
+		{"/blog/post", nil, "/blog/post"},
+		{"/blog/post.html", nil, "/blog/post.html"},
+		{"/assets/app.js", nil, "/assets/app.js"},
+		{"/static/js/vendor/chunk.js", nil, "/static/js/vendor/chunk.js"},
+		{"/docs/", nil, "/docs/index.html"},
+		{"/d1", []string{"d1"}, "/index.html"},
+		{"/d1/", []string{"d1"}, "/index.html"},
+		{"/d1/assets/app.js", []string{"d1"}, "/assets/app.js"},
+		{"/d1/docs/", []string{"d1"}, "/docs/index.html"},
+		{"/brave-fox/assets/app.js", []string{"brave-fox", "d1"}, "/assets/app.js"},
+		{"/d1/assets/app.js", []string{"brave-fox", "d1"}, "/assets/app.js"},
+		// Only a whole leading segment is stripped
+		{"/d10/assets/app.js", []string{"d1"}, "/d10/assets/app.js"},
+		{"/assets/d1/app.js", []string{"d1"}, "/assets/d1/app.js"},
+		{"/fonts/a.woff2", []string{"", "d1"}, "/fonts/a.woff2"},
+	}
+	for _, tt := range tests {
+		if got := RewritePath(tt.urlPath, tt.prefixes...); got != tt.want {
+			t.Errorf("RewritePath(%q, %q) = %q, want %q", tt.urlPath, tt.prefixes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package proxy
+
+import "net/http"
+
+// SecurityHeaders sets headers on every response of next that it doesn't set itself. Headers
+// the deployment's objects were served with win, so a site can e.g. allow being framed.
+func SecurityHeaders(headers http.Header, next http.Handler) http.Handler {
+	if len(headers) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&securityHeadersWriter{ResponseWriter: w, headers: headers}, r)
+	})
+}
+
+// securityHeadersWriter adds the missing security headers just before the response is sent
+type securityHeadersWriter struct {
+	http.ResponseWriter
+	headers     http.Header
+	wroteHeader bool
+}
+
+func (w *securityHeadersWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		for name, values := range w.headers {
+			if _, ok := w.Header()[name]; !ok {
+				w.Header()[name] = append([]string(nil), values...)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *securityHeadersWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *securityHeadersWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *securityHeadersWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	headers := http.Header{
+		"X-Content-Type-Options": {"nosniff"},
+		"X-Frame-Options":        {"DENY"},
+	}
+	handler := SecurityHeaders(headers, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/embed" {
+			w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+		}
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	tests := []struct {
+		path        string
+		wantFrame   string
+		wantNoSniff string
+	}{
+		{"/", "DENY", "nosniff"},
+		// Headers set by the deployment win
+		{"/embed", "SAMEORIGIN", "nosniff"},
+		// Error responses get them too
+		{"/missing", "DENY", "nosniff"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja"+tt.path, nil))
+		if got := rec.Header().Get("X-Frame-Options"); got != tt.wantFrame {
+			t.Errorf("%s: X-Frame-Options = %q, want %q", tt.path, got, tt.wantFrame)
+		}
+		if got := rec.Header().Get("X-Content-Type-Options"); got != tt.wantNoSniff {
+			t.Errorf("%s: X-Content-Type-Options = %q, want %q", tt.path, got, tt.wantNoSniff)
+		}
+	}
+
+	// Responses don't share the configured values
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://brave-fox.yok.ninja/", nil))
+	rec.Header()["X-Frame-Options"][0] = "changed"
+	if got := headers.Get("X-Frame-Options"); got != "DENY" {
+		t.Errorf("configured X-Frame-Options changed to %q", got)
+	}
+}
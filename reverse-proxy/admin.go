@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+// adminHandler serves the admin endpoints to requests authorized with token
+func adminHandler(token string, resolveCache *proxy.ResolveCache) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/resolve-cache/purge", purgeResolveCache(resolveCache))
+	return requireToken(token, mux)
+}
+
+// requireToken answers requests without an "Authorization: Bearer <token>" header with a 401
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// purgeResolveCache handles POST /resolve-cache/purge?key=<slug or custom domain>, which makes
+// the proxy resolve key with the API server again on its next request. The API server calls it
+// after creating a project, so the new slug isn't answered with a cached 404.
+func purgeResolveCache(resolveCache *proxy.ResolveCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("key")))
+		if key == "" {
+			http.Error(w, "Missing key", http.StatusBadRequest)
+			return
+		}
+
+		resolveCache.Purge(key)
+		slog.Info("Purged resolve cache", "key", key)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
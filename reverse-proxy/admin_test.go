@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+func TestAdminHandler(t *testing.T) {
+	resolveCache := proxy.NewResolveCache(time.Hour, time.Hour, func(ctx context.Context, key string) (proxy.Target, error) {
+		return proxy.Target{DeploymentID: "d1"}, nil
+	})
+	resolveCache.Resolve(context.Background(), "brave-fox", false)
+	handler := adminHandler("s3cret", resolveCache)
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		key           string
+		want          int
+	}{
+		{"no token", http.MethodPost, "", "brave-fox", http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "Bearer guess", "brave-fox", http.StatusUnauthorized},
+		{"not a bearer token", http.MethodPost, "Basic s3cret", "brave-fox", http.StatusUnauthorized},
+		{"wrong method", http.MethodGet, "Bearer s3cret", "brave-fox", http.StatusMethodNotAllowed},
+		{"no key", http.MethodPost, "Bearer s3cret", "", http.StatusBadRequest},
+		{"purge", http.MethodPost, "Bearer s3cret", "brave-fox", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/resolve-cache/purge?key="+tt.key, nil)
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: %s = %d, want %d", tt.name, tt.method, rec.Code, tt.want)
+		}
+		// Only the authorized purge forgets the cached resolution
+		if cached := resolveCache.Cached("brave-fox"); cached != (tt.want != http.StatusNoContent) {
+			t.Errorf("%s: brave-fox cached = %v", tt.name, cached)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+// Defaults for the circuit breaker around the API server
+const (
+	defaultCircuitBreakerFailures = 5
+	defaultCircuitBreakerCooldown = 30 * time.Second
+)
+
+// errAPIUnavailable is wrapped by the errors of resolves that failed because the API server
+// couldn't be reached or failed, as opposed to answering that there is no deployment
+var errAPIUnavailable = errors.New("API server unavailable")
+
+// circuitState is the state of a circuitBreaker, exported as a metric
+type circuitState int
+
+const (
+	// circuitClosed lets every resolve through
+	circuitClosed circuitState = iota
+	// circuitHalfOpen lets a single resolve through to probe whether the API server recovered
+	circuitHalfOpen
+	// circuitOpen fails resolves right away
+	circuitOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitHalfOpen:
+		return "half-open"
+	case circuitOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops resolves from waiting on the API server while it is down. After
+// maxFailures consecutive failures the circuit opens and resolves fail right away for cooldown,
+// after which a single probe is let through: it closes the circuit if it succeeds and opens it
+// again if it fails.
+type circuitBreaker struct {
+	maxFailures int
+	cooldown    time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// newCircuitBreaker returns a closed circuit breaker
+func newCircuitBreaker(maxFailures int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{maxFailures: maxFailures, cooldown: cooldown}
+}
+
+// allow reports whether a resolve may be sent to the API server. Every allowed resolve must be
+// followed by a call to record.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+		return true
+	case circuitHalfOpen:
+		// Only the probe is let through until it finishes
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record counts the outcome of a resolve that allow let through
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.maxFailures {
+		b.openedAt = time.Now()
+		b.setState(circuitOpen)
+	}
+}
+
+// setState moves the breaker to state, logging the change; the caller must hold b.mu
+func (b *circuitBreaker) setState(state circuitState) {
+	if b.state == state {
+		return
+	}
+	slog.Warn("API server circuit changed", "from", b.state.String(), "to", state.String(), "consecutive_failures", b.failures)
+	b.state = state
+}
+
+// State returns the breaker's current state
+func (b *circuitBreaker) State() circuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// guard wraps a resolve with the breaker. While the circuit is open, resolves fail right away
+// with a 503; resolves the cache can answer never get here, so cached slugs keep being served.
+// A breaker with maxFailures 0 is turned off.
+func (b *circuitBreaker) guard(resolve func() (proxy.Target, error)) (proxy.Target, error) {
+	if b.maxFailures == 0 {
+		return resolve()
+	}
+	if !b.allow() {
+		return proxy.Target{}, &proxy.ResolveError{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "This site is temporarily unavailable. Please try again in a moment.",
+			Err:        errAPIUnavailable,
+		}
+	}
+
+	target, err := resolve()
+	b.record(errors.Is(err, errAPIUnavailable))
+	return target, err
+}
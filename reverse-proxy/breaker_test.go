@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := newCircuitBreaker(3, time.Hour)
+	failing := func() (proxy.Target, error) {
+		return proxy.Target{}, fmt.Errorf("resolving: %w", errAPIUnavailable)
+	}
+	calls := 0
+	succeeding := func() (proxy.Target, error) {
+		calls++
+		return proxy.Target{DeploymentID: "d1"}, nil
+	}
+	notFound := func() (proxy.Target, error) {
+		return proxy.Target{}, &proxy.ResolveError{StatusCode: http.StatusNotFound, Message: "Not found"}
+	}
+
+	// 404s mean the API server is up, so they don't count as failures
+	for range 5 {
+		breaker.guard(notFound)
+	}
+	if got := breaker.State(); got != circuitClosed {
+		t.Fatalf("state after 404s = %s, want closed", got)
+	}
+
+	// A success resets the consecutive failures
+	breaker.guard(failing)
+	breaker.guard(failing)
+	breaker.guard(succeeding)
+	breaker.guard(failing)
+	breaker.guard(failing)
+	if got := breaker.State(); got != circuitClosed {
+		t.Fatalf("state after 2 consecutive failures = %s, want closed", got)
+	}
+
+	breaker.guard(failing)
+	if got := breaker.State(); got != circuitOpen {
+		t.Fatalf("state after 3 consecutive failures = %s, want open", got)
+	}
+
+	// While open, resolves fail right away with a 503
+	calls = 0
+	_, err := breaker.guard(succeeding)
+	var resolveErr *proxy.ResolveError
+	if !errors.As(err, &resolveErr) || resolveErr.StatusCode != http.StatusServiceUnavailable || !errors.Is(err, errAPIUnavailable) {
+		t.Errorf("err while open = %v, want a 503", err)
+	}
+	if calls != 0 {
+		t.Error("resolved while the circuit is open")
+	}
+
+	// After the cooldown a single probe is let through, and its failure opens the circuit again
+	breaker.openedAt = time.Now().Add(-time.Hour)
+	if !breaker.allow() {
+		t.Fatal("the probe wasn't allowed after the cooldown")
+	}
+	if got := breaker.State(); got != circuitHalfOpen {
+		t.Errorf("state while probing = %s, want half-open", got)
+	}
+	if breaker.allow() {
+		t.Error("a second resolve was allowed while probing")
+	}
+	breaker.record(true)
+	if got := breaker.State(); got != circuitOpen {
+		t.Errorf("state after a failed probe = %s, want open", got)
+	}
+	if breaker.allow() {
+		t.Error("a failed probe didn't restart the cooldown")
+	}
+
+	// A successful probe closes it
+	breaker.openedAt = time.Now().Add(-time.Hour)
+	if _, err := breaker.guard(succeeding); err != nil {
+		t.Fatal(err)
+	}
+	if got := breaker.State(); got != circuitClosed {
+		t.Errorf("state after a successful probe = %s, want closed", got)
+	}
+}
+
+func TestCircuitBreakerOff(t *testing.T) {
+	breaker := newCircuitBreaker(0, time.Hour)
+	for range 10 {
+		_, err := breaker.guard(func() (proxy.Target, error) { return proxy.Target{}, errAPIUnavailable })
+		if !errors.Is(err, errAPIUnavailable) {
+			t.Fatalf("err = %v, want the resolve's error", err)
+		}
+	}
+	if got := breaker.State(); got != circuitClosed {
+		t.Errorf("state = %s, want closed", got)
+	}
+}
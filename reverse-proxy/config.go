@@ -0,0 +1,330 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+// defaultPort is the port the proxy listens on without PORT
+const defaultPort = "8080"
+
+// Config is the configuration of the proxy, read from the environment by loadConfig
+type Config struct {
+	Port string
+	// Bucket and Region are the default bucket deployments are served from and its region
+	Bucket string
+	Region string
+	// APIServerURL is the API server slugs and custom domains are resolved with
+	APIServerURL string
+	// UpstreamBaseURL serves deployments instead of the bucket, e.g. a CloudFront distribution
+	UpstreamBaseURL string
+	// OutputPrefix is where deployments are within the bucket or UpstreamBaseURL
+	OutputPrefix string
+	// S3Public turns off signing the requests sent to the bucket
+	S3Public  bool
+	LogFormat string
+	LogLevel  string
+
+	// BasePath is the URL deployments live under, and Origins where each one is served from
+	BasePath string
+	Origins  *origins
+	// BaseDomain is the domain whose direct subdomains are slugs and deployment IDs
+	BaseDomain string
+	// CustomDomains maps hosts to the deployments they serve without asking the API server
+	CustomDomains  map[string]string
+	SPAFallback    bool
+	DirectoryIndex proxy.DirectoryIndex
+
+	ResolveCacheTTL         time.Duration
+	ResolveCacheNegativeTTL time.Duration
+
+	// The asset cache is off unless AssetCache is set
+	AssetCache            bool
+	AssetCacheSizeMB      int
+	AssetCacheMaxObjectKB int
+	AssetCacheTTL         time.Duration
+
+	CircuitBreakerFailures int
+	CircuitBreakerCooldown time.Duration
+
+	// The rate limits are off unless RateLimit is set
+	RateLimit             bool
+	RateLimitRPS          int
+	RateLimitBurst        int
+	RateLimitResolveRPS   int
+	RateLimitResolveBurst int
+	RateLimitAllowlist    []netip.Prefix
+
+	// TrustedProxies are the proxies whose forwarded headers are believed
+	TrustedProxies []netip.Prefix
+
+	// Metrics are served on MetricsPort if it is set
+	MetricsPort         string
+	MetricsPerSubdomain bool
+	// The admin endpoints, such as purging the resolve cache, are served on AdminPort if it is
+	// set, to requests carrying AdminToken
+	AdminPort  string
+	AdminToken string
+
+	ShutdownGracePeriod time.Duration
+	TLS                 tlsSettings
+	SecurityHeaders     http.Header
+	// HashedAssetPattern matches the paths of assets served as immutable; nil leaves
+	// Cache-Control to the object store
+	HashedAssetPattern *regexp.Regexp
+}
+
+// loadConfig reads the Config from the environment, applying defaults. Every missing or invalid
+// variable is listed in the returned error, so they can all be fixed at once.
+func loadConfig() (Config, error) {
+	env := &envReader{}
+	cfg := Config{
+		Port:            envOr("PORT", defaultPort),
+		Bucket:          os.Getenv("AWS_S3_BUCKET"),
+		Region:          os.Getenv("AWS_REGION"),
+		APIServerURL:    strings.TrimSuffix(os.Getenv("API_SERVER_URL"), "/"),
+		UpstreamBaseURL: os.Getenv("UPSTREAM_BASE_URL"),
+		OutputPrefix:    defaultOutputPrefix,
+		LogFormat:       envOr("LOG_FORMAT", "json"),
+		LogLevel:        envOr("LOG_LEVEL", "info"),
+	}
+	if prefix, ok := os.LookupEnv("OUTPUT_PREFIX"); ok {
+		cfg.OutputPrefix = prefix
+	}
+
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		env.invalid("PORT %q must be a port number from 1 to 65535", cfg.Port)
+	}
+
+	if cfg.APIServerURL == "" {
+		env.invalid("API_SERVER_URL is not set")
+	} else if parsed, err := url.Parse(cfg.APIServerURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		env.invalid("API_SERVER_URL %q must be an absolute http or https URL", cfg.APIServerURL)
+	}
+
+	// The bucket is only needed when deployments aren't served from UPSTREAM_BASE_URL
+	if cfg.UpstreamBaseURL == "" {
+		if cfg.Bucket == "" {
+			env.invalid("AWS_S3_BUCKET is not set; set it and AWS_REGION, or UPSTREAM_BASE_URL")
+		}
+		if cfg.Region == "" {
+			env.invalid("AWS_REGION is not set; set it and AWS_S3_BUCKET, or UPSTREAM_BASE_URL")
+		}
+	}
+	bucketValid := cfg.Bucket == "" || bucketPattern.MatchString(cfg.Bucket)
+	if !bucketValid {
+		env.invalid("AWS_S3_BUCKET %q is not a valid bucket name", cfg.Bucket)
+	}
+	regionValid := cfg.Region == "" || regionPattern.MatchString(cfg.Region)
+	if !regionValid {
+		env.invalid("AWS_REGION %q is not a valid region, e.g. us-east-1", cfg.Region)
+	}
+
+	// Deployments are served from UPSTREAM_BASE_URL, or else straight from the bucket, under
+	// OUTPUT_PREFIX, and from the buckets ORIGIN_OVERRIDES maps their slug prefixes to
+	if cfg.UpstreamBaseURL != "" || (cfg.Bucket != "" && cfg.Region != "" && bucketValid && regionValid) {
+		basePath, err := buildBasePath(cfg.upstreamBaseURL(), cfg.OutputPrefix)
+		if err != nil {
+			env.invalid("UPSTREAM_BASE_URL %q must be an absolute http or https URL", cfg.UpstreamBaseURL)
+		}
+		cfg.BasePath = basePath
+	}
+	origins, err := newOrigins(cfg.BasePath, cfg.Bucket, cfg.Region, cfg.OutputPrefix, os.Getenv("ORIGIN_OVERRIDES"))
+	env.add(err)
+	cfg.Origins = origins
+
+	// Requests to the bucket are signed unless S3_PUBLIC is true, which it is by default with
+	// UPSTREAM_BASE_URL, since that is usually a CDN rather than the bucket
+	cfg.S3Public = env.bool("S3_PUBLIC", cfg.UpstreamBaseURL != "")
+	if !cfg.S3Public && cfg.Region == "" && cfg.UpstreamBaseURL != "" {
+		env.invalid("AWS_REGION is not set, which signing requests to S3 needs; set S3_PUBLIC=true for a public bucket")
+	}
+
+	if _, err := newLogger(cfg.LogFormat, cfg.LogLevel); err != nil {
+		env.add(err)
+	}
+
+	// Deployments are served on subdomains of BASE_DOMAIN (TLS_DOMAIN if unset); other hosts are
+	// custom domains, which CUSTOM_DOMAINS can map to deployments statically
+	cfg.BaseDomain = hostname(envOr("BASE_DOMAIN", os.Getenv("TLS_DOMAIN")))
+	cfg.CustomDomains, err = parseCustomDomains(os.Getenv("CUSTOM_DOMAINS"))
+	env.add(err)
+
+	cfg.SPAFallback = env.bool("SPA_FALLBACK", false)
+	cfg.DirectoryIndex = proxy.DirectoryIndex(strings.ToLower(envOr("DIRECTORY_INDEX", string(proxy.DirectoryIndexRedirect))))
+	switch cfg.DirectoryIndex {
+	case proxy.DirectoryIndexRedirect, proxy.DirectoryIndexRewrite, proxy.DirectoryIndexOff:
+	default:
+		env.invalid("DIRECTORY_INDEX %q must be redirect, rewrite or off", cfg.DirectoryIndex)
+	}
+
+	cfg.ResolveCacheTTL = env.duration("RESOLVE_CACHE_TTL", defaultResolveCacheTTL)
+	cfg.ResolveCacheNegativeTTL = env.duration("RESOLVE_CACHE_NEGATIVE_TTL", defaultResolveCacheNegativeTTL)
+
+	cfg.AssetCache = env.bool("ASSET_CACHE", true)
+	cfg.AssetCacheSizeMB = env.int("ASSET_CACHE_SIZE_MB", defaultAssetCacheSizeMB)
+	cfg.AssetCacheMaxObjectKB = env.int("ASSET_CACHE_MAX_OBJECT_KB", defaultAssetCacheMaxObjectKB)
+	cfg.AssetCacheTTL = env.duration("ASSET_CACHE_TTL", defaultAssetCacheTTL)
+
+	cfg.CircuitBreakerFailures = env.int("CIRCUIT_BREAKER_FAILURES", defaultCircuitBreakerFailures)
+	cfg.CircuitBreakerCooldown = env.duration("CIRCUIT_BREAKER_COOLDOWN", defaultCircuitBreakerCooldown)
+
+	cfg.RateLimit = env.bool("RATE_LIMIT", false)
+	cfg.RateLimitRPS = env.int("RATE_LIMIT_RPS", defaultRateLimitRPS)
+	cfg.RateLimitBurst = env.int("RATE_LIMIT_BURST", defaultRateLimitBurst)
+	cfg.RateLimitResolveRPS = env.int("RATE_LIMIT_RESOLVE_RPS", defaultRateLimitResolveRPS)
+	cfg.RateLimitResolveBurst = env.int("RATE_LIMIT_RESOLVE_BURST", defaultRateLimitResolveBurst)
+	cfg.RateLimitAllowlist = env.prefixes("RATE_LIMIT_ALLOWLIST")
+	cfg.TrustedProxies = env.prefixes("TRUSTED_PROXIES")
+
+	cfg.MetricsPort = os.Getenv("METRICS_PORT")
+	cfg.MetricsPerSubdomain = env.bool("METRICS_PER_SUBDOMAIN", false)
+
+	cfg.AdminPort = os.Getenv("ADMIN_PORT")
+	cfg.AdminToken = os.Getenv("ADMIN_TOKEN")
+	if cfg.AdminPort != "" {
+		if port, err := strconv.Atoi(cfg.AdminPort); err != nil || port < 1 || port > 65535 {
+			env.invalid("ADMIN_PORT %q must be a port number from 1 to 65535", cfg.AdminPort)
+		}
+		if cfg.AdminToken == "" {
+			env.invalid("ADMIN_TOKEN is not set, which ADMIN_PORT needs to authorize requests")
+		}
+	}
+
+	cfg.ShutdownGracePeriod = env.duration("SHUTDOWN_GRACE_PERIOD", defaultShutdownGracePeriod)
+	cfg.TLS, err = loadTLSSettings()
+	env.add(err)
+	cfg.SecurityHeaders, err = loadSecurityHeaders(cfg.TLS.mode != tlsModeOff)
+	env.add(err)
+	cfg.HashedAssetPattern, err = loadHashedAssetPattern()
+	env.add(err)
+
+	if err := errors.Join(env.problems...); err != nil {
+		return cfg, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// upstreamBaseURL returns the URL deployments are served from: UpstreamBaseURL, or the bucket
+func (c Config) upstreamBaseURL() string {
+	if c.UpstreamBaseURL != "" {
+		return c.UpstreamBaseURL
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", c.Bucket, c.Region)
+}
+
+// log logs the configuration the proxy runs with. It holds no secrets, but URLs are logged
+// without any password they contain.
+func (c Config) log() {
+	slog.Info("Loaded configuration",
+		"port", c.Port,
+		"bucket", c.Bucket,
+		"region", c.Region,
+		"api_server_url", redactURL(c.APIServerURL),
+		"upstream_base_url", redactURL(c.upstreamBaseURL()),
+		"output_prefix", c.OutputPrefix,
+		"sign_s3_requests", !c.S3Public,
+	)
+}
+
+// redactURL returns rawURL with the password it contains, if any, replaced
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsed.Redacted()
+}
+
+// envReader reads typed environment variables, collecting the problems with invalid ones
+// instead of stopping at the first. Invalid variables read as their default.
+type envReader struct {
+	problems []error
+}
+
+// invalid records a problem with the configuration
+func (e *envReader) invalid(format string, args ...any) {
+	e.problems = append(e.problems, fmt.Errorf(format, args...))
+}
+
+// add records err as a problem with the configuration, if it isn't nil
+func (e *envReader) add(err error) {
+	if err != nil {
+		e.problems = append(e.problems, err)
+	}
+}
+
+// duration returns the duration in the environment variable name, or def if it isn't set
+func (e *envReader) duration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		e.invalid("%s %q must be a duration such as 30s", name, value)
+		return def
+	}
+	return parsed
+}
+
+// int returns the integer in the environment variable name, or def if it isn't set
+func (e *envReader) int(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		e.invalid("%s %q must be a non-negative integer", name, value)
+		return def
+	}
+	return parsed
+}
+
+// bool returns the boolean in the environment variable name, or def if it isn't set
+func (e *envReader) bool(name string, def bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.invalid("%s %q must be true or false", name, value)
+		return def
+	}
+	return parsed
+}
+
+// prefixes returns the comma-separated CIDR ranges or addresses in the environment variable
+// name; an address is taken as a range of one
+func (e *envReader) prefixes(name string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(value)
+			if addrErr != nil {
+				e.invalid("%s entry %q must be a CIDR range or IP address", name, value)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
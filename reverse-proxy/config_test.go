@@ -0,0 +1,191 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+// configEnv are the variables loadConfig reads, which are unset for each test
+var configEnv = []string{
+	"PORT", "AWS_S3_BUCKET", "AWS_REGION", "API_SERVER_URL", "UPSTREAM_BASE_URL", "OUTPUT_PREFIX",
+	"S3_PUBLIC", "LOG_FORMAT", "LOG_LEVEL", "ORIGIN_OVERRIDES", "BASE_DOMAIN", "CUSTOM_DOMAINS",
+	"SPA_FALLBACK", "DIRECTORY_INDEX", "RESOLVE_CACHE_TTL", "RESOLVE_CACHE_NEGATIVE_TTL",
+	"ASSET_CACHE", "ASSET_CACHE_SIZE_MB", "ASSET_CACHE_MAX_OBJECT_KB", "ASSET_CACHE_TTL",
+	"CIRCUIT_BREAKER_FAILURES", "CIRCUIT_BREAKER_COOLDOWN", "RATE_LIMIT", "RATE_LIMIT_RPS",
+	"RATE_LIMIT_BURST", "RATE_LIMIT_RESOLVE_RPS", "RATE_LIMIT_RESOLVE_BURST", "RATE_LIMIT_ALLOWLIST",
+	"TRUSTED_PROXIES", "METRICS_PORT", "METRICS_PER_SUBDOMAIN", "ADMIN_PORT", "ADMIN_TOKEN", "SHUTDOWN_GRACE_PERIOD",
+	"TLS_MODE", "TLS_DOMAIN", "TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_EXTRA_DOMAINS",
+	"SECURITY_HEADERS", "CACHE_CONTROL", "HASHED_ASSET_PATTERN",
+}
+
+// setConfigEnv sets the environment to env, unsetting every other variable loadConfig reads
+func setConfigEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range configEnv {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	setConfigEnv(t, map[string]string{
+		"API_SERVER_URL": "http://api:9000/",
+		"AWS_S3_BUCKET":  "yok-outputs",
+		"AWS_REGION":     "ap-south-1",
+	})
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"Port", cfg.Port, "8080"},
+		{"APIServerURL", cfg.APIServerURL, "http://api:9000"},
+		{"BasePath", cfg.BasePath, "https://yok-outputs.s3.ap-south-1.amazonaws.com/__output/"},
+		{"S3Public", cfg.S3Public, false},
+		{"DirectoryIndex", cfg.DirectoryIndex, proxy.DirectoryIndexRedirect},
+		{"ResolveCacheTTL", cfg.ResolveCacheTTL, defaultResolveCacheTTL},
+		{"ResolveCacheNegativeTTL", cfg.ResolveCacheNegativeTTL, defaultResolveCacheNegativeTTL},
+		{"AssetCache", cfg.AssetCache, true},
+		{"AssetCacheSizeMB", cfg.AssetCacheSizeMB, defaultAssetCacheSizeMB},
+		{"CircuitBreakerFailures", cfg.CircuitBreakerFailures, defaultCircuitBreakerFailures},
+		{"RateLimit", cfg.RateLimit, false},
+		{"TrustedProxies", len(cfg.TrustedProxies), 0},
+		{"ShutdownGracePeriod", cfg.ShutdownGracePeriod, defaultShutdownGracePeriod},
+		{"TLS mode", cfg.TLS.mode, tlsModeOff},
+		{"X-Content-Type-Options", cfg.SecurityHeaders.Get("X-Content-Type-Options"), "nosniff"},
+		{"Strict-Transport-Security", cfg.SecurityHeaders.Get("Strict-Transport-Security"), ""},
+		{"HashedAssetPattern", cfg.HashedAssetPattern != nil, true},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.want)
+		}
+	}
+}
+
+func TestLoadConfigUpstream(t *testing.T) {
+	setConfigEnv(t, map[string]string{
+		"API_SERVER_URL":    "https://api.yok.ninja",
+		"UPSTREAM_BASE_URL": "https://d1234abcd.cloudfront.net/",
+		"OUTPUT_PREFIX":     "",
+		"TRUSTED_PROXIES":   "10.0.0.0/8, 192.0.2.1",
+		"RESOLVE_CACHE_TTL": "30s",
+		"TLS_MODE":          "auto",
+		"TLS_DOMAIN":        "yok.ninja",
+	})
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BasePath != "https://d1234abcd.cloudfront.net/" {
+		t.Errorf("BasePath = %q", cfg.BasePath)
+	}
+	if !cfg.S3Public {
+		t.Error("requests to UPSTREAM_BASE_URL are signed by default")
+	}
+	if len(cfg.TrustedProxies) != 2 || cfg.TrustedProxies[1].String() != "192.0.2.1/32" {
+		t.Errorf("TrustedProxies = %v", cfg.TrustedProxies)
+	}
+	if cfg.ResolveCacheTTL != 30*time.Second {
+		t.Errorf("ResolveCacheTTL = %v", cfg.ResolveCacheTTL)
+	}
+	if cfg.BaseDomain != "yok.ninja" {
+		t.Errorf("BaseDomain = %q, want TLS_DOMAIN", cfg.BaseDomain)
+	}
+	if cfg.SecurityHeaders.Get("Strict-Transport-Security") == "" {
+		t.Error("HSTS isn't sent with TLS on")
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "nothing set",
+			env:  nil,
+			want: []string{"API_SERVER_URL is not set", "AWS_S3_BUCKET is not set", "AWS_REGION is not set"},
+		},
+		{
+			name: "invalid core settings",
+			env: map[string]string{
+				"PORT":           "http",
+				"API_SERVER_URL": "api:9000",
+				"AWS_S3_BUCKET":  "Not_A_Bucket",
+				"AWS_REGION":     "mars",
+				"S3_PUBLIC":      "maybe",
+				"LOG_FORMAT":     "xml",
+			},
+			want: []string{`PORT "http"`, `API_SERVER_URL "api:9000"`, `AWS_S3_BUCKET "Not_A_Bucket"`, `AWS_REGION "mars"`, `S3_PUBLIC "maybe"`, "xml"},
+		},
+		{
+			name: "invalid optional settings",
+			env: map[string]string{
+				"API_SERVER_URL":            "http://api:9000",
+				"UPSTREAM_BASE_URL":         "https://cdn.example.com",
+				"CUSTOM_DOMAINS":            "www.example.com",
+				"ORIGIN_OVERRIDES":          "acme-=acme-sites",
+				"DIRECTORY_INDEX":           "list",
+				"RESOLVE_CACHE_TTL":         "a minute",
+				"ASSET_CACHE_SIZE_MB":       "-1",
+				"RATE_LIMIT":                "yes please",
+				"TRUSTED_PROXIES":           "10.0.0.0/8,lb.internal",
+				"TLS_MODE":                  "manual",
+				"SECURITY_HEADERS":          "sometimes",
+				"HASHED_ASSET_PATTERN":      "[",
+				"CIRCUIT_BREAKER_COOLDOWN":  "-5s",
+				"ASSET_CACHE_MAX_OBJECT_KB": "1MB",
+				"ADMIN_PORT":                "9091",
+			},
+			want: []string{
+				`CUSTOM_DOMAINS entry "www.example.com"`,
+				`ORIGIN_OVERRIDES entry "acme-=acme-sites"`,
+				`DIRECTORY_INDEX "list"`,
+				`RESOLVE_CACHE_TTL "a minute"`,
+				`ASSET_CACHE_SIZE_MB "-1"`,
+				`ASSET_CACHE_MAX_OBJECT_KB "1MB"`,
+				`RATE_LIMIT "yes please"`,
+				`TRUSTED_PROXIES entry "lb.internal"`,
+				"TLS_MODE=manual requires TLS_CERT_FILE and TLS_KEY_FILE",
+				`SECURITY_HEADERS "sometimes"`,
+				`HASHED_ASSET_PATTERN "["`,
+				`CIRCUIT_BREAKER_COOLDOWN "-5s"`,
+				"ADMIN_TOKEN is not set",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConfigEnv(t, tt.env)
+
+			_, err := loadConfig()
+			if err == nil {
+				t.Fatal("loadConfig succeeded")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error doesn't mention %s:\n%v", want, err)
+				}
+			}
+			// Every problem is on its own line, after the heading
+			if lines := strings.Count(err.Error(), "\n"); lines != len(tt.want) {
+				t.Errorf("error has %d problems, want %d:\n%v", lines, len(tt.want), err)
+			}
+		})
+	}
+}
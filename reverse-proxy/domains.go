@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// hostname returns the lowercased host of a request's Host header, without the port
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// subdomainOf returns the label of host directly below baseDomain, reporting whether host is
+// such a subdomain. Without a base domain, the first label of any host is used.
+func subdomainOf(host string, baseDomain string) (string, bool) {
+	if baseDomain == "" {
+		return strings.Split(host, ".")[0], true
+	}
+	label, ok := strings.CutSuffix(host, "."+baseDomain)
+	if !ok || label == "" || strings.Contains(label, ".") {
+		return "", false
+	}
+	return label, true
+}
+
+// parseCustomDomains parses CUSTOM_DOMAINS, a comma-separated list of host=deploymentId pairs
+func parseCustomDomains(value string) (map[string]string, error) {
+	domains := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		host, deploymentID, ok := strings.Cut(pair, "=")
+		host, deploymentID = hostname(strings.TrimSpace(host)), strings.TrimSpace(deploymentID)
+		if !ok || host == "" || deploymentID == "" {
+			return nil, fmt.Errorf("invalid CUSTOM_DOMAINS entry %q: must be host=deploymentId", pair)
+		}
+		domains[host] = deploymentID
+	}
+	return domains, nil
+}
@@ -1,5 +1,31 @@
-module reverse-proxy
+module github.com/velgardey/yok/reverse-proxy
 
 go 1.24.4
 
-require github.com/joho/godotenv v1.5.1
\ No newline at end of file
+require (
+	github.com/joho/godotenv v1.5.1
+	github.com/velgardey/yok/cli v0.0.0
+)
+
+require (
+	golang.org/x/crypto v0.39.0
+	golang.org/x/net v0.41.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_golang v1.20.5
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.55.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	golang.org/x/sys v0.34.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
+// The proxy package is shared with the CLI's preview server and lives in the CLI module, so the
+// CLI can be installed with go install. The proxy is only built from this repository.
+replace github.com/velgardey/yok/cli => ../cli
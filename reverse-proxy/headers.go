@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+// securityHeaderDefaults are the security headers sent by default, keyed by the suffix of the
+// SECURITY_HEADER_ variable overriding them
+var securityHeaderDefaults = []struct {
+	env   string
+	name  string
+	value string
+}{
+	{"X_CONTENT_TYPE_OPTIONS", "X-Content-Type-Options", "nosniff"},
+	{"REFERRER_POLICY", "Referrer-Policy", "strict-origin-when-cross-origin"},
+	{"X_FRAME_OPTIONS", "X-Frame-Options", "SAMEORIGIN"},
+	// Only sent by default when the proxy terminates TLS itself
+	{"STRICT_TRANSPORT_SECURITY", "Strict-Transport-Security", "max-age=31536000"},
+}
+
+// loadSecurityHeaders returns the security headers added to responses. SECURITY_HEADERS=off
+// turns them all off; SECURITY_HEADER_<NAME>, e.g. SECURITY_HEADER_X_FRAME_OPTIONS, replaces a
+// header's value, or drops the header if empty.
+func loadSecurityHeaders(tlsOn bool) (http.Header, error) {
+	headers := http.Header{}
+	switch value := strings.ToLower(os.Getenv("SECURITY_HEADERS")); value {
+	case "", "on", "true", "1":
+	case "off", "false", "0":
+		return headers, nil
+	default:
+		return headers, fmt.Errorf("SECURITY_HEADERS %q must be on or off", value)
+	}
+
+	for _, header := range securityHeaderDefaults {
+		value, ok := os.LookupEnv("SECURITY_HEADER_" + header.env)
+		if !ok {
+			value = header.value
+			if header.name == "Strict-Transport-Security" && !tlsOn {
+				value = ""
+			}
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			headers.Set(header.name, value)
+		}
+	}
+	return headers, nil
+}
+
+// loadHashedAssetPattern returns the pattern matching the paths of content-hashed assets, which
+// are served as immutable, from HASHED_ASSET_PATTERN. CACHE_CONTROL=off leaves Cache-Control to
+// the object store and returns nil.
+func loadHashedAssetPattern() (*regexp.Regexp, error) {
+	switch value := strings.ToLower(os.Getenv("CACHE_CONTROL")); value {
+	case "", "on", "true", "1":
+	case "off", "false", "0":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("CACHE_CONTROL %q must be on or off", value)
+	}
+
+	pattern := envOr("HASHED_ASSET_PATTERN", proxy.DefaultHashedAssetPattern)
+	hashedAssets, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("HASHED_ASSET_PATTERN %q is not a valid regular expression: %w", pattern, err)
+	}
+	return hashedAssets, nil
+}
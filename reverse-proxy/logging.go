@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+// newLogger returns a logger writing to stdout in format ("json" or "text"), dropping records
+// below level ("debug", "info", "warn" or "error"). Records logged with a request's context
+// include its ID.
+func newLogger(format string, level string) (*slog.Logger, error) {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL %q: must be debug, info, warn or error", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	switch strings.ToLower(format) {
+	case "json":
+		return slog.New(requestIDHandler{slog.NewJSONHandler(os.Stdout, opts)}), nil
+	case "text":
+		return slog.New(requestIDHandler{slog.NewTextHandler(os.Stdout, opts)}), nil
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT %q: must be json or text", format)
+	}
+}
+
+// requestIDHandler adds the ID of the request a record is logged for to the record
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := proxy.RequestIDFrom(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
+
+// accessRecord collects what is only known deeper in the handler for a request's access log
+type accessRecord struct {
+	deploymentID    string
+	upstreamLatency time.Duration
+}
+
+type accessRecordKey struct{}
+
+// recordFor returns the access record of the request ctx belongs to, if it is being logged
+func recordFor(ctx context.Context) *accessRecord {
+	record, _ := ctx.Value(accessRecordKey{}).(*accessRecord)
+	return record
+}
+
+// logAccess writes one access log record for every request handled by next
+func logAccess(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		path := r.URL.Path
+		record := &accessRecord{}
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), accessRecordKey{}, record)))
+
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("host", r.Host),
+			slog.String("subdomain", strings.Split(r.Host, ".")[0]),
+			slog.String("deployment_id", record.deploymentID),
+			slog.String("method", r.Method),
+			slog.String("path", path),
+			slog.Int("status", recorder.status),
+			slog.Int("bytes", recorder.bytes),
+			slog.Float64("duration_ms", milliseconds(time.Since(start))),
+			slog.Float64("upstream_ms", milliseconds(record.upstreamLatency)),
+			slog.String("client_ip", clientIP(r)),
+			slog.String("user_agent", r.UserAgent()),
+		)
+	})
+}
+
+// clientIP returns the client address RealIP found for r, or its peer address without one
+func clientIP(r *http.Request) string {
+	if addr := proxy.ClientIPFrom(r.Context()); addr.IsValid() {
+		return addr.String()
+	}
+	return r.RemoteAddr
+}
+
+// recordDeployment adds the deployment resolve returns to the request's access record
+func recordDeployment(resolve proxy.TargetResolver) proxy.TargetResolver {
+	return func(r *http.Request) (proxy.Target, error) {
+		target, err := resolve(r)
+		if record := recordFor(r.Context()); record != nil && err == nil {
+			record.deploymentID = target.DeploymentID
+		}
+		return target, err
+	}
+}
+
+// recordUpstreamLatency adds the time taken by the object store to the request's access record
+func recordUpstreamLatency(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		if record := recordFor(req.Context()); record != nil {
+			record.upstreamLatency += time.Since(start)
+		}
+		return resp, err
+	})
+}
+
+// milliseconds returns d in fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
 	"os"
 	"regexp"
@@ -14,131 +16,358 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/velgardey/yok/cli/proxy"
 )
 
 type SubDomainResponse struct {
 	DeploymentId string `json:"deploymentId"`
+	SpaFallback  *bool  `json:"spaFallback"`
+	// Bucket and Region locate the deployment if it isn't stored in the default bucket
+	Bucket string `json:"bucket,omitempty"`
+	Region string `json:"region,omitempty"`
 }
 
+// bypassResolveCacheHeader makes the proxy resolve a slug with the API server instead of its
+// cache when set on a request from one of TRUSTED_PROXIES, for debugging
+const bypassResolveCacheHeader = "X-Yok-Bypass-Resolve-Cache"
+
+// defaultResolveCacheTTL is how long a resolved slug is used before it is resolved again
+const defaultResolveCacheTTL = 60 * time.Second
+
+// defaultResolveCacheNegativeTTL is how long a slug without a deployment is answered with a 404
+// before it is resolved again
+const defaultResolveCacheNegativeTTL = 30 * time.Second
+
+// Defaults for the asset cache
+const (
+	defaultAssetCacheSizeMB      = 64
+	defaultAssetCacheMaxObjectKB = 1024
+	defaultAssetCacheTTL         = 5 * time.Minute
+)
+
+// defaultOutputPrefix is where the build server uploads deployments within the bucket
+const defaultOutputPrefix = "__output/"
+
+// slugPattern matches project slugs, which are resolved to deployment IDs via the API server
+var slugPattern = regexp.MustCompile(`^[a-z]+-[a-z]+-[a-z]+$`)
+
 func main() {
 	godotenv.Load()
 
-	//Get Environment Variables
-	PORT := os.Getenv("PORT")
-	bucketName := os.Getenv("AWS_S3_BUCKET")
-	region := os.Getenv("AWS_REGION")
-	apiServerUrl := os.Getenv("API_SERVER_URL")
+	// The configuration is checked before anything else, so every problem is reported at once
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Logs are written as JSON unless LOG_FORMAT is text
+	logger, err := newLogger(cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+	// log.Fatal calls from here on are logged as errors rather than at the default info level
+	slog.SetLogLoggerLevel(slog.LevelError)
+	cfg.log()
+
+	// SPA fallback applies to deployments the API server doesn't set it for
+	spaFallback := cfg.SPAFallback
+	// Without a base domain, the first label of every host is the subdomain
+	baseDomain := cfg.BaseDomain
+	// Custom domains in CUSTOM_DOMAINS are served without asking the API server, for self-hosters
+	customDomains := cfg.CustomDomains
+	// Deployments are served from the bucket and region the API server resolves them to, or from
+	// the bucket ORIGIN_OVERRIDES maps their slug prefix to, if not from the default one
+	origins := cfg.Origins
 
-	//Generate base path for S3
-	basePath := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/__output/", bucketName, region)
+	// Requests to the bucket are signed with the AWS credentials found in the environment, the
+	// ECS task role or the instance profile, so it can be private, unless S3_PUBLIC is true
+	var s3Credentials *awsCredentialsProvider
+	if !cfg.S3Public {
+		s3Credentials = newAWSCredentialsProvider()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*credentialsTimeout)
+		_, err := s3Credentials.Retrieve(ctx)
+		cancel()
+		if err != nil {
+			log.Fatalf("%v; set S3_PUBLIC=true to serve a public bucket without signing requests", err)
+		}
+	}
+
+	// Extensionless paths that are directories are redirected to, or served, with DIRECTORY_INDEX
+	handlerOpts := []proxy.HandlerOption{proxy.WithDirectoryIndex(cfg.DirectoryIndex)}
+
+	// Hot assets are cached in memory unless ASSET_CACHE is false
+	var assetCache *proxy.AssetCache
+	if cfg.AssetCache {
+		assetCache = proxy.NewAssetCache(
+			int64(cfg.AssetCacheSizeMB)<<20,
+			int64(cfg.AssetCacheMaxObjectKB)<<10,
+			cfg.AssetCacheTTL,
+		)
+		handlerOpts = append(handlerOpts, proxy.WithAssetCache(assetCache))
+	}
 
 	// Create HTTP client with timeout
 	client := &http.Client{
 		Timeout: 5 * time.Second,
 	}
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		hostName := r.Host
-		// Get the subdomain/slug from the host name
-		parts := strings.Split(hostName, ".")
-		subDomain := parts[0]
-		deploymentId := subDomain
+	// Resolves stop waiting on the API server after CIRCUIT_BREAKER_FAILURES consecutive failures,
+	// for CIRCUIT_BREAKER_COOLDOWN
+	breaker := newCircuitBreaker(cfg.CircuitBreakerFailures, cfg.CircuitBreakerCooldown)
 
-		// Validate the slug pattern and check if the deployment ID is being fetched from the API server
-		var slugPattern = regexp.MustCompile(`^[a-z]+-[a-z]+-[a-z]+$`)
-		if slugPattern.MatchString(subDomain) {
-			apiUrl := fmt.Sprintf("%s/resolve/%s", apiServerUrl, subDomain)
-			log.Printf("Resolving deployment ID for subdomain: %s", subDomain)
-
-			resp, err := client.Get(apiUrl)
+	// Resolve slugs and custom domains through the API server, reusing recent resolutions for
+	// RESOLVE_CACHE_TTL, and their absence for RESOLVE_CACHE_NEGATIVE_TTL. Keys with a dot are
+	// custom domains, since slugs never contain one.
+	resolveCache := proxy.NewResolveCache(cfg.ResolveCacheTTL, cfg.ResolveCacheNegativeTTL, func(ctx context.Context, key string) (proxy.Target, error) {
+		return breaker.guard(func() (proxy.Target, error) {
+			var resolved *SubDomainResponse
+			var err error
+			pathPrefixes := []string{key}
+			if strings.Contains(key, ".") {
+				resolved, err = resolveCustomDomain(ctx, client, cfg.APIServerURL, key)
+				pathPrefixes = nil
+			} else {
+				resolved, err = resolveDeployment(ctx, client, fmt.Sprintf("%s/resolve/%s", cfg.APIServerURL, key), key)
+			}
 			if err != nil {
-				log.Printf("Error resolving deployment ID: %v", err)
-				http.Error(w, "Failed to receive deployment Id", http.StatusInternalServerError)
-				return
+				return proxy.Target{}, err
 			}
-			defer resp.Body.Close()
-
-			log.Printf("Response status: %v", resp.StatusCode)
 
-			if resp.StatusCode != http.StatusOK {
-				log.Printf("Error resolving deployment ID: %v", resp.StatusCode)
-				http.Error(w, "Failed to receive deployment Id", http.StatusInternalServerError)
-				return
+			target := proxy.Target{
+				DeploymentID: resolved.DeploymentId,
+				BasePath:     origins.basePath(key, resolved.Bucket, resolved.Region) + resolved.DeploymentId + "/",
+				PathPrefixes: append(pathPrefixes, resolved.DeploymentId),
+				SPAFallback:  spaFallback,
 			}
-
-			//Read the response body with the deployment ID
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				http.Error(w, "Failed to read response body for deployment ID", http.StatusInternalServerError)
-				return
+			if resolved.SpaFallback != nil {
+				target.SPAFallback = *resolved.SpaFallback
 			}
-			log.Printf("Response body: %s", string(body))
+			return target, nil
+		})
+	})
 
-			var response SubDomainResponse
-			if err := json.Unmarshal(body, &response); err != nil {
-				log.Printf("Error unmarshalling response body: %v", err)
-				http.Error(w, "Failed to unmarshal response body for deployment ID", http.StatusInternalServerError)
-				return
-			}
-			log.Printf("Deployment ID: %s", response.DeploymentId)
-			if response.DeploymentId == "" {
-				log.Printf("No deployment ID found for subdomain: %s", subDomain)
-				http.Error(w, "No deployment ID found", http.StatusNotFound)
-				return
+	// Clients are rate limited per IP if RATE_LIMIT is set, more strictly for uncached resolves
+	limits := newRateLimits(cfg)
+	trustedProxies := cfg.TrustedProxies
+
+	resolveTarget := func(r *http.Request) (proxy.Target, error) {
+		host := hostname(r.Host)
+		// Anyone else could make every request reach the API server
+		bypass := r.Header.Get(bypassResolveCacheHeader) != "" && proxy.ContainsAddr(trustedProxies, proxy.PeerAddr(r))
+
+		// Custom domains are mapped to deployments statically or by the API server
+		subDomain, ok := subdomainOf(host, baseDomain)
+		if !ok {
+			if deploymentID, ok := customDomains[host]; ok {
+				return proxy.Target{
+					DeploymentID: deploymentID,
+					BasePath:     origins.basePath(host, "", "") + deploymentID + "/",
+					PathPrefixes: []string{deploymentID},
+					SPAFallback:  spaFallback,
+				}, nil
 			}
-			deploymentId = response.DeploymentId
+			return limits.limitResolve(resolveCache, r, host, bypass)
 		}
 
-		// Construct the S3 URL for the deployment
-		resolvesTo := basePath + deploymentId + "/"
-		log.Printf("Resolves to: %s", resolvesTo)
-		targetUrl, err := url.Parse(resolvesTo)
-		if err != nil {
-			log.Printf("Error parsing resolvesTo URL: %v", err)
-			http.Error(w, "Failed to parse resolvesTo URL", http.StatusInternalServerError)
-			return
+		// Validate the slug pattern and check if the deployment ID is being fetched from the API server
+		if slugPattern.MatchString(subDomain) {
+			return limits.limitResolve(resolveCache, r, subDomain, bypass)
 		}
 
-		//Properly append index.html to the URL
-		urlPath := r.URL.Path
-		if urlPath == "/" || urlPath == "" {
-			urlPath = "/index.html"
-			r.URL.Path = urlPath
+		// Construct the S3 URL for the deployment
+		return proxy.Target{
+			DeploymentID: subDomain,
+			BasePath:     origins.basePath(subDomain, "", "") + subDomain + "/",
+			PathPrefixes: []string{subDomain},
+			SPAFallback:  spaFallback,
+		}, nil
+	}
+
+	resolveTarget = recordDeployment(resolveTarget)
+	var upstreamTransport http.RoundTripper = newUpstreamTransport()
+	if s3Credentials != nil {
+		upstreamTransport = signS3Requests(s3Credentials, cfg.Region, upstreamTransport)
+	}
+	transport := recordUpstreamLatency(upstreamTransport)
+
+	// Metrics are served on their own port, since every path on the main one belongs to a deployment
+	var m *metrics
+	if metricsPort := cfg.MetricsPort; metricsPort != "" {
+		// Per-subdomain labels add a series per deployment, so they're opt-in
+		m = newMetrics(cfg.MetricsPerSubdomain, assetCache, resolveCache, breaker, limits)
+		resolveTarget = m.instrumentResolver(resolveTarget)
+		client.Transport = m.instrumentAPITransport(http.DefaultTransport)
+		transport = m.instrumentTransport(transport)
+		go m.serve(metricsPort)
+	}
+
+	handlerOpts = append(handlerOpts, proxy.WithTransport(transport))
+	handler := limits.limitRequests(proxy.Handler(resolveTarget, handlerOpts...))
+	if m != nil {
+		handler = m.instrument(handler)
+	}
+	// In-flight requests get SHUTDOWN_GRACE_PERIOD to finish when the proxy is stopped
+	gracePeriod := cfg.ShutdownGracePeriod
+	// TLS is terminated by the proxy itself if TLS_MODE is auto or manual
+	tlsSettings := cfg.TLS
+	// Security headers are added to responses that don't set them, configured with SECURITY_HEADERS*
+	handler = proxy.SecurityHeaders(cfg.SecurityHeaders, handler)
+	// Browser caching is set for responses without a Cache-Control, configured with CACHE_CONTROL
+	// and HASHED_ASSET_PATTERN
+	handler = proxy.CacheControl(cfg.HashedAssetPattern, handler)
+	// The client IP of requests from TRUSTED_PROXIES is taken from their forwarded headers, which
+	// are dropped from any other request
+	handler = proxy.RealIP(trustedProxies, logAccess(logger, handler))
+	servers, err := newServers(cfg.Port, proxy.RequestID(handler), tlsSettings)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The API server purges the resolve cache through ADMIN_PORT, authorized with ADMIN_TOKEN
+	if cfg.AdminPort != "" {
+		servers = append(servers, newServer(cfg.AdminPort, adminHandler(cfg.AdminToken, resolveCache)))
+		slog.Info("Admin endpoints are served", "port", cfg.AdminPort)
+	} else {
+		slog.Warn("ADMIN_PORT is not set, so the API server can't purge the resolve cache; new and promoted deployments are served once cached entries expire")
+	}
+
+	slog.Info("Server is running", "port", cfg.Port, "tls", tlsSettings.mode)
+	if err := serveUntilSignalled(servers, gracePeriod); err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("Server stopped")
+}
+
+// buildBasePath joins the upstream base URL and the output prefix into the URL that deployments
+// live under, failing if it isn't an absolute http(s) URL
+func buildBasePath(upstreamBaseUrl string, outputPrefix string) (string, error) {
+	basePath := strings.TrimSuffix(upstreamBaseUrl, "/") + "/"
+	if prefix := strings.Trim(outputPrefix, "/"); prefix != "" {
+		basePath += prefix + "/"
+	}
+
+	parsed, err := url.Parse(basePath)
+	if err != nil {
+		return "", fmt.Errorf("invalid upstream base URL %q: %w", basePath, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("invalid upstream base URL %q: must be an absolute http or https URL", basePath)
+	}
+	return basePath, nil
+}
+
+// resolveCustomDomain asks the API server which deployment a custom domain points to, failing
+// with a 404 explaining that the domain isn't set up if it points to none
+func resolveCustomDomain(ctx context.Context, client *http.Client, apiServerUrl string, host string) (*SubDomainResponse, error) {
+	notConnected := &proxy.ResolveError{
+		StatusCode: http.StatusNotFound,
+		Message:    fmt.Sprintf("The domain %s isn't connected to a Yok deployment. If it's yours, add it to your project to serve your site here.", host),
+	}
+
+	resolved, err := resolveDeployment(ctx, client, fmt.Sprintf("%s/resolve/domain/%s", apiServerUrl, url.PathEscape(host)), host)
+	var resolveErr *proxy.ResolveError
+	if errors.As(err, &resolveErr) && resolveErr.StatusCode == http.StatusNotFound {
+		return nil, notConnected
+	}
+	return resolved, err
+}
+
+// Resolve requests to the API server are retried with exponential backoff when it can't be
+// reached or fails, so a brief outage doesn't fail the requests of slugs that aren't cached
+const (
+	resolveAttempts       = 3
+	resolveAttemptTimeout = 2 * time.Second
+	resolveBackoff        = 100 * time.Millisecond
+)
+
+// apiUnavailable is the error served when the API server couldn't tell which deployment to serve
+func apiUnavailable(err error) *proxy.ResolveError {
+	return &proxy.ResolveError{
+		StatusCode: http.StatusBadGateway,
+		Message:    "This site is temporarily unavailable because its deployment couldn't be looked up. Please try again in a moment.",
+		Err:        fmt.Errorf("%w: %w", errAPIUnavailable, err),
+	}
+}
+
+// resolveDeployment asks the API server at apiUrl which deployment a project slug or custom
+// domain currently points to, passing on the ID of the request in ctx. Failed requests are
+// retried up to resolveAttempts times; the API server answering that there is no deployment is not.
+func resolveDeployment(ctx context.Context, client *http.Client, apiUrl string, subDomain string) (*SubDomainResponse, error) {
+	slog.DebugContext(ctx, "Resolving deployment", "subdomain", subDomain)
+
+	backoff := resolveBackoff
+	for attempt := 1; ; attempt++ {
+		resolved, retry, err := resolveDeploymentOnce(ctx, client, apiUrl, subDomain)
+		if err == nil || !retry || attempt == resolveAttempts || ctx.Err() != nil {
+			return resolved, err
 		}
 
-		// Check if the assets folder is nested or not and resolve it
-		pathRegex := regexp.MustCompile(`^/([^/]+)/(.*)$`)
-		pathMatch := pathRegex.FindStringSubmatch(urlPath)
-
-		if pathMatch != nil {
-			firstSegment := pathMatch[1]
-			remainingPath := pathMatch[2]
-			knownAssetDirs := map[string]bool{
-				"assets": true,
-				"images": true,
-				"static": true,
-				"media":  true,
-				"_next":  true,
-				"js":     true,
-				"css":    true,
-			}
-			if !knownAssetDirs[firstSegment] {
-				r.URL.Path = "/" + remainingPath
-				log.Printf("Rewriting path from %s to %s", urlPath, r.URL.Path)
-			}
+		slog.WarnContext(ctx, "Retrying deployment resolve", "subdomain", subDomain, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
 		}
+		backoff *= 2
+	}
+}
 
-		// Create a reverse proxy to the target URL
-		proxy := httputil.NewSingleHostReverseProxy(targetUrl)
+// resolveDeploymentOnce sends a single resolve request to the API server, reporting whether it
+// is worth retrying if it fails
+func resolveDeploymentOnce(ctx context.Context, client *http.Client, apiUrl string, subDomain string) (*SubDomainResponse, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, resolveAttemptTimeout)
+	defer cancel()
 
-		ogDirector := proxy.Director
-		proxy.Director = func(req *http.Request) {
-			ogDirector(req)
-			req.Host = targetUrl.Host
-			req.Header.Set("Host", targetUrl.Host)
-		}
-		proxy.ServeHTTP(w, r)
-	})
-	fmt.Printf("Server is running on port %s\n", PORT)
-	log.Fatal(http.ListenAndServe(":"+PORT, nil))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiUrl, nil)
+	if err != nil {
+		return nil, false, &proxy.ResolveError{StatusCode: http.StatusInternalServerError, Message: "Failed to receive deployment Id", Err: err}
+	}
+	if id := proxy.RequestIDFrom(ctx); id != "" {
+		req.Header.Set(proxy.RequestIDHeader, id)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to resolve deployment", "subdomain", subDomain, "error", err)
+		return nil, true, apiUnavailable(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		slog.WarnContext(ctx, "No deployment found", "subdomain", subDomain)
+		return nil, false, &proxy.ResolveError{StatusCode: http.StatusNotFound, Message: "No deployment ID found"}
+	}
+	if resp.StatusCode != http.StatusOK {
+		slog.ErrorContext(ctx, "Failed to resolve deployment", "subdomain", subDomain, "status", resp.StatusCode)
+		retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retry, apiUnavailable(fmt.Errorf("API server answered %s", resp.Status))
+	}
+
+	//Read the response body with the deployment ID
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to read resolve response", "subdomain", subDomain, "error", err)
+		return nil, true, apiUnavailable(err)
+	}
+
+	var response SubDomainResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		slog.ErrorContext(ctx, "Failed to parse resolve response", "subdomain", subDomain, "error", err)
+		return nil, false, apiUnavailable(err)
+	}
+	if response.DeploymentId == "" {
+		slog.WarnContext(ctx, "No deployment found", "subdomain", subDomain)
+		return nil, false, &proxy.ResolveError{StatusCode: http.StatusNotFound, Message: "No deployment ID found"}
+	}
+
+	slog.DebugContext(ctx, "Resolved deployment", "subdomain", subDomain, "deployment_id", response.DeploymentId)
+	return &response, false, nil
+}
+
+// envOr returns the environment variable name, or def if it isn't set
+func envOr(name string, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
 }
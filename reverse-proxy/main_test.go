@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+func TestResolveDeploymentRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []int // Status codes answered in turn, the last one repeated
+		body      string
+		wantCalls int32
+		wantCode  int // Status code of the ResolveError, or 0 for success
+	}{
+		{"success", []int{http.StatusOK}, `{"deploymentId":"d1"}`, 1, 0},
+		{"recovers", []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, `{"deploymentId":"d1"}`, 3, 0},
+		{"rate limited", []int{http.StatusTooManyRequests, http.StatusOK}, `{"deploymentId":"d1"}`, 2, 0},
+		{"down", []int{http.StatusInternalServerError}, "", resolveAttempts, http.StatusBadGateway},
+		{"no deployment", []int{http.StatusNotFound}, "", 1, http.StatusNotFound},
+		{"empty deployment", []int{http.StatusOK}, `{"deploymentId":""}`, 1, http.StatusNotFound},
+		{"bad request", []int{http.StatusBadRequest}, "", 1, http.StatusBadGateway},
+		{"malformed response", []int{http.StatusOK}, "<html>", 1, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get(proxy.RequestIDHeader); got != "req-123" {
+					t.Errorf("%s = %q, want the request's ID", proxy.RequestIDHeader, got)
+				}
+				call := int(calls.Add(1))
+				w.WriteHeader(tt.responses[min(call, len(tt.responses))-1])
+				w.Write([]byte(tt.body))
+			}))
+			defer api.Close()
+
+			ctx := proxy.ContextWithRequestID(context.Background(), "req-123")
+			resolved, err := resolveDeployment(ctx, api.Client(), api.URL+"/resolve/brave-fox", "brave-fox")
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("API server called %d times, want %d", got, tt.wantCalls)
+			}
+			if tt.wantCode == 0 {
+				if err != nil || resolved.DeploymentId != "d1" {
+					t.Errorf("resolveDeployment = %+v, %v, want d1", resolved, err)
+				}
+				return
+			}
+			var resolveErr *proxy.ResolveError
+			if !errors.As(err, &resolveErr) || resolveErr.StatusCode != tt.wantCode {
+				t.Fatalf("err = %v, want a %d ResolveError", err, tt.wantCode)
+			}
+			// Only failures of the API server count toward opening the circuit breaker
+			if got, want := errors.Is(err, errAPIUnavailable), tt.wantCode == http.StatusBadGateway; got != want {
+				t.Errorf("errors.Is(err, errAPIUnavailable) = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+// version is the proxy's build version, set with -ldflags "-X main.version=..."
+var version = "dev"
+
+// metrics holds the proxy's Prometheus collectors. Labels are kept to status classes, and to
+// subdomains only when perSubdomain is set, so the number of series stays bounded.
+type metrics struct {
+	registry     *prometheus.Registry
+	perSubdomain bool
+
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	responseBytes    *prometheus.CounterVec
+	resolveFailures  *prometheus.CounterVec
+	apiRequests      *prometheus.CounterVec
+	upstreamErrors   *prometheus.CounterVec
+	upstreamDuration prometheus.Histogram
+}
+
+// newMetrics registers the proxy's metrics, including the counters of the caches and rate limits
+// in use and the state of the circuit breaker around the API server
+func newMetrics(perSubdomain bool, assetCache *proxy.AssetCache, resolveCache *proxy.ResolveCache, breaker *circuitBreaker, limits *rateLimits) *metrics {
+	requestLabels := []string{"code_class"}
+	if perSubdomain {
+		requestLabels = append(requestLabels, "subdomain")
+	}
+
+	m := &metrics{
+		registry:     prometheus.NewRegistry(),
+		perSubdomain: perSubdomain,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yok_proxy_requests_total",
+			Help: "Requests handled, by status class.",
+		}, requestLabels),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "yok_proxy_request_duration_seconds",
+			Help:    "Time taken to handle requests, by status class.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"code_class"}),
+		responseBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yok_proxy_response_bytes_total",
+			Help: "Response body bytes served, by status class.",
+		}, requestLabels),
+		resolveFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yok_proxy_resolve_failures_total",
+			Help: "Requests whose deployment couldn't be resolved, by the status code returned.",
+		}, []string{"code"}),
+		apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yok_proxy_api_requests_total",
+			Help: "Requests sent to the API server to resolve deployments, including retries, by outcome (ok, not_found, 5xx, other or error).",
+		}, []string{"outcome"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yok_proxy_upstream_errors_total",
+			Help: "Failed requests to the object store, by reason (error or 5xx).",
+		}, []string{"reason"}),
+		upstreamDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "yok_proxy_upstream_duration_seconds",
+			Help:    "Time taken by the object store to respond.",
+			Buckets: prometheus.DefBuckets,
+		}),
+	}
+
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "yok_proxy_build_info",
+		Help:        "Build information about the proxy, always 1.",
+		ConstLabels: prometheus.Labels{"version": version},
+	})
+	buildInfo.Set(1)
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		buildInfo,
+		m.requests,
+		m.requestDuration,
+		m.responseBytes,
+		m.resolveFailures,
+		m.apiRequests,
+		m.upstreamErrors,
+		m.upstreamDuration,
+	)
+
+	if resolveCache != nil {
+		m.registry.MustRegister(
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "yok_proxy_resolve_cache_hits_total",
+				Help: "Slugs resolved from the resolve cache.",
+			}, func() float64 { return float64(resolveCache.Stats().Hits) }),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "yok_proxy_resolve_cache_misses_total",
+				Help: "Slugs resolved with the API server while the request waited.",
+			}, func() float64 { return float64(resolveCache.Stats().Misses) }),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "yok_proxy_resolve_cache_negative_hits_total",
+				Help: "Requests for slugs without a deployment answered from the resolve cache.",
+			}, func() float64 { return float64(resolveCache.Stats().NegativeHits) }),
+		)
+	}
+
+	if breaker != nil {
+		m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "yok_proxy_api_circuit_state",
+			Help: "State of the circuit breaker around the API server: 0 closed, 1 half-open, 2 open.",
+		}, func() float64 { return float64(breaker.State()) }))
+	}
+
+	if limits != nil {
+		for name, limiter := range map[string]*proxy.RateLimiter{"requests": limits.requests, "resolves": limits.resolves} {
+			labels := prometheus.Labels{"limit": name}
+			m.registry.MustRegister(
+				prometheus.NewCounterFunc(prometheus.CounterOpts{
+					Name:        "yok_proxy_rate_limited_total",
+					Help:        "Requests refused with a 429 for exceeding a per-client rate limit, by limit (requests or resolves).",
+					ConstLabels: labels,
+				}, func() float64 { return float64(limiter.Stats().Limited) }),
+				prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+					Name:        "yok_proxy_rate_limit_clients",
+					Help:        "Clients tracked by a per-client rate limit, by limit (requests or resolves).",
+					ConstLabels: labels,
+				}, func() float64 { return float64(limiter.Stats().Clients) }),
+			)
+		}
+	}
+
+	if assetCache != nil {
+		m.registry.MustRegister(
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "yok_proxy_asset_cache_hits_total",
+				Help: "Requests served from the asset cache.",
+			}, func() float64 { return float64(assetCache.Stats().Hits) }),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "yok_proxy_asset_cache_misses_total",
+				Help: "Cacheable requests not found in the asset cache.",
+			}, func() float64 { return float64(assetCache.Stats().Misses) }),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "yok_proxy_asset_cache_evictions_total",
+				Help: "Responses evicted from the asset cache to make room.",
+			}, func() float64 { return float64(assetCache.Stats().Evictions) }),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "yok_proxy_asset_cache_entries",
+				Help: "Responses in the asset cache.",
+			}, func() float64 { return float64(assetCache.Stats().Entries) }),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "yok_proxy_asset_cache_bytes",
+				Help: "Size of the response bodies in the asset cache.",
+			}, func() float64 { return float64(assetCache.Stats().Bytes) }),
+		)
+	}
+
+	return m
+}
+
+// instrument records the status class, duration and size of the responses of next
+func (m *metrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		codeClass := fmt.Sprintf("%dxx", recorder.status/100)
+		labels := []string{codeClass}
+		if m.perSubdomain {
+			labels = append(labels, strings.Split(r.Host, ".")[0])
+		}
+
+		m.requests.WithLabelValues(labels...).Inc()
+		m.responseBytes.WithLabelValues(labels...).Add(float64(recorder.bytes))
+		m.requestDuration.WithLabelValues(codeClass).Observe(time.Since(start).Seconds())
+	})
+}
+
+// instrumentResolver counts the requests resolve fails for
+func (m *metrics) instrumentResolver(resolve proxy.TargetResolver) proxy.TargetResolver {
+	return func(r *http.Request) (proxy.Target, error) {
+		target, err := resolve(r)
+		if err != nil {
+			code := http.StatusInternalServerError
+			var resolveErr *proxy.ResolveError
+			if errors.As(err, &resolveErr) {
+				code = resolveErr.StatusCode
+			}
+			m.resolveFailures.WithLabelValues(strconv.Itoa(code)).Inc()
+		}
+		return target, err
+	}
+}
+
+// instrumentTransport times the requests sent to the object store and counts the failed ones
+func (m *metrics) instrumentTransport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		m.upstreamDuration.Observe(time.Since(start).Seconds())
+
+		switch {
+		case err != nil:
+			m.upstreamErrors.WithLabelValues("error").Inc()
+		case resp.StatusCode >= http.StatusInternalServerError:
+			m.upstreamErrors.WithLabelValues("5xx").Inc()
+		}
+		return resp, err
+	})
+}
+
+// instrumentAPITransport counts the requests sent to the API server by their outcome
+func (m *metrics) instrumentAPITransport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		switch {
+		case err != nil:
+			m.apiRequests.WithLabelValues("error").Inc()
+		case resp.StatusCode == http.StatusOK:
+			m.apiRequests.WithLabelValues("ok").Inc()
+		case resp.StatusCode == http.StatusNotFound:
+			m.apiRequests.WithLabelValues("not_found").Inc()
+		case resp.StatusCode >= http.StatusInternalServerError:
+			m.apiRequests.WithLabelValues("5xx").Inc()
+		default:
+			m.apiRequests.WithLabelValues("other").Inc()
+		}
+		return resp, err
+	})
+}
+
+// serve serves the metrics on port
+func (m *metrics) serve(port string) {
+	slog.Info("Metrics are served", "port", port)
+	log.Fatal(newServer(port, m.handler()).ListenAndServe())
+}
+
+// handler serves the metrics in the Prometheus text format at /metrics
+func (m *metrics) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// statusRecorder remembers the status code and body size written through it
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// Flush lets the reverse proxy flush streamed responses through the recorder
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// roundTripperFunc adapts a function to an http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
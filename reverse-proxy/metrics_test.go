@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+// scrape returns the metrics served by handler
+func scrape(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+// newInstrumentedHandler returns a proxy handler instrumented by m, serving brave-fox from an
+// object store and answering any other slug with a 404
+func newInstrumentedHandler(m *metrics, resolveCache *proxy.ResolveCache) http.Handler {
+	resolve := m.instrumentResolver(func(r *http.Request) (proxy.Target, error) {
+		return resolveCache.Resolve(r.Context(), strings.Split(r.Host, ".")[0], false)
+	})
+	return m.instrument(proxy.Handler(resolve, proxy.WithTransport(m.instrumentTransport(http.DefaultTransport))))
+}
+
+// newMetricsResolveCache returns a resolve cache serving brave-fox from objects
+func newMetricsResolveCache(objects *httptest.Server) *proxy.ResolveCache {
+	return proxy.NewResolveCache(time.Minute, time.Minute, func(ctx context.Context, key string) (proxy.Target, error) {
+		if key != "brave-fox" {
+			return proxy.Target{}, &proxy.ResolveError{StatusCode: http.StatusNotFound, Message: "Deployment not found"}
+		}
+		return proxy.Target{DeploymentID: "d1", BasePath: objects.URL + "/d1/", PathPrefixes: []string{"d1"}}, nil
+	})
+}
+
+func newMetricsObjectStore(t *testing.T) *httptest.Server {
+	objects := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/d1/index.html":
+			io.WriteString(w, "home")
+		case "/d1/boom.js":
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(objects.Close)
+	return objects
+}
+
+func TestMetricsScrape(t *testing.T) {
+	resolveCache := newMetricsResolveCache(newMetricsObjectStore(t))
+	m := newMetrics(false, nil, resolveCache, nil, nil)
+	handler := newInstrumentedHandler(m, resolveCache)
+
+	requests := []struct {
+		url  string
+		want int
+	}{
+		{"http://brave-fox.yok.ninja/", http.StatusOK},
+		{"http://brave-fox.yok.ninja/", http.StatusOK},
+		{"http://brave-fox.yok.ninja/boom.js?v=1", http.StatusInternalServerError},
+		{"http://quiet-gray-owl.yok.ninja/", http.StatusNotFound},
+		{"http://quiet-gray-owl.yok.ninja/about", http.StatusNotFound},
+	}
+	for _, req := range requests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, req.url, nil))
+		if rec.Code != req.want {
+			t.Fatalf("GET %s = %d, want %d", req.url, rec.Code, req.want)
+		}
+	}
+
+	out := scrape(t, m.handler())
+	for _, want := range []string{
+		`yok_proxy_build_info{version="dev"} 1`,
+		`yok_proxy_requests_total{code_class="2xx"} 2`,
+		`yok_proxy_requests_total{code_class="4xx"} 2`,
+		`yok_proxy_requests_total{code_class="5xx"} 1`,
+		`yok_proxy_response_bytes_total{code_class="2xx"} 8`,
+		`yok_proxy_request_duration_seconds_count{code_class="2xx"} 2`,
+		`yok_proxy_resolve_failures_total{code="404"} 2`,
+		`yok_proxy_upstream_errors_total{reason="5xx"} 1`,
+		`yok_proxy_upstream_duration_seconds_count 3`,
+		`yok_proxy_resolve_cache_hits_total 2`,
+		`yok_proxy_resolve_cache_negative_hits_total 1`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("metrics are missing %s", want)
+		}
+	}
+	// Paths and subdomains would add a series per page or deployment
+	for _, unwanted := range []string{"boom.js", "brave-fox", "subdomain="} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("metrics contain %q", unwanted)
+		}
+	}
+}
+
+func TestMetricsPerSubdomain(t *testing.T) {
+	resolveCache := newMetricsResolveCache(newMetricsObjectStore(t))
+	m := newMetrics(true, nil, resolveCache, nil, nil)
+	handler := newInstrumentedHandler(m, resolveCache)
+
+	for _, url := range []string{"http://brave-fox.yok.ninja/", "http://quiet-gray-owl.yok.ninja/"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+
+	out := scrape(t, m.handler())
+	for _, want := range []string{
+		`yok_proxy_requests_total{code_class="2xx",subdomain="brave-fox"} 1`,
+		`yok_proxy_requests_total{code_class="4xx",subdomain="quiet-gray-owl"} 1`,
+		// Histograms stay per status class only
+		`yok_proxy_request_duration_seconds_count{code_class="2xx"} 1`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("metrics are missing %s", want)
+		}
+	}
+}
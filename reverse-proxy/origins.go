@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Patterns of valid S3 bucket names and AWS regions, checked before they become part of a URL
+var (
+	bucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+)
+
+// s3HostRegionPattern finds the region in the host of a regional S3 URL
+var s3HostRegionPattern = regexp.MustCompile(`\.s3[.-]([a-z0-9-]+)\.amazonaws\.com$`)
+
+// originOverride serves the deployments of keys starting with prefix from another bucket
+type originOverride struct {
+	prefix   string
+	basePath string
+}
+
+// origins picks the base path each deployment is served under: the bucket and region the API
+// server resolved it to, an ORIGIN_OVERRIDES entry for its key, or the default one
+type origins struct {
+	defaultBasePath string
+	defaultBucket   string
+	defaultRegion   string
+	outputPrefix    string
+	overrides       []originOverride
+}
+
+// newOrigins returns the origins serving deployments from defaultBasePath unless overridden by
+// overrides, comma-separated prefix=bucket:region entries such as "acme-=acme-sites:eu-west-1".
+// The longest matching prefix wins.
+func newOrigins(defaultBasePath, defaultBucket, defaultRegion, outputPrefix, overrides string) (*origins, error) {
+	o := &origins{
+		defaultBasePath: defaultBasePath,
+		defaultBucket:   defaultBucket,
+		defaultRegion:   defaultRegion,
+		outputPrefix:    outputPrefix,
+	}
+	for _, entry := range strings.Split(overrides, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		prefix, location, ok := strings.Cut(entry, "=")
+		bucket, region, hasRegion := strings.Cut(location, ":")
+		if !ok || !hasRegion || strings.TrimSpace(prefix) == "" {
+			return nil, fmt.Errorf("invalid ORIGIN_OVERRIDES entry %q: must be prefix=bucket:region", entry)
+		}
+		basePath, err := o.bucketBasePath(strings.TrimSpace(bucket), strings.TrimSpace(region))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ORIGIN_OVERRIDES entry %q: %w", entry, err)
+		}
+		o.overrides = append(o.overrides, originOverride{prefix: strings.ToLower(strings.TrimSpace(prefix)), basePath: basePath})
+	}
+	sort.SliceStable(o.overrides, func(i, j int) bool {
+		return len(o.overrides[i].prefix) > len(o.overrides[j].prefix)
+	})
+	return o, nil
+}
+
+// basePath returns the base path the deployments of key, a slug, deployment ID or custom domain,
+// are served under. bucket and region are what the API server resolved key to, if anything; a
+// missing one falls back to AWS_S3_BUCKET or AWS_REGION.
+func (o *origins) basePath(key, bucket, region string) string {
+	if bucket != "" || region != "" {
+		if bucket == "" {
+			bucket = o.defaultBucket
+		}
+		if region == "" {
+			region = o.defaultRegion
+		}
+		basePath, err := o.bucketBasePath(bucket, region)
+		if err == nil {
+			return basePath
+		}
+		slog.Warn("Ignoring the origin resolved by the API server", "key", key, "error", err)
+	}
+
+	key = strings.ToLower(key)
+	for _, override := range o.overrides {
+		if strings.HasPrefix(key, override.prefix) {
+			return override.basePath
+		}
+	}
+	return o.defaultBasePath
+}
+
+// bucketBasePath returns the base path of deployments stored in bucket in region
+func (o *origins) bucketBasePath(bucket, region string) (string, error) {
+	if !bucketPattern.MatchString(bucket) {
+		return "", fmt.Errorf("invalid bucket %q", bucket)
+	}
+	if !regionPattern.MatchString(region) {
+		return "", fmt.Errorf("invalid region %q", region)
+	}
+	return buildBasePath(fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", bucket, region), o.outputPrefix)
+}
+
+// s3Region returns the region in host if it is a regional S3 host, or fallback otherwise
+func s3Region(host, fallback string) string {
+	if match := s3HostRegionPattern.FindStringSubmatch(strings.ToLower(hostname(host))); match != nil {
+		return match[1]
+	}
+	return fallback
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOriginsBasePath(t *testing.T) {
+	o, err := newOrigins("https://yok-outputs.s3.ap-south-1.amazonaws.com/__output/", "yok-outputs", "ap-south-1", "__output/",
+		"acme-=acme-sites:eu-west-1, acme-eu-=acme-eu:eu-central-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, bucket, region string
+		want                string
+	}{
+		{"brave-fox", "", "", "https://yok-outputs.s3.ap-south-1.amazonaws.com/__output/"},
+		{"brave-fox", "other-bucket", "us-east-1", "https://other-bucket.s3.us-east-1.amazonaws.com/__output/"},
+		// A missing bucket or region falls back to the default
+		{"brave-fox", "other-bucket", "", "https://other-bucket.s3.ap-south-1.amazonaws.com/__output/"},
+		{"brave-fox", "", "us-east-1", "https://yok-outputs.s3.us-east-1.amazonaws.com/__output/"},
+		// Invalid origins from the API server are ignored
+		{"brave-fox", "evil.example/x", "us-east-1", "https://yok-outputs.s3.ap-south-1.amazonaws.com/__output/"},
+		// The longest override prefix wins, case-insensitively
+		{"acme-shop", "", "", "https://acme-sites.s3.eu-west-1.amazonaws.com/__output/"},
+		{"ACME-EU-shop", "", "", "https://acme-eu.s3.eu-central-1.amazonaws.com/__output/"},
+		// The API server's origin wins over an override
+		{"acme-shop", "other-bucket", "us-east-1", "https://other-bucket.s3.us-east-1.amazonaws.com/__output/"},
+	}
+	for _, tt := range tests {
+		if got := o.basePath(tt.key, tt.bucket, tt.region); got != tt.want {
+			t.Errorf("basePath(%q, %q, %q) = %q, want %q", tt.key, tt.bucket, tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestOriginsInvalidOverrides(t *testing.T) {
+	for _, overrides := range []string{"acme-", "acme-=acme-sites", "=acme-sites:eu-west-1", "acme-=Acme_Sites:eu-west-1", "acme-=acme-sites:mars"} {
+		if _, err := newOrigins("", "yok-outputs", "ap-south-1", "__output/", overrides); err == nil || !strings.Contains(err.Error(), "ORIGIN_OVERRIDES") {
+			t.Errorf("newOrigins(%q) error = %v, want an invalid ORIGIN_OVERRIDES entry", overrides, err)
+		}
+	}
+}
+
+func TestS3Region(t *testing.T) {
+	tests := []struct {
+		host, want string
+	}{
+		{"acme-sites.s3.eu-west-1.amazonaws.com", "eu-west-1"},
+		{"acme-sites.s3-eu-west-1.amazonaws.com:443", "eu-west-1"},
+		{"acme-sites.s3.amazonaws.com", "ap-south-1"},
+		{"d1234abcd.cloudfront.net", "ap-south-1"},
+	}
+	for _, tt := range tests {
+		if got := s3Region(tt.host, "ap-south-1"); got != tt.want {
+			t.Errorf("s3Region(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/velgardey/yok/cli/proxy"
+)
+
+// Defaults for the per-client rate limits, in requests per second and burst sizes. Resolves
+// reach the API server, so they're limited much more strictly than cached requests.
+const (
+	defaultRateLimitRPS          = 50
+	defaultRateLimitBurst        = 100
+	defaultRateLimitResolveRPS   = 2
+	defaultRateLimitResolveBurst = 10
+)
+
+// rateLimits limits the requests of each client IP, and separately the requests that need the
+// API server to resolve their deployment
+type rateLimits struct {
+	requests  *proxy.RateLimiter
+	resolves  *proxy.RateLimiter
+	allowlist []netip.Prefix
+}
+
+// newRateLimits returns the rate limits configured with RATE_LIMIT*, or nil if RATE_LIMIT isn't
+// set. They're off by default since behind a load balancer, unless TRUSTED_PROXIES lists it,
+// every client has the load balancer's address and would share a single limit.
+func newRateLimits(cfg Config) *rateLimits {
+	if !cfg.RateLimit {
+		return nil
+	}
+	return &rateLimits{
+		requests:  proxy.NewRateLimiter(float64(cfg.RateLimitRPS), cfg.RateLimitBurst),
+		resolves:  proxy.NewRateLimiter(float64(cfg.RateLimitResolveRPS), cfg.RateLimitResolveBurst),
+		allowlist: cfg.RateLimitAllowlist,
+	}
+}
+
+// client returns the key the requests of r's client are limited by, or an empty string if the
+// client is allowlisted
+func (l *rateLimits) client(r *http.Request) string {
+	addr := proxy.ClientIPFrom(r.Context())
+	if !addr.IsValid() {
+		return r.RemoteAddr
+	}
+	if proxy.ContainsAddr(l.allowlist, addr) {
+		return ""
+	}
+	return addr.String()
+}
+
+// limitRequests refuses the requests of clients over the request limit
+func (l *rateLimits) limitRequests(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return proxy.RateLimit(l.requests, l.client, next)
+}
+
+// limitResolve fails resolving key for r with a 429 if it isn't cached, or bypass is set, and r's
+// client is over the resolve limit, and resolves it from resolveCache otherwise
+func (l *rateLimits) limitResolve(resolveCache *proxy.ResolveCache, r *http.Request, key string, bypass bool) (proxy.Target, error) {
+	if l != nil && (bypass || !resolveCache.Cached(key)) {
+		if client := l.client(r); client != "" {
+			if ok, retryAfter := l.resolves.Allow(client); !ok {
+				return proxy.Target{}, &proxy.ResolveError{
+					StatusCode: http.StatusTooManyRequests,
+					Message:    "Too many requests",
+					RetryAfter: retryAfter,
+				}
+			}
+		}
+	}
+	return resolveCache.Resolve(r.Context(), key, bypass)
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty body, which is what GET and HEAD requests send
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Endpoints of the AWS credential sources used when the environment has no access key
+const (
+	ecsCredentialsHost     = "http://169.254.170.2"
+	instanceMetadataURL    = "http://169.254.169.254/latest"
+	instanceMetadataRole   = instanceMetadataURL + "/meta-data/iam/security-credentials/"
+	instanceMetadataTTL    = "21600"
+	credentialsTimeout     = 2 * time.Second
+	credentialsRefreshSkew = 5 * time.Minute
+)
+
+// awsCredentials are the keys requests to S3 are signed with. Expires is zero for keys that
+// don't expire.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time
+}
+
+// credentialSource looks up AWS credentials, e.g. from the instance metadata service
+type credentialSource func(ctx context.Context) (awsCredentials, error)
+
+// awsCredentialsProvider hands out the credentials of a source, looking them up again shortly
+// before they expire
+type awsCredentialsProvider struct {
+	source credentialSource
+
+	mu          sync.Mutex
+	credentials awsCredentials
+	fetched     bool
+}
+
+// newAWSCredentialsProvider returns a provider using the standard AWS credential sources, in
+// the order the AWS SDKs use them: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, then the
+// AWS_PROFILE profile of the shared credentials file, then the ECS task role, then the EC2
+// instance profile unless AWS_EC2_METADATA_DISABLED is set
+func newAWSCredentialsProvider() *awsCredentialsProvider {
+	client := &http.Client{Timeout: credentialsTimeout}
+	var source credentialSource
+	switch {
+	case os.Getenv("AWS_ACCESS_KEY_ID") != "" || os.Getenv("AWS_SECRET_ACCESS_KEY") != "":
+		source = envCredentials
+	case sharedCredentialsPath() != "":
+		source = sharedCredentials
+	case os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "":
+		source = containerCredentials(client)
+	case strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true"):
+		source = func(context.Context) (awsCredentials, error) {
+			return awsCredentials{}, errors.New("no AWS credentials in the environment and the instance metadata service is disabled")
+		}
+	default:
+		source = instanceCredentials(client)
+	}
+	return &awsCredentialsProvider{source: source}
+}
+
+// Retrieve returns the current credentials, looking them up if there are none yet or they are
+// about to expire. If a refresh fails, credentials that haven't expired yet keep being used.
+func (p *awsCredentialsProvider) Retrieve(ctx context.Context) (awsCredentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.fetched && (p.credentials.Expires.IsZero() || now.Before(p.credentials.Expires.Add(-credentialsRefreshSkew))) {
+		return p.credentials, nil
+	}
+
+	credentials, err := p.source(ctx)
+	if err != nil {
+		if p.fetched && now.Before(p.credentials.Expires) {
+			return p.credentials, nil
+		}
+		return awsCredentials{}, fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+	p.credentials, p.fetched = credentials, true
+	return credentials, nil
+}
+
+// envCredentials reads static credentials from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN
+func envCredentials(context.Context) (awsCredentials, error) {
+	credentials := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return awsCredentials{}, errors.New("set both AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return credentials, nil
+}
+
+// sharedCredentialsPath returns the shared credentials file, AWS_SHARED_CREDENTIALS_FILE or
+// ~/.aws/credentials, or an empty string if it doesn't exist
+func sharedCredentialsPath() string {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// sharedCredentials reads the keys of the AWS_PROFILE profile, or the default one, from the
+// shared credentials file
+func sharedCredentials(context.Context) (awsCredentials, error) {
+	path := sharedCredentialsPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	profile := envOr("AWS_PROFILE", "default")
+
+	var credentials awsCredentials
+	section := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section != profile {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "aws_access_key_id":
+			credentials.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			credentials.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("no access key for profile %s in %s", profile, path)
+	}
+	return credentials, nil
+}
+
+// containerCredentials gets the credentials of the ECS task role from the container credentials
+// endpoint
+func containerCredentials(client *http.Client) credentialSource {
+	return func(ctx context.Context) (awsCredentials, error) {
+		endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+		if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+			endpoint = ecsCredentialsHost + relative
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		return fetchCredentials(client, req)
+	}
+}
+
+// instanceCredentials gets the credentials of the EC2 instance profile from the instance
+// metadata service, using IMDSv2 session tokens
+func instanceCredentials(client *http.Client) credentialSource {
+	return func(ctx context.Context) (awsCredentials, error) {
+		tokenReq, err := http.NewRequestWithContext(ctx, http.MethodPut, instanceMetadataURL+"/api/token", nil)
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		tokenReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", instanceMetadataTTL)
+		token, err := readMetadata(client, tokenReq)
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("instance metadata token: %w", err)
+		}
+
+		roleReq, err := http.NewRequestWithContext(ctx, http.MethodGet, instanceMetadataRole, nil)
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		roleReq.Header.Set("X-aws-ec2-metadata-token", token)
+		role, err := readMetadata(client, roleReq)
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("instance profile role: %w", err)
+		}
+		role, _, _ = strings.Cut(strings.TrimSpace(role), "\n")
+
+		credentialsReq, err := http.NewRequestWithContext(ctx, http.MethodGet, instanceMetadataRole+url.PathEscape(role), nil)
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		credentialsReq.Header.Set("X-aws-ec2-metadata-token", token)
+		return fetchCredentials(client, credentialsReq)
+	}
+}
+
+// readMetadata returns the body of a successful metadata service response
+func readMetadata(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	return string(body), err
+}
+
+// fetchCredentials requests credentials in the JSON format shared by the ECS and EC2 endpoints
+func fetchCredentials(client *http.Client, req *http.Request) (awsCredentials, error) {
+	body, err := readMetadata(client, req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	var response struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return awsCredentials{}, fmt.Errorf("invalid credentials response: %w", err)
+	}
+	if response.AccessKeyID == "" || response.SecretAccessKey == "" {
+		return awsCredentials{}, errors.New("credentials response has no access key")
+	}
+	return awsCredentials{
+		AccessKeyID:     response.AccessKeyID,
+		SecretAccessKey: response.SecretAccessKey,
+		SessionToken:    response.Token,
+		Expires:         response.Expiration,
+	}, nil
+}
+
+// signS3Requests signs every request sent with next with AWS Signature Version 4 for S3, so
+// objects can be read from a private bucket. Each request, including the extra ones made for
+// index.html and 404 pages, is signed right before it is sent, for the region in its host, or
+// region if the host has none.
+func signS3Requests(provider *awsCredentialsProvider, region string, next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		credentials, err := provider.Retrieve(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		signed := req.Clone(req.Context())
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+		signV4(signed, credentials, s3Region(host, region), time.Now())
+		return next.RoundTrip(signed)
+	})
+}
+
+// signV4 adds the headers signing req, which must have no body, for S3 at now. Headers left
+// over from an earlier signature and x-amz- headers sent by the client are dropped, since S3
+// rejects x-amz- headers that aren't signed. So is the query, which would otherwise let clients
+// pick signed subresources and parameters such as versionId or response-content-type.
+func signV4(req *http.Request, credentials awsCredentials, region string, now time.Time) {
+	for name := range req.Header {
+		if strings.HasPrefix(strings.ToLower(name), "x-amz-") {
+			req.Header.Del(name)
+		}
+	}
+	req.Header.Del("Authorization")
+
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	// Send the path exactly as it is signed
+	req.URL.RawPath = s3URIEncode(req.URL.Path, false)
+	req.URL.RawQuery = ""
+	req.URL.ForceQuery = false
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") || lower == "range" {
+			headers[lower] = strings.Join(values, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalURI := req.URL.RawPath
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// s3URIEncode percent-encodes every byte of s but unreserved characters, and slashes unless
+// encodeSlash is set, as Signature Version 4 requires
+func s3URIEncode(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			encoded.WriteByte('%')
+			encoded.WriteByte(hexDigits[c>>4])
+			encoded.WriteByte(hexDigits[c&15])
+		}
+	}
+	return encoded.String()
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
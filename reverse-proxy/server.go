@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Timeouts of the server, so slow clients can't hold connections open indefinitely
+const (
+	serverReadHeaderTimeout = 10 * time.Second
+	serverWriteTimeout      = 2 * time.Minute
+	serverIdleTimeout       = 2 * time.Minute
+)
+
+// Timeouts of the requests sent to the object store
+const (
+	upstreamDialTimeout           = 5 * time.Second
+	upstreamTLSHandshakeTimeout   = 5 * time.Second
+	upstreamResponseHeaderTimeout = 15 * time.Second
+	upstreamIdleConnTimeout       = 90 * time.Second
+)
+
+// defaultShutdownGracePeriod is how long in-flight requests may take to finish on shutdown
+const defaultShutdownGracePeriod = 30 * time.Second
+
+// newServer returns a server for handler on port with timeouts set
+func newServer(port string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+		WriteTimeout:      serverWriteTimeout,
+		IdleTimeout:       serverIdleTimeout,
+	}
+}
+
+// newUpstreamTransport returns the transport used for the object store, with its own timeouts
+// so an unresponsive upstream can't tie up requests
+func newUpstreamTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   upstreamDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = upstreamTLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = upstreamResponseHeaderTimeout
+	transport.IdleConnTimeout = upstreamIdleConnTimeout
+	transport.MaxIdleConnsPerHost = 100
+	return transport
+}
+
+// serveUntilSignalled runs servers until SIGINT or SIGTERM, then stops accepting connections
+// and waits up to gracePeriod for in-flight requests to finish
+func serveUntilSignalled(servers []*http.Server, gracePeriod time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return serveUntilDone(ctx, servers, gracePeriod)
+}
+
+// serveUntilDone runs servers until ctx is done, then shuts them down like serveUntilSignalled
+func serveUntilDone(ctx context.Context, servers []*http.Server, gracePeriod time.Duration) error {
+	serveErr := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			serveErr <- listenAndServe(server)
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, waiting for in-flight requests", "grace_period", gracePeriod.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	var errs []error
+	for _, server := range servers {
+		errs = append(errs, server.Shutdown(shutdownCtx))
+	}
+	for range servers {
+		if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// listenAndServe runs server, over TLS if it has a TLS config
+func listenAndServe(server *http.Server) error {
+	if server.TLSConfig != nil {
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
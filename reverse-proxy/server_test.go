@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// freePort returns a port nothing listens on
+func freePort(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port
+}
+
+// waitForListener waits until addr accepts connections, or stops doing so if listening is false
+func waitForListener(t *testing.T, addr string, listening bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		if (err == nil) == listening {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s: listening is not %v", addr, listening)
+}
+
+// slowServer returns a server on a free port whose /slow requests wait for release
+func slowServer(t *testing.T, started chan<- struct{}, release <-chan struct{}) (*http.Server, string) {
+	port := freePort(t)
+	server := newServer(port, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		io.WriteString(w, "done")
+	}))
+	return server, "127.0.0.1:" + port
+}
+
+func TestShutdownFinishesInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	server, addr := slowServer(t, started, release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- serveUntilDone(ctx, []*http.Server{server}, 5*time.Second) }()
+	waitForListener(t, addr, true)
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{string(body), err}
+	}()
+	<-started
+
+	// New connections are refused while the in-flight request is still allowed to finish
+	cancel()
+	waitForListener(t, addr, false)
+	select {
+	case err := <-served:
+		t.Fatalf("the server stopped with a request in flight: %v", err)
+	default:
+	}
+	close(release)
+
+	if res := <-responses; res.err != nil || res.body != "done" {
+		t.Errorf("in-flight request = %q, %v, want it to complete", res.body, res.err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("serveUntilDone = %v, want a clean shutdown", err)
+	}
+}
+
+func TestShutdownGracePeriod(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	server, addr := slowServer(t, started, release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- serveUntilDone(ctx, []*http.Server{server}, 50*time.Millisecond) }()
+	waitForListener(t, addr, true)
+
+	go func() {
+		if resp, err := http.Get("http://" + addr + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+	cancel()
+
+	if err := <-served; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("serveUntilDone = %v, want the grace period to run out", err)
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	server := newServer("8000", http.NotFoundHandler())
+	if server.Addr != ":8000" || server.ReadHeaderTimeout == 0 || server.WriteTimeout == 0 || server.IdleTimeout == 0 {
+		t.Errorf("server = %s with timeouts %s, %s and %s, want all set", server.Addr, server.ReadHeaderTimeout, server.WriteTimeout, server.IdleTimeout)
+	}
+	transport := newUpstreamTransport()
+	if transport.TLSHandshakeTimeout == 0 || transport.ResponseHeaderTimeout == 0 || transport.IdleConnTimeout == 0 {
+		t.Errorf("upstream transport timeouts %s, %s and %s, want all set", transport.TLSHandshakeTimeout, transport.ResponseHeaderTimeout, transport.IdleConnTimeout)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLS modes set with TLS_MODE
+const (
+	tlsModeOff    = "off"
+	tlsModeAuto   = "auto"
+	tlsModeManual = "manual"
+)
+
+// Defaults for TLS
+const (
+	defaultTLSCacheDir = "certs"
+	defaultHTTPPort    = "80"
+)
+
+// tlsSettings configures how the proxy terminates TLS
+type tlsSettings struct {
+	mode string
+	// domain is the base domain deployments are served under, e.g. yok.ninja; certificates are
+	// only requested for it and its direct subdomains in auto mode
+	domain string
+	// extraDomains are other hosts certificates may be requested for, such as custom domains
+	extraDomains []string
+	cacheDir     string
+	email        string
+	certFile     string
+	keyFile      string
+	// httpPort is where plain HTTP requests are redirected to HTTPS while TLS is on
+	httpPort string
+}
+
+// loadTLSSettings reads the TLS settings from the environment, failing if the mode's required
+// settings are missing
+func loadTLSSettings() (tlsSettings, error) {
+	settings := tlsSettings{
+		mode:     strings.ToLower(envOr("TLS_MODE", tlsModeOff)),
+		domain:   strings.ToLower(strings.TrimSuffix(os.Getenv("TLS_DOMAIN"), ".")),
+		cacheDir: envOr("TLS_CACHE_DIR", defaultTLSCacheDir),
+		email:    os.Getenv("TLS_EMAIL"),
+		certFile: os.Getenv("TLS_CERT_FILE"),
+		keyFile:  os.Getenv("TLS_KEY_FILE"),
+		httpPort: envOr("HTTP_PORT", defaultHTTPPort),
+	}
+	for _, domain := range strings.Split(os.Getenv("TLS_EXTRA_DOMAINS"), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			settings.extraDomains = append(settings.extraDomains, domain)
+		}
+	}
+
+	switch settings.mode {
+	case tlsModeOff:
+	case tlsModeAuto:
+		if settings.domain == "" {
+			return settings, fmt.Errorf("TLS_MODE=auto requires TLS_DOMAIN")
+		}
+	case tlsModeManual:
+		if settings.certFile == "" || settings.keyFile == "" {
+			return settings, fmt.Errorf("TLS_MODE=manual requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+	default:
+		return settings, fmt.Errorf("invalid TLS_MODE %q: must be off, auto or manual", settings.mode)
+	}
+	return settings, nil
+}
+
+// newServers returns the servers the proxy runs for handler: one on port, serving HTTPS if TLS
+// is on, and then another on the HTTP port redirecting to it
+func newServers(port string, handler http.Handler, settings tlsSettings) ([]*http.Server, error) {
+	server := newServer(port, handler)
+
+	switch settings.mode {
+	case tlsModeAuto:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(settings.cacheDir),
+			HostPolicy: hostPolicy(settings.domain, settings.extraDomains),
+			Email:      settings.email,
+		}
+		server.TLSConfig = manager.TLSConfig()
+
+		// The redirect server also answers the ACME HTTP-01 challenges
+		redirect := newServer(settings.httpPort, manager.HTTPHandler(redirectToHTTPS(port)))
+		slog.Warn("Certificates are requested per host on first use; wildcard certificates need a DNS challenge, which isn't supported, so each subdomain counts towards the CA's rate limits",
+			"domain", settings.domain, "cache_dir", settings.cacheDir)
+		return []*http.Server{server, redirect}, nil
+
+	case tlsModeManual:
+		cert, err := tls.LoadX509KeyPair(settings.certFile, settings.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+
+		redirect := newServer(settings.httpPort, redirectToHTTPS(port))
+		return []*http.Server{server, redirect}, nil
+
+	default:
+		return []*http.Server{server}, nil
+	}
+}
+
+// hostPolicy allows certificates for the base domain, its direct subdomains, where deployments
+// are served, and the extra domains
+func hostPolicy(domain string, extraDomains []string) autocert.HostPolicy {
+	return func(_ context.Context, host string) error {
+		host = strings.ToLower(host)
+		if host == domain {
+			return nil
+		}
+		if label, ok := strings.CutSuffix(host, "."+domain); ok && label != "" && !strings.Contains(label, ".") {
+			return nil
+		}
+		for _, extra := range extraDomains {
+			if host == extra {
+				return nil
+			}
+		}
+		return fmt.Errorf("host %q is not served by this proxy", host)
+	}
+}
+
+// redirectToHTTPS redirects every request to the same URL over HTTPS on port
+func redirectToHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate and its key to a temporary directory
+func writeCertificate(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "yok.ninja"},
+		DNSNames:     []string{"yok.ninja", "*.yok.ninja"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestNewServers(t *testing.T) {
+	certFile, keyFile := writeCertificate(t)
+	tests := []struct {
+		name      string
+		settings  tlsSettings
+		wantAddrs []string
+		wantTLS   bool
+		wantErr   bool
+	}{
+		{"off", tlsSettings{mode: tlsModeOff}, []string{":8000"}, false, false},
+		{"auto", tlsSettings{mode: tlsModeAuto, domain: "yok.ninja", cacheDir: t.TempDir(), httpPort: "80"}, []string{":8000", ":80"}, true, false},
+		{"manual", tlsSettings{mode: tlsModeManual, certFile: certFile, keyFile: keyFile, httpPort: "8080"}, []string{":8000", ":8080"}, true, false},
+		{"manual with a missing key", tlsSettings{mode: tlsModeManual, certFile: certFile, keyFile: certFile + ".missing", httpPort: "80"}, nil, false, true},
+	}
+	for _, tt := range tests {
+		servers, err := newServers("8000", http.NotFoundHandler(), tt.settings)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want an error: %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		var addrs []string
+		for _, server := range servers {
+			addrs = append(addrs, server.Addr)
+		}
+		if !slices.Equal(addrs, tt.wantAddrs) {
+			t.Errorf("%s: servers on %q, want %q", tt.name, addrs, tt.wantAddrs)
+		}
+		if len(servers) > 0 && (servers[0].TLSConfig != nil) != tt.wantTLS {
+			t.Errorf("%s: TLS on the main server = %v, want %v", tt.name, servers[0].TLSConfig != nil, tt.wantTLS)
+		}
+		// The redirect server only speaks plain HTTP
+		if len(servers) > 1 && servers[1].TLSConfig != nil {
+			t.Errorf("%s: the redirect server has a TLS config", tt.name)
+		}
+	}
+}
+
+func TestHostPolicy(t *testing.T) {
+	policy := hostPolicy("yok.ninja", []string{"www.example.com"})
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"yok.ninja", true},
+		{"brave-fox.yok.ninja", true},
+		{"Brave-Fox.YOK.ninja", true},
+		{"www.example.com", true},
+		{"a.b.yok.ninja", false},
+		{".yok.ninja", false},
+		{"evilyok.ninja", false},
+		{"example.com", false},
+		{"yok.ninja.evil.com", false},
+	}
+	for _, tt := range tests {
+		if err := policy(context.Background(), tt.host); (err == nil) != tt.want {
+			t.Errorf("hostPolicy(%q) = %v, want allowed: %v", tt.host, err, tt.want)
+		}
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		port string
+		url  string
+		want string
+	}{
+		{"443", "http://brave-fox.yok.ninja/docs/?page=2", "https://brave-fox.yok.ninja/docs/?page=2"},
+		{"443", "http://brave-fox.yok.ninja:80/", "https://brave-fox.yok.ninja/"},
+		{"8443", "http://brave-fox.yok.ninja:8080/app.js", "https://brave-fox.yok.ninja:8443/app.js"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		redirectToHTTPS(tt.port).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != tt.want {
+			t.Errorf("GET %s = %d to %q, want a redirect to %q", tt.url, rec.Code, rec.Header().Get("Location"), tt.want)
+		}
+	}
+}